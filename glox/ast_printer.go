@@ -4,8 +4,6 @@ import (
 	"fmt"
 	"glox/expr"
 	"glox/tokens"
-	"strconv"
-	"strings"
 )
 
 type AstPrinter struct{}
@@ -30,6 +28,58 @@ func (p AstPrinter) VisitForSet(e *expr.Set[string]) (string, error) {
 	panic("Not implemented")
 }
 
+func (p AstPrinter) VisitForLambda(e *expr.Lambda[string]) (string, error) {
+	panic("Not implemented")
+}
+
+func (p AstPrinter) VisitForInterpolation(e *expr.Interpolation[string]) (string, error) {
+	panic("Not implemented")
+}
+
+func (p AstPrinter) VisitForCompoundAssign(e *expr.CompoundAssign[string]) (string, error) {
+	panic("Not implemented")
+}
+
+func (p AstPrinter) VisitForCompoundSet(e *expr.CompoundSet[string]) (string, error) {
+	panic("Not implemented")
+}
+
+func (p AstPrinter) VisitForCompoundIndexSet(e *expr.CompoundIndexSet[string]) (string, error) {
+	panic("Not implemented")
+}
+
+func (p AstPrinter) VisitForConditional(e *expr.Conditional[string]) (string, error) {
+	return p.parenthesize("?:", e.Condition, e.Then, e.Else)
+}
+
+func (p AstPrinter) VisitForOptionalGet(e *expr.OptionalGet[string]) (string, error) {
+	panic("Not implemented")
+}
+
+func (p AstPrinter) VisitForOptionalChain(e *expr.OptionalChain[string]) (string, error) {
+	panic("Not implemented")
+}
+
+func (p AstPrinter) VisitForList(e *expr.List[string]) (string, error) {
+	panic("Not implemented")
+}
+
+func (p AstPrinter) VisitForMap(e *expr.Map[string]) (string, error) {
+	panic("Not implemented")
+}
+
+func (p AstPrinter) VisitForIndex(e *expr.Index[string]) (string, error) {
+	panic("Not implemented")
+}
+
+func (p AstPrinter) VisitForIndexSet(e *expr.IndexSet[string]) (string, error) {
+	panic("Not implemented")
+}
+
+func (p AstPrinter) VisitForSlice(e *expr.Slice[string]) (string, error) {
+	panic("Not implemented")
+}
+
 func (p AstPrinter) VisitForBinary(e *expr.Binary[string]) (string, error) {
 	return p.parenthesize(e.Operator.Lexeme, e.Left, e.Right)
 }
@@ -84,11 +134,7 @@ func format(v any) string {
 		return fmt.Sprintf("%d.0", i)
 	}
 	if f, ok := v.(float64); ok {
-		s := strconv.FormatFloat(f, 'f', -1, 64)
-		if !strings.Contains(s, ".") {
-			s = s + ".0"
-		}
-		return s
+		return tokens.FormatFloat(f)
 	}
 	return fmt.Sprintf("%v", v)
 }
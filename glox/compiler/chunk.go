@@ -0,0 +1,181 @@
+package compiler
+
+import (
+	"glox/tokens"
+	"sort"
+)
+
+type OpCode byte
+
+const (
+	OpConstant     OpCode = iota // [constant u16] pushes a constant
+	OpNil                        // pushes nil
+	OpTrue                       // pushes true
+	OpFalse                      // pushes false
+	OpPop                        // discards the top of the stack
+	OpCopy                       // [depth u8] pushes the value at depth from the top of the stack
+	OpSwap                       // swaps the two values on top of the stack
+	OpResult                     // pops the value of a top-level expression statement and keeps it as the result
+	OpGetLocal                   // [slot u8]
+	OpSetLocal                   // [slot u8]
+	OpGetGlobal                  // [name u16]
+	OpDefineGlobal               // [name u16]
+	OpSetGlobal                  // [name u16]
+	OpGetUpvalue                 // [index u8]
+	OpSetUpvalue                 // [index u8]
+	OpGetProperty                // [name u16]
+	OpSetProperty                // [name u16]
+	OpGetSuper                   // [name u16]
+	OpEqual
+	OpGreater
+	OpGreaterEqual
+	OpLess
+	OpLessEqual
+	OpAdd
+	OpSubtract
+	OpMultiply
+	OpDivide
+	OpModulo
+	OpIntDivide
+	OpNot
+	OpNegate
+	OpInterpolate // [parts count u8] concatenates the string value of the parts
+	OpPrint
+	OpJump        // [offset u16] jumps forward
+	OpJumpIfFalse // [offset u16] jumps forward if the top of the stack is falsey, it does not pop it
+	OpJumpIfNil   // [offset u16] jumps forward if the top of the stack is nil, it does not pop it
+	OpLoop        // [offset u16] jumps backwards
+	OpCall        // [arguments count u8]
+	OpClosure     // [function u16] followed by [is local u8][index u8] for each upvalue
+	OpCloseUpvalue
+	OpReturn
+	OpClass   // [name u16]
+	OpInherit // copies the superclass methods into the subclass
+	OpMethod  // [name u16]
+	OpThrow
+	OpPushHandler // [offset u16] registers the code to jump to if an error happens
+	OpPopHandler
+	OpPushFinally // [offset u16] registers the finally code to run if an error happens, the error is pushed as is
+	OpRethrow     // throws again the error pushed by a finally handler
+)
+
+var opCodeName = map[OpCode]string{
+	OpConstant:     "OP_CONSTANT",
+	OpNil:          "OP_NIL",
+	OpTrue:         "OP_TRUE",
+	OpFalse:        "OP_FALSE",
+	OpPop:          "OP_POP",
+	OpCopy:         "OP_COPY",
+	OpSwap:         "OP_SWAP",
+	OpResult:       "OP_RESULT",
+	OpGetLocal:     "OP_GET_LOCAL",
+	OpSetLocal:     "OP_SET_LOCAL",
+	OpGetGlobal:    "OP_GET_GLOBAL",
+	OpDefineGlobal: "OP_DEFINE_GLOBAL",
+	OpSetGlobal:    "OP_SET_GLOBAL",
+	OpGetUpvalue:   "OP_GET_UPVALUE",
+	OpSetUpvalue:   "OP_SET_UPVALUE",
+	OpGetProperty:  "OP_GET_PROPERTY",
+	OpSetProperty:  "OP_SET_PROPERTY",
+	OpGetSuper:     "OP_GET_SUPER",
+	OpEqual:        "OP_EQUAL",
+	OpGreater:      "OP_GREATER",
+	OpGreaterEqual: "OP_GREATER_EQUAL",
+	OpLess:         "OP_LESS",
+	OpLessEqual:    "OP_LESS_EQUAL",
+	OpAdd:          "OP_ADD",
+	OpSubtract:     "OP_SUBTRACT",
+	OpMultiply:     "OP_MULTIPLY",
+	OpDivide:       "OP_DIVIDE",
+	OpModulo:       "OP_MODULO",
+	OpIntDivide:    "OP_INT_DIVIDE",
+	OpNot:          "OP_NOT",
+	OpNegate:       "OP_NEGATE",
+	OpInterpolate:  "OP_INTERPOLATE",
+	OpPrint:        "OP_PRINT",
+	OpJump:         "OP_JUMP",
+	OpJumpIfFalse:  "OP_JUMP_IF_FALSE",
+	OpJumpIfNil:    "OP_JUMP_IF_NIL",
+	OpLoop:         "OP_LOOP",
+	OpCall:         "OP_CALL",
+	OpClosure:      "OP_CLOSURE",
+	OpCloseUpvalue: "OP_CLOSE_UPVALUE",
+	OpReturn:       "OP_RETURN",
+	OpClass:        "OP_CLASS",
+	OpInherit:      "OP_INHERIT",
+	OpMethod:       "OP_METHOD",
+	OpThrow:        "OP_THROW",
+	OpPushHandler:  "OP_PUSH_HANDLER",
+	OpPopHandler:   "OP_POP_HANDLER",
+	OpPushFinally:  "OP_PUSH_FINALLY",
+	OpRethrow:      "OP_RETHROW",
+}
+
+func (op OpCode) String() string {
+	return opCodeName[op]
+}
+
+// tokenRun represents that the code starting at offset belongs to token until the next run
+type tokenRun struct {
+	offset int
+	token  tokens.Token
+}
+
+// Chunk holds a sequence of bytecode along with its constant pool and a run-length encoded table of the tokens
+// the code comes from, used to locate errors.
+type Chunk struct {
+	Code      []byte
+	Constants []any
+	tokens    []tokenRun
+}
+
+// Write appends a byte corresponding to the provided token
+func (c *Chunk) Write(b byte, token tokens.Token) {
+	if l := len(c.tokens); l == 0 || c.tokens[l-1].token != token {
+		c.tokens = append(c.tokens, tokenRun{offset: len(c.Code), token: token})
+	}
+	c.Code = append(c.Code, b)
+}
+
+// AddConstant adds a value to the constant pool and returns its index
+func (c *Chunk) AddConstant(value any) int {
+	c.Constants = append(c.Constants, value)
+	return len(c.Constants) - 1
+}
+
+// Token returns the token the byte at the provided offset comes from
+func (c *Chunk) Token(offset int) tokens.Token {
+	i := sort.Search(len(c.tokens), func(i int) bool { return c.tokens[i].offset > offset })
+	if i == 0 {
+		return tokens.Token{}
+	}
+	return c.tokens[i-1].token
+}
+
+// Line returns the source line of the byte at the provided offset
+func (c *Chunk) Line(offset int) int {
+	return c.Token(offset).Line
+}
+
+// Function is the compiled representation of a glox function
+type Function struct {
+	Name         string
+	Arity        int
+	UpvalueCount int
+	Chunk        Chunk
+	// ClassName is the name of the class the function belongs to if it is a method
+	ClassName string
+}
+
+// anonymousName is the name of the functions declared by lambda expressions
+const anonymousName = "<anonymous>"
+
+func (f *Function) String() string {
+	if f.Name == "" {
+		return "<script>"
+	}
+	if f.Name == anonymousName {
+		return "<anonymous fn>"
+	}
+	return "<fn " + f.Name + ">"
+}
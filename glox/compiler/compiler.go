@@ -0,0 +1,1074 @@
+// Package compiler compiles a resolved glox program to bytecode to be executed by the vm package.
+package compiler
+
+import (
+	"fmt"
+	"glox/expr"
+	"glox/stmt"
+	"glox/tokens"
+	"math"
+	"slices"
+)
+
+type Expr = expr.Expr[any]
+type Stmt = stmt.Stmt[any]
+type FunctionStmt = stmt.Function[any]
+
+const maxLocals = math.MaxUint8 + 1
+const maxUpvalues = math.MaxUint8 + 1
+
+// CompileError is returned when the program exceeds the limits of the bytecode
+type CompileError struct {
+	Token   tokens.Token
+	Message string
+}
+
+func (e *CompileError) Error() string {
+	return e.Message
+}
+
+// unsupported returns the error for the language features the bytecode does not implement yet
+func unsupported(token tokens.Token, feature string) error {
+	return &CompileError{Token: token, Message: fmt.Sprintf("%s are not supported by the vm backend.", feature)}
+}
+
+type functionKind int
+
+const (
+	functionKindScript functionKind = iota
+	functionKindFunction
+	functionKindMethod
+	functionKindInitializer
+)
+
+type local struct {
+	name       string
+	depth      int // -1 while the variable is being initialized
+	isCaptured bool
+}
+
+type upvalue struct {
+	index   uint8
+	isLocal bool
+}
+
+// tryContext represents a try statement being compiled, it is needed to execute finally blocks when returning
+// from a function in the middle of a try statement.
+type tryContext struct {
+	// protected is true if there is an error handler registered, it needs to be removed when returning
+	protected   bool
+	finallyBody []Stmt
+}
+
+// loopContext represents a loop being compiled, it collects the jumps of its break and continue statements
+type loopContext struct {
+	scopeDepth int
+	// tries is the number of try statements enclosing the loop
+	tries         int
+	breakJumps    []int
+	continueJumps []int
+}
+
+// functionCompiler holds the state of the function being compiled
+type functionCompiler struct {
+	enclosing  *functionCompiler
+	function   *Function
+	kind       functionKind
+	locals     []local
+	upvalues   []upvalue
+	scopeDepth int
+	tries      []tryContext
+	loops      []*loopContext
+	// constants indexes the numbers and strings in the constant pool, so they are not duplicated
+	constants map[any]int
+}
+
+func newFunctionCompiler(enclosing *functionCompiler, kind functionKind, name string) *functionCompiler {
+	fc := &functionCompiler{enclosing: enclosing, function: &Function{Name: name}, kind: kind, constants: map[any]int{}}
+	// the first slot holds the function itself, or the instance for methods
+	slotName := ""
+	if kind == functionKindMethod || kind == functionKindInitializer {
+		slotName = "this"
+	}
+	fc.locals = append(fc.locals, local{name: slotName, depth: 0})
+	return fc
+}
+
+type classCompiler struct {
+	enclosing     *classCompiler
+	hasSuperclass bool
+}
+
+// Compiler turns statements into bytecode. It expects the statements to be already resolved, so it does not
+// check the errors reported by the resolver again.
+type Compiler struct {
+	current *functionCompiler
+	class   *classCompiler
+	// token is the token the emitted code belongs to
+	token tokens.Token
+	// err holds the first error found when adding constants
+	err error
+	// optionalJumps holds the jumps to the end of the optional chain being compiled, taken when an optional access
+	// finds nil
+	optionalJumps []int
+}
+
+// Compile returns the function executing the provided statements
+func Compile(statements []Stmt) (*Function, error) {
+	c := &Compiler{current: newFunctionCompiler(nil, functionKindScript, "")}
+	for _, statement := range statements {
+		if err := c.statement(statement); err != nil {
+			return nil, err
+		}
+	}
+	c.emitReturn()
+	if c.err != nil {
+		return nil, c.err
+	}
+	return c.current.function, nil
+}
+
+func (c *Compiler) statement(s Stmt) error {
+	_, err := s.Accept(c)
+	return err
+}
+
+func (c *Compiler) statements(statements []Stmt) error {
+	for _, s := range statements {
+		if err := c.statement(s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Compiler) expression(e Expr) error {
+	_, err := e.Accept(c)
+	return err
+}
+
+func (c *Compiler) VisitForBlock(b *stmt.Block[any]) (any, error) {
+	return nil, c.block(b.Statements)
+}
+
+// block compiles the provided statements in a new scope
+func (c *Compiler) block(statements []Stmt) error {
+	c.beginScope()
+	if err := c.statements(statements); err != nil {
+		return err
+	}
+	c.endScope()
+	return nil
+}
+
+func (c *Compiler) VisitForClass(s *stmt.Class[any]) (any, error) {
+	c.token = s.Name
+	if len(s.ClassMethods) > 0 || len(s.Getters) > 0 || len(s.Setters) > 0 {
+		return nil, unsupported(s.Name, "Class methods, getters and setters")
+	}
+	if len(s.Traits) > 0 {
+		return nil, unsupported(s.Traits[0].Name, "Traits")
+	}
+	name := c.identifierConstant(s.Name.Lexeme)
+	if err := c.declareVariable(s.Name); err != nil {
+		return nil, err
+	}
+	c.emitOpShort(OpClass, name)
+	c.defineVariable(name)
+
+	class := &classCompiler{enclosing: c.class}
+	c.class = class
+	defer func() {
+		c.class = class.enclosing
+	}()
+
+	if s.SuperClass != nil {
+		if err := c.expression(s.SuperClass); err != nil {
+			return nil, err
+		}
+		c.beginScope()
+		if err := c.addLocal(tokens.Token{Lexeme: "super", Line: s.SuperClass.Name.Line}); err != nil {
+			return nil, err
+		}
+		c.markInitialized()
+		if err := c.namedVariable(s.Name, false); err != nil {
+			return nil, err
+		}
+		c.token = s.SuperClass.Name
+		c.emitOp(OpInherit)
+		class.hasSuperclass = true
+	}
+
+	// the class is kept on the stack while its methods are defined
+	if err := c.namedVariable(s.Name, false); err != nil {
+		return nil, err
+	}
+	for _, method := range s.Methods {
+		kind := functionKindMethod
+		if method.Name.Lexeme == "init" {
+			kind = functionKindInitializer
+		}
+		if err := c.function(method, kind, s.Name.Lexeme); err != nil {
+			return nil, err
+		}
+		c.emitOpShort(OpMethod, c.identifierConstant(method.Name.Lexeme))
+	}
+	c.emitOp(OpPop)
+
+	if class.hasSuperclass {
+		c.endScope()
+	}
+	return nil, nil
+}
+
+func (c *Compiler) VisitForExpression(s *stmt.Expression[any]) (any, error) {
+	if err := c.expression(s.Expression); err != nil {
+		return nil, err
+	}
+	if c.current.kind == functionKindScript && c.current.scopeDepth == 0 {
+		c.emitOp(OpResult)
+	} else {
+		c.emitOp(OpPop)
+	}
+	return nil, nil
+}
+
+func (c *Compiler) VisitForFunction(f *FunctionStmt) (any, error) {
+	if err := c.declareVariable(f.Name); err != nil {
+		return nil, err
+	}
+	c.markInitialized() // a function can refer to itself
+	if err := c.function(f, functionKindFunction, ""); err != nil {
+		return nil, err
+	}
+	c.defineVariable(c.identifierConstant(f.Name.Lexeme))
+	return nil, nil
+}
+
+// function compiles the provided function declaration and emits the code creating its closure
+func (c *Compiler) function(f *FunctionStmt, kind functionKind, className string) error {
+	c.token = f.Name
+	if f.Variadic || slices.ContainsFunc(f.Defaults, func(e Expr) bool { return e != nil }) {
+		return unsupported(f.Name, "Default and rest parameters")
+	}
+	name := f.Name.Lexeme
+	if name == "" {
+		name = anonymousName
+	}
+	fc := newFunctionCompiler(c.current, kind, name)
+	fc.function.ClassName = className
+	c.current = fc
+
+	c.beginScope()
+	for _, param := range f.Params {
+		fc.function.Arity++
+		if err := c.addLocal(param); err != nil {
+			return err
+		}
+		c.markInitialized()
+	}
+	if err := c.statements(f.Body); err != nil {
+		return err
+	}
+	c.emitReturn()
+
+	c.current = fc.enclosing
+	c.token = f.Name
+	c.emitOpShort(OpClosure, c.makeConstant(fc.function))
+	for _, upvalue := range fc.upvalues {
+		isLocal := byte(0)
+		if upvalue.isLocal {
+			isLocal = 1
+		}
+		c.emitBytes(isLocal, upvalue.index)
+	}
+	return nil
+}
+
+func (c *Compiler) VisitForImport(s *stmt.Import[any]) (any, error) {
+	return nil, unsupported(s.Keyword, "Imports")
+}
+
+func (c *Compiler) VisitForIf(s *stmt.If[any]) (any, error) {
+	if err := c.expression(s.Condition); err != nil {
+		return nil, err
+	}
+	thenJump := c.emitJump(OpJumpIfFalse)
+	c.emitOp(OpPop)
+	if err := c.statement(s.ThenBranch); err != nil {
+		return nil, err
+	}
+	elseJump := c.emitJump(OpJump)
+	if err := c.patchJump(thenJump); err != nil {
+		return nil, err
+	}
+	c.emitOp(OpPop)
+	if s.ElseBranch != nil {
+		if err := c.statement(s.ElseBranch); err != nil {
+			return nil, err
+		}
+	}
+	return nil, c.patchJump(elseJump)
+}
+
+func (c *Compiler) VisitForPrint(s *stmt.Print[any]) (any, error) {
+	if err := c.expression(s.Expression); err != nil {
+		return nil, err
+	}
+	c.emitOp(OpPrint)
+	return nil, nil
+}
+
+func (c *Compiler) VisitForReturn(s *stmt.Return[any]) (any, error) {
+	c.token = s.Keyword
+	if s.Value == nil {
+		c.emitImplicitReturnValue()
+	} else if err := c.expression(s.Value); err != nil {
+		return nil, err
+	}
+	if err := c.exitTries(0); err != nil {
+		return nil, err
+	}
+	c.emitOp(OpReturn)
+	return nil, nil
+}
+
+// exitTries emits the code needed to leave the try statements of the current function beyond the provided
+// depth: their handlers are removed and their finally blocks executed. It expects the value to be kept to be
+// on top of the stack.
+func (c *Compiler) exitTries(depth int) error {
+	fc := c.current
+	tries := fc.tries
+	defer func() {
+		fc.tries = tries
+	}()
+	// the value on top of the stack is kept as a hidden local, so the finally blocks can declare variables
+	locals := len(fc.locals)
+	if err := c.addLocal(tokens.Token{Line: c.token.Line}); err != nil {
+		return err
+	}
+	c.markInitialized()
+	for i := len(tries) - 1; i >= depth; i-- {
+		fc.tries = tries[:i]
+		if tries[i].protected {
+			c.emitOp(OpPopHandler)
+		}
+		if tries[i].finallyBody != nil {
+			if err := c.block(tries[i].finallyBody); err != nil {
+				return err
+			}
+		}
+	}
+	fc.locals = fc.locals[:locals]
+	return nil
+}
+
+func (c *Compiler) VisitForThrow(s *stmt.Throw[any]) (any, error) {
+	if err := c.expression(s.Value); err != nil {
+		return nil, err
+	}
+	c.token = s.Keyword
+	c.emitOp(OpThrow)
+	return nil, nil
+}
+
+func (c *Compiler) VisitForTry(s *stmt.Try[any]) (any, error) {
+	fc := c.current
+	handlerOp := OpPushHandler
+	if s.CatchName == nil {
+		handlerOp = OpPushFinally
+	}
+	handler := c.emitJump(handlerOp)
+	fc.tries = append(fc.tries, tryContext{protected: true, finallyBody: s.FinallyBody})
+	if err := c.block(s.Body); err != nil {
+		return nil, err
+	}
+	fc.tries = fc.tries[:len(fc.tries)-1]
+	c.emitOp(OpPopHandler)
+	if err := c.finally(s.FinallyBody); err != nil {
+		return nil, err
+	}
+	exitJumps := []int{c.emitJump(OpJump)}
+
+	// when an error happens, the stack is restored and the error value pushed
+	if err := c.patchJump(handler); err != nil {
+		return nil, err
+	}
+	if s.CatchName == nil {
+		return nil, c.rethrowAfterFinally(s.FinallyBody, exitJumps)
+	}
+
+	c.beginScope()
+	if err := c.addLocal(*s.CatchName); err != nil {
+		return nil, err
+	}
+	c.markInitialized()
+	catchHandler := -1
+	if s.FinallyBody != nil {
+		// errors in the catch body still need to run the finally block
+		catchHandler = c.emitJump(OpPushFinally)
+		fc.tries = append(fc.tries, tryContext{protected: true, finallyBody: s.FinallyBody})
+	}
+	if err := c.statements(s.CatchBody); err != nil {
+		return nil, err
+	}
+	if catchHandler >= 0 {
+		fc.tries = fc.tries[:len(fc.tries)-1]
+		c.emitOp(OpPopHandler)
+	}
+	c.endScope()
+	if err := c.finally(s.FinallyBody); err != nil {
+		return nil, err
+	}
+	if catchHandler < 0 {
+		return nil, c.patchJumps(exitJumps)
+	}
+	exitJumps = append(exitJumps, c.emitJump(OpJump))
+	if err := c.patchJump(catchHandler); err != nil {
+		return nil, err
+	}
+	// the catch variable is still on the stack below the error
+	locals := len(fc.locals)
+	fc.locals = append(fc.locals, local{name: "", depth: fc.scopeDepth})
+	err := c.rethrowAfterFinally(s.FinallyBody, exitJumps)
+	fc.locals = fc.locals[:locals]
+	return nil, err
+}
+
+// rethrowAfterFinally emits the code executing the finally block for the error pushed by a finally handler and
+// throwing it again.
+func (c *Compiler) rethrowAfterFinally(finallyBody []Stmt, exitJumps []int) error {
+	fc := c.current
+	locals := len(fc.locals)
+	if err := c.addLocal(tokens.Token{Line: c.token.Line}); err != nil {
+		return err
+	}
+	c.markInitialized()
+	if err := c.finally(finallyBody); err != nil {
+		return err
+	}
+	c.emitOp(OpRethrow)
+	fc.locals = fc.locals[:locals]
+	return c.patchJumps(exitJumps)
+}
+
+func (c *Compiler) finally(body []Stmt) error {
+	if body == nil {
+		return nil
+	}
+	return c.block(body)
+}
+
+func (c *Compiler) VisitForVar(s *stmt.Var[any]) (any, error) {
+	c.token = s.Name
+	if err := c.declareVariable(s.Name); err != nil {
+		return nil, err
+	}
+	if s.Initializer != nil {
+		if err := c.expression(s.Initializer); err != nil {
+			return nil, err
+		}
+	} else {
+		c.emitOp(OpNil)
+	}
+	c.token = s.Name
+	c.defineVariable(c.identifierConstant(s.Name.Lexeme))
+	return nil, nil
+}
+
+func (c *Compiler) VisitForWhile(s *stmt.While[any]) (any, error) {
+	loopStart := len(c.chunk().Code)
+	if err := c.expression(s.Condition); err != nil {
+		return nil, err
+	}
+	exitJump := c.emitJump(OpJumpIfFalse)
+	c.emitOp(OpPop)
+	fc := c.current
+	loop := &loopContext{scopeDepth: fc.scopeDepth, tries: len(fc.tries)}
+	fc.loops = append(fc.loops, loop)
+	if err := c.statement(s.Body); err != nil {
+		return nil, err
+	}
+	fc.loops = fc.loops[:len(fc.loops)-1]
+	if err := c.patchJumps(loop.continueJumps); err != nil {
+		return nil, err
+	}
+	if s.Increment != nil {
+		if err := c.expression(s.Increment); err != nil {
+			return nil, err
+		}
+		c.emitOp(OpPop)
+	}
+	if err := c.emitLoop(loopStart); err != nil {
+		return nil, err
+	}
+	if err := c.patchJump(exitJump); err != nil {
+		return nil, err
+	}
+	c.emitOp(OpPop)
+	return nil, c.patchJumps(loop.breakJumps)
+}
+
+func (c *Compiler) VisitForBreak(s *stmt.Break[any]) (any, error) {
+	c.token = s.Keyword
+	loop := c.current.loops[len(c.current.loops)-1]
+	if err := c.exitLoop(loop); err != nil {
+		return nil, err
+	}
+	loop.breakJumps = append(loop.breakJumps, c.emitJump(OpJump))
+	return nil, nil
+}
+
+func (c *Compiler) VisitForContinue(s *stmt.Continue[any]) (any, error) {
+	c.token = s.Keyword
+	loop := c.current.loops[len(c.current.loops)-1]
+	if err := c.exitLoop(loop); err != nil {
+		return nil, err
+	}
+	loop.continueJumps = append(loop.continueJumps, c.emitJump(OpJump))
+	return nil, nil
+}
+
+// exitLoop emits the code leaving the try statements and discarding the local variables declared inside the
+// provided loop, before jumping out of its body.
+func (c *Compiler) exitLoop(loop *loopContext) error {
+	fc := c.current
+	if len(fc.tries) > loop.tries {
+		c.emitOp(OpNil)
+		if err := c.exitTries(loop.tries); err != nil {
+			return err
+		}
+		c.emitOp(OpPop)
+	}
+	for i := len(fc.locals) - 1; i >= 0 && fc.locals[i].depth > loop.scopeDepth; i-- {
+		if fc.locals[i].isCaptured {
+			c.emitOp(OpCloseUpvalue)
+		} else {
+			c.emitOp(OpPop)
+		}
+	}
+	return nil
+}
+
+func (c *Compiler) VisitForAssign(a *expr.Assign[any]) (any, error) {
+	if err := c.expression(a.Value); err != nil {
+		return nil, err
+	}
+	return nil, c.namedVariable(a.Name, true)
+}
+
+func (c *Compiler) VisitForBinary(b *expr.Binary[any]) (any, error) {
+	if err := c.expression(b.Left); err != nil {
+		return nil, err
+	}
+	if err := c.expression(b.Right); err != nil {
+		return nil, err
+	}
+	c.token = b.Operator
+	c.binaryOperator(b.Operator.TokenType)
+	return nil, nil
+}
+
+// binaryOperator emits the instructions applying the operator to the two values on top of the stack
+func (c *Compiler) binaryOperator(operator tokens.TokenType) {
+	switch operator {
+	case tokens.Plus:
+		c.emitOp(OpAdd)
+	case tokens.Minus:
+		c.emitOp(OpSubtract)
+	case tokens.Star:
+		c.emitOp(OpMultiply)
+	case tokens.Slash:
+		c.emitOp(OpDivide)
+	case tokens.Percent:
+		c.emitOp(OpModulo)
+	case tokens.TildeSlash:
+		c.emitOp(OpIntDivide)
+	case tokens.Greater:
+		c.emitOp(OpGreater)
+	case tokens.GreaterEqual:
+		c.emitOp(OpGreaterEqual)
+	case tokens.Less:
+		c.emitOp(OpLess)
+	case tokens.LessEqual:
+		c.emitOp(OpLessEqual)
+	case tokens.EqualEqual:
+		c.emitOp(OpEqual)
+	case tokens.BangEqual:
+		// as `a != b` is `!(a == b)`, overloading `==` is enough
+		c.emitOp(OpEqual)
+		c.emitOp(OpNot)
+	}
+}
+
+func (c *Compiler) VisitForCompoundAssign(a *expr.CompoundAssign[any]) (any, error) {
+	if err := c.namedVariable(a.Name, false); err != nil {
+		return nil, err
+	}
+	if a.Postfix {
+		c.emitBytes(byte(OpCopy), 0) // the previous value is the result
+	}
+	if err := c.compoundValue(a.Operator, a.Value); err != nil {
+		return nil, err
+	}
+	if err := c.namedVariable(a.Name, true); err != nil {
+		return nil, err
+	}
+	if a.Postfix {
+		c.emitOp(OpPop)
+	}
+	return nil, nil
+}
+
+func (c *Compiler) VisitForCompoundSet(s *expr.CompoundSet[any]) (any, error) {
+	if err := c.expression(s.Object); err != nil {
+		return nil, err
+	}
+	// the object is evaluated once and kept for OpSetProperty
+	c.emitBytes(byte(OpCopy), 0)
+	c.token = s.Name
+	name := c.identifierConstant(s.Name.Lexeme)
+	c.emitOpShort(OpGetProperty, name)
+	if s.Postfix {
+		// the previous value is moved below the object to be the result: [value, object, value]
+		c.emitOp(OpSwap)
+		c.emitBytes(byte(OpCopy), 1)
+	}
+	if err := c.compoundValue(s.Operator, s.Value); err != nil {
+		return nil, err
+	}
+	c.token = s.Name
+	c.emitOpShort(OpSetProperty, name)
+	if s.Postfix {
+		c.emitOp(OpPop)
+	}
+	return nil, nil
+}
+
+// compoundValue emits the code applying the operator of a compound assignment to the current value on top of the stack
+func (c *Compiler) compoundValue(operator tokens.Token, value expr.Expr[any]) error {
+	if err := c.expression(value); err != nil {
+		return err
+	}
+	c.token = operator
+	c.binaryOperator(tokens.BinaryOperator(operator).TokenType)
+	return nil
+}
+
+func (c *Compiler) VisitForCall(call *expr.Call[any]) (any, error) {
+	if err := c.expression(call.Callee); err != nil {
+		return nil, err
+	}
+	for _, arg := range call.Arguments {
+		if err := c.expression(arg); err != nil {
+			return nil, err
+		}
+	}
+	c.token = call.Paren
+	c.emitBytes(byte(OpCall), byte(len(call.Arguments)))
+	return nil, nil
+}
+
+func (c *Compiler) VisitForGet(g *expr.Get[any]) (any, error) {
+	if err := c.expression(g.Object); err != nil {
+		return nil, err
+	}
+	c.token = g.Name
+	c.emitOpShort(OpGetProperty, c.identifierConstant(g.Name.Lexeme))
+	return nil, nil
+}
+
+func (c *Compiler) VisitForSet(s *expr.Set[any]) (any, error) {
+	if err := c.expression(s.Object); err != nil {
+		return nil, err
+	}
+	if err := c.expression(s.Value); err != nil {
+		return nil, err
+	}
+	c.token = s.Name
+	c.emitOpShort(OpSetProperty, c.identifierConstant(s.Name.Lexeme))
+	return nil, nil
+}
+
+func (c *Compiler) VisitForGrouping(g *expr.Grouping[any]) (any, error) {
+	return nil, c.expression(g.Expression)
+}
+
+func (c *Compiler) VisitForMatch(m *stmt.Match[any]) (any, error) {
+	return nil, unsupported(m.Keyword, "Match statements")
+}
+
+func (c *Compiler) VisitForTrait(s *stmt.Trait[any]) (any, error) {
+	return nil, unsupported(s.Name, "Traits")
+}
+
+func (c *Compiler) VisitForLambda(l *expr.Lambda[any]) (any, error) {
+	return nil, c.function(stmt.LambdaFunction(l), functionKindFunction, "")
+}
+
+func (c *Compiler) VisitForInterpolation(i *expr.Interpolation[any]) (any, error) {
+	for _, part := range i.Parts {
+		if err := c.expression(part); err != nil {
+			return nil, err
+		}
+	}
+	c.token = i.Start
+	c.emitBytes(byte(OpInterpolate), byte(len(i.Parts)))
+	return nil, nil
+}
+
+func (c *Compiler) VisitForList(l *expr.List[any]) (any, error) {
+	return nil, unsupported(l.Bracket, "Lists")
+}
+
+func (c *Compiler) VisitForMap(m *expr.Map[any]) (any, error) {
+	return nil, unsupported(m.Brace, "Maps")
+}
+
+func (c *Compiler) VisitForIndex(i *expr.Index[any]) (any, error) {
+	return nil, unsupported(i.Bracket, "Lists")
+}
+
+func (c *Compiler) VisitForIndexSet(i *expr.IndexSet[any]) (any, error) {
+	return nil, unsupported(i.Bracket, "Lists")
+}
+
+func (c *Compiler) VisitForCompoundIndexSet(i *expr.CompoundIndexSet[any]) (any, error) {
+	return nil, unsupported(i.Bracket, "Lists")
+}
+
+func (c *Compiler) VisitForSlice(s *expr.Slice[any]) (any, error) {
+	return nil, unsupported(s.Bracket, "Lists")
+}
+
+func (c *Compiler) VisitForLiteral(l *expr.Literal[any]) (any, error) {
+	switch l.Value {
+	case true:
+		c.emitOp(OpTrue)
+	case false:
+		c.emitOp(OpFalse)
+	case tokens.NilLiteral, nil:
+		c.emitOp(OpNil)
+	default:
+		c.emitOpShort(OpConstant, c.makeConstant(l.Value))
+	}
+	return nil, nil
+}
+
+func (c *Compiler) VisitForLogical(l *expr.Logical[any]) (any, error) {
+	if err := c.expression(l.Left); err != nil {
+		return nil, err
+	}
+	var endJump int
+	switch l.Operator.TokenType {
+	case tokens.Or, tokens.QuestionQuestion:
+		elseOp := OpJumpIfFalse
+		if l.Operator.TokenType == tokens.QuestionQuestion {
+			elseOp = OpJumpIfNil
+		}
+		elseJump := c.emitJump(elseOp)
+		endJump = c.emitJump(OpJump)
+		if err := c.patchJump(elseJump); err != nil {
+			return nil, err
+		}
+	default: // And
+		endJump = c.emitJump(OpJumpIfFalse)
+	}
+	c.emitOp(OpPop)
+	if err := c.expression(l.Right); err != nil {
+		return nil, err
+	}
+	return nil, c.patchJump(endJump)
+}
+
+func (c *Compiler) VisitForConditional(e *expr.Conditional[any]) (any, error) {
+	if err := c.expression(e.Condition); err != nil {
+		return nil, err
+	}
+	c.token = e.Question
+	thenJump := c.emitJump(OpJumpIfFalse)
+	c.emitOp(OpPop)
+	if err := c.expression(e.Then); err != nil {
+		return nil, err
+	}
+	elseJump := c.emitJump(OpJump)
+	if err := c.patchJump(thenJump); err != nil {
+		return nil, err
+	}
+	c.emitOp(OpPop)
+	if err := c.expression(e.Else); err != nil {
+		return nil, err
+	}
+	return nil, c.patchJump(elseJump)
+}
+
+func (c *Compiler) VisitForOptionalGet(g *expr.OptionalGet[any]) (any, error) {
+	if err := c.expression(g.Object); err != nil {
+		return nil, err
+	}
+	c.token = g.Name
+	c.optionalJumps = append(c.optionalJumps, c.emitJump(OpJumpIfNil))
+	c.emitOpShort(OpGetProperty, c.identifierConstant(g.Name.Lexeme))
+	return nil, nil
+}
+
+func (c *Compiler) VisitForOptionalChain(o *expr.OptionalChain[any]) (any, error) {
+	enclosing := c.optionalJumps
+	c.optionalJumps = nil
+	err := c.expression(o.Expression)
+	jumps := c.optionalJumps
+	c.optionalJumps = enclosing
+	if err != nil {
+		return nil, err
+	}
+	// the nil found by the optional access is the result of the chain
+	return nil, c.patchJumps(jumps)
+}
+
+func (c *Compiler) VisitForSuper(s *expr.Super[any]) (any, error) {
+	c.token = s.Keyword
+	if err := c.namedVariable(tokens.Token{Lexeme: "this", Line: s.Keyword.Line}, false); err != nil {
+		return nil, err
+	}
+	if err := c.namedVariable(s.Keyword, false); err != nil {
+		return nil, err
+	}
+	c.token = s.Method
+	c.emitOpShort(OpGetSuper, c.identifierConstant(s.Method.Lexeme))
+	return nil, nil
+}
+
+func (c *Compiler) VisitForThis(t *expr.This[any]) (any, error) {
+	return nil, c.namedVariable(t.Keyword, false)
+}
+
+func (c *Compiler) VisitForUnary(u *expr.Unary[any]) (any, error) {
+	if err := c.expression(u.Right); err != nil {
+		return nil, err
+	}
+	c.token = u.Operator
+	if u.Operator.TokenType == tokens.Minus {
+		c.emitOp(OpNegate)
+	} else {
+		c.emitOp(OpNot)
+	}
+	return nil, nil
+}
+
+func (c *Compiler) VisitForVariable(v *expr.Variable[any]) (any, error) {
+	return nil, c.namedVariable(v.Name, false)
+}
+
+// namedVariable emits the code to get the variable, or to set it with the value on top of the stack
+func (c *Compiler) namedVariable(name tokens.Token, assign bool) error {
+	c.token = name
+	getOp, setOp := OpGetLocal, OpSetLocal
+	arg, err := c.resolveLocal(c.current, name)
+	if err != nil {
+		return err
+	}
+	if arg < 0 {
+		getOp, setOp = OpGetUpvalue, OpSetUpvalue
+		if arg, err = c.resolveUpvalue(c.current, name); err != nil {
+			return err
+		}
+	}
+	if arg >= 0 {
+		op := getOp
+		if assign {
+			op = setOp
+		}
+		c.emitBytes(byte(op), byte(arg))
+		return nil
+	}
+
+	op := OpGetGlobal
+	if assign {
+		op = OpSetGlobal
+	}
+	c.emitOpShort(op, c.identifierConstant(name.Lexeme))
+	return nil
+}
+
+func (c *Compiler) resolveLocal(fc *functionCompiler, name tokens.Token) (int, error) {
+	for i := len(fc.locals) - 1; i >= 0; i-- {
+		if fc.locals[i].name == name.Lexeme {
+			if fc.locals[i].depth == -1 {
+				return 0, &CompileError{Token: name, Message: "Can't read local variable in its own initializer."}
+			}
+			return i, nil
+		}
+	}
+	return -1, nil
+}
+
+func (c *Compiler) resolveUpvalue(fc *functionCompiler, name tokens.Token) (int, error) {
+	if fc.enclosing == nil {
+		return -1, nil
+	}
+	local, err := c.resolveLocal(fc.enclosing, name)
+	if err != nil {
+		return 0, err
+	}
+	if local >= 0 {
+		fc.enclosing.locals[local].isCaptured = true
+		return c.addUpvalue(fc, name, uint8(local), true)
+	}
+	upvalue, err := c.resolveUpvalue(fc.enclosing, name)
+	if err != nil || upvalue < 0 {
+		return upvalue, err
+	}
+	return c.addUpvalue(fc, name, uint8(upvalue), false)
+}
+
+func (c *Compiler) addUpvalue(fc *functionCompiler, name tokens.Token, index uint8, isLocal bool) (int, error) {
+	for i, upvalue := range fc.upvalues {
+		if upvalue.index == index && upvalue.isLocal == isLocal {
+			return i, nil
+		}
+	}
+	if len(fc.upvalues) == maxUpvalues {
+		return 0, &CompileError{Token: name, Message: "Too many closure variables in function."}
+	}
+	fc.upvalues = append(fc.upvalues, upvalue{index: index, isLocal: isLocal})
+	fc.function.UpvalueCount = len(fc.upvalues)
+	return len(fc.upvalues) - 1, nil
+}
+
+// declareVariable registers a local variable, globals are late bound so there is nothing to do for them
+func (c *Compiler) declareVariable(name tokens.Token) error {
+	if c.current.scopeDepth == 0 {
+		return nil
+	}
+	return c.addLocal(name)
+}
+
+func (c *Compiler) addLocal(name tokens.Token) error {
+	if len(c.current.locals) == maxLocals {
+		return &CompileError{Token: name, Message: "Too many local variables in function."}
+	}
+	c.current.locals = append(c.current.locals, local{name: name.Lexeme, depth: -1})
+	return nil
+}
+
+// defineVariable makes the variable available: the value on top of the stack becomes the local variable or
+// it is stored in the global with the provided name.
+func (c *Compiler) defineVariable(name int) {
+	if c.current.scopeDepth > 0 {
+		c.markInitialized()
+		return
+	}
+	c.emitOpShort(OpDefineGlobal, name)
+}
+
+func (c *Compiler) markInitialized() {
+	if c.current.scopeDepth == 0 {
+		return
+	}
+	c.current.locals[len(c.current.locals)-1].depth = c.current.scopeDepth
+}
+
+func (c *Compiler) beginScope() {
+	c.current.scopeDepth++
+}
+
+func (c *Compiler) endScope() {
+	fc := c.current
+	fc.scopeDepth--
+	for len(fc.locals) > 0 && fc.locals[len(fc.locals)-1].depth > fc.scopeDepth {
+		if fc.locals[len(fc.locals)-1].isCaptured {
+			c.emitOp(OpCloseUpvalue)
+		} else {
+			c.emitOp(OpPop)
+		}
+		fc.locals = fc.locals[:len(fc.locals)-1]
+	}
+}
+
+func (c *Compiler) chunk() *Chunk {
+	return &c.current.function.Chunk
+}
+
+func (c *Compiler) emitOp(op OpCode) {
+	c.chunk().Write(byte(op), c.token)
+}
+
+func (c *Compiler) emitBytes(bytes ...byte) {
+	for _, b := range bytes {
+		c.chunk().Write(b, c.token)
+	}
+}
+
+// emitOpShort emits an instruction with a two bytes operand
+func (c *Compiler) emitOpShort(op OpCode, operand int) {
+	c.emitBytes(byte(op), byte(operand>>8), byte(operand))
+}
+
+func (c *Compiler) emitImplicitReturnValue() {
+	if c.current.kind == functionKindInitializer {
+		c.emitBytes(byte(OpGetLocal), 0)
+	} else {
+		c.emitOp(OpNil)
+	}
+}
+
+func (c *Compiler) emitReturn() {
+	c.emitImplicitReturnValue()
+	c.emitOp(OpReturn)
+}
+
+// emitJump emits a jump instruction with a placeholder offset and returns the offset where it is placed
+func (c *Compiler) emitJump(op OpCode) int {
+	c.emitBytes(byte(op), 0xff, 0xff)
+	return len(c.chunk().Code) - 2
+}
+
+// patchJump sets the offset of the jump in the provided position to the end of the current code
+func (c *Compiler) patchJump(offset int) error {
+	jump := len(c.chunk().Code) - offset - 2
+	if jump > math.MaxUint16 {
+		return &CompileError{Token: c.token, Message: "Too much code to jump over."}
+	}
+	c.chunk().Code[offset] = byte(jump >> 8)
+	c.chunk().Code[offset+1] = byte(jump)
+	return nil
+}
+
+func (c *Compiler) patchJumps(offsets []int) error {
+	for _, offset := range offsets {
+		if err := c.patchJump(offset); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Compiler) emitLoop(loopStart int) error {
+	c.emitOp(OpLoop)
+	offset := len(c.chunk().Code) - loopStart + 2
+	if offset > math.MaxUint16 {
+		return &CompileError{Token: c.token, Message: "Loop body too large."}
+	}
+	c.emitBytes(byte(offset>>8), byte(offset))
+	return nil
+}
+
+// makeConstant adds the value to the constant pool reusing the existing entry for equal numbers and strings
+func (c *Compiler) makeConstant(value any) int {
+	switch value.(type) {
+	case float64, string:
+		if index, exists := c.current.constants[value]; exists {
+			return index
+		}
+	}
+	index := c.chunk().AddConstant(value)
+	if index > math.MaxUint16 && c.err == nil {
+		c.err = &CompileError{Token: c.token, Message: "Too many constants in one chunk."}
+	}
+	c.current.constants[value] = index
+	return index
+}
+
+func (c *Compiler) identifierConstant(name string) int {
+	return c.makeConstant(name)
+}
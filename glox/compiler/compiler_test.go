@@ -0,0 +1,96 @@
+package compiler
+
+import (
+	"fmt"
+	"glox/errors"
+	"glox/parser"
+	"glox/scanner"
+	"glox/tokens"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func compile(t *testing.T, source string) (*Function, error) {
+	t.Helper()
+	reporter := errors.NewReporter(io.Discard)
+	s := scanner.NewScanner(source, reporter)
+	s.ScanTokens()
+	p := parser.NewParser[any](s.Tokens(), reporter)
+	statements, parseErrors := p.Parse()
+	require.Empty(t, parseErrors)
+	return Compile(statements)
+}
+
+func TestChunkTokens(t *testing.T) {
+	var c Chunk
+	first := tokens.Token{TokenType: tokens.Print, Lexeme: "print", Line: 1}
+	second := tokens.Token{TokenType: tokens.Plus, Lexeme: "+", Line: 2}
+	c.Write(byte(OpNil), first)
+	c.Write(byte(OpNil), first)
+	c.Write(byte(OpAdd), second)
+
+	require.Equal(t, first, c.Token(0))
+	require.Equal(t, first, c.Token(1))
+	require.Equal(t, second, c.Token(2))
+	require.Equal(t, 2, c.Line(2))
+	require.Len(t, c.tokens, 2)
+}
+
+func TestDisassemble(t *testing.T) {
+	function, err := compile(t, "var a = 1;\nprint a + 2;")
+	require.NoError(t, err)
+	expected := `== <script> ==
+0000    1 OP_CONSTANT         0 '1'
+0003    | OP_DEFINE_GLOBAL    1 'a'
+0006    2 OP_GET_GLOBAL       1 'a'
+0009    | OP_CONSTANT         2 '2'
+0012    | OP_ADD
+0013    | OP_PRINT
+0014    | OP_NIL
+0015    | OP_RETURN
+`
+	require.Equal(t, expected, function.Chunk.Disassemble(function.String()))
+}
+
+// declarations returns a block declaring count variables
+func declarations(count int) string {
+	var b strings.Builder
+	b.WriteString("{")
+	for i := 0; i < count; i++ {
+		fmt.Fprintf(&b, "var v%d;", i)
+	}
+	b.WriteString("}")
+	return b.String()
+}
+
+func TestCompileErrors(t *testing.T) {
+	cases := []struct {
+		name     string
+		source   string
+		expected string
+	}{
+		{
+			name:     "too many locals",
+			source:   "fun f() " + declarations(256),
+			expected: "Too many local variables in function.",
+		},
+		{
+			name:     "too much code to jump over",
+			source:   "if (true) { " + strings.Repeat("print 1 + 2 + 3 + 4 + 5;", 4000) + " }",
+			expected: "Too much code to jump over.",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := compile(t, tc.source)
+			var compileError *CompileError
+			require.ErrorAs(t, err, &compileError)
+			require.Equal(t, tc.expected, compileError.Message)
+		})
+	}
+}
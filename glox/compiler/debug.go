@@ -0,0 +1,71 @@
+package compiler
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Disassemble returns a human readable representation of the chunk's bytecode, including the functions it
+// contains.
+func (c *Chunk) Disassemble(name string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "== %s ==\n", name)
+	for offset := 0; offset < len(c.Code); {
+		offset = c.disassembleInstruction(&b, offset)
+	}
+	for _, constant := range c.Constants {
+		if f, isFunction := constant.(*Function); isFunction {
+			b.WriteString(f.Chunk.Disassemble(f.String()))
+		}
+	}
+	return b.String()
+}
+
+func (c *Chunk) disassembleInstruction(b *strings.Builder, offset int) int {
+	fmt.Fprintf(b, "%04d ", offset)
+	if offset > 0 && c.Line(offset) == c.Line(offset-1) {
+		b.WriteString("   | ")
+	} else {
+		fmt.Fprintf(b, "%4d ", c.Line(offset))
+	}
+
+	op := OpCode(c.Code[offset])
+	switch op {
+	case OpConstant, OpGetGlobal, OpDefineGlobal, OpSetGlobal, OpGetProperty, OpSetProperty, OpGetSuper,
+		OpClass, OpMethod:
+		index := c.readShort(offset + 1)
+		fmt.Fprintf(b, "%-16s %4d '%v'\n", op, index, c.Constants[index])
+		return offset + 3
+	case OpGetLocal, OpSetLocal, OpGetUpvalue, OpSetUpvalue, OpCall, OpInterpolate, OpCopy:
+		fmt.Fprintf(b, "%-16s %4d\n", op, c.Code[offset+1])
+		return offset + 2
+	case OpJump, OpJumpIfFalse, OpJumpIfNil, OpPushHandler, OpPushFinally:
+		jump := c.readShort(offset + 1)
+		fmt.Fprintf(b, "%-16s %4d -> %d\n", op, offset, offset+3+jump)
+		return offset + 3
+	case OpLoop:
+		jump := c.readShort(offset + 1)
+		fmt.Fprintf(b, "%-16s %4d -> %d\n", op, offset, offset+3-jump)
+		return offset + 3
+	case OpClosure:
+		index := c.readShort(offset + 1)
+		function := c.Constants[index].(*Function)
+		fmt.Fprintf(b, "%-16s %4d %v\n", op, index, function)
+		offset += 3
+		for i := 0; i < function.UpvalueCount; i++ {
+			kind := "upvalue"
+			if c.Code[offset] == 1 {
+				kind = "local"
+			}
+			fmt.Fprintf(b, "%04d    |                     %s %d\n", offset, kind, c.Code[offset+1])
+			offset += 2
+		}
+		return offset
+	}
+	fmt.Fprintf(b, "%s\n", op)
+	return offset + 1
+}
+
+func (c *Chunk) readShort(offset int) int {
+	return int(c.Code[offset])<<8 | int(c.Code[offset+1])
+}
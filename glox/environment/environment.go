@@ -6,47 +6,59 @@ import (
 	"glox/tokens"
 )
 
+// Environment holds the values of the variables in a scope. The global environment looks variables up by name,
+// local environments store their values by declaration order, matching the slots assigned by the resolver.
 type Environment struct {
 	Enclosing *Environment
-	values    map[string]any
+	values    []any
+	globals   map[string]any
 }
 
+// NewGlobals returns the environment holding the global variables
+func NewGlobals() *Environment {
+	return &Environment{globals: map[string]any{}}
+}
+
+// New returns a local environment nested in enclosing
 func New(enclosing *Environment) *Environment {
-	return &Environment{values: map[string]any{}, Enclosing: enclosing}
+	return &Environment{Enclosing: enclosing}
 }
 
+// Define defines a new variable. In local environments the name is not stored, the variable gets the next slot.
 func (e *Environment) Define(name string, value any) {
-	e.values[name] = value
+	if e.globals != nil {
+		e.globals[name] = value
+		return
+	}
+	e.values = append(e.values, value)
 }
 
+// Get returns the value of a global variable
 func (e *Environment) Get(name tokens.Token) (any, error) {
-	value, found := e.values[name.Lexeme]
+	value, found := e.globals[name.Lexeme]
 	if !found {
-		if e.Enclosing != nil {
-			return e.Enclosing.Get(name)
-		}
 		return nil, errors.NewRuntimeError(name, fmt.Sprintf("Undefined variable '%s'.", name.Lexeme))
 	}
 	return value, nil
 }
 
+// Assign sets the value of an existing global variable
 func (e *Environment) Assign(name tokens.Token, value any) error {
-	if _, defined := e.values[name.Lexeme]; defined {
-		e.values[name.Lexeme] = value
+	if _, defined := e.globals[name.Lexeme]; defined {
+		e.globals[name.Lexeme] = value
 		return nil
 	}
-	if e.Enclosing != nil {
-		return e.Enclosing.Assign(name, value)
-	}
 	return errors.NewRuntimeError(name, fmt.Sprintf("Undefined variable '%s'.", name.Lexeme))
 }
 
-func (e *Environment) GetAt(distance int, name string) any {
-	return e.ancestor(distance).values[name]
+// GetAt returns the value in the provided slot of the environment distance levels up
+func (e *Environment) GetAt(distance int, slot int) any {
+	return e.ancestor(distance).values[slot]
 }
 
-func (e *Environment) AssignAt(distance int, name tokens.Token, value any) {
-	e.ancestor(distance).values[name.Lexeme] = value
+// AssignAt sets the value in the provided slot of the environment distance levels up
+func (e *Environment) AssignAt(distance int, slot int, value any) {
+	e.ancestor(distance).values[slot] = value
 }
 
 func (e *Environment) ancestor(distance int) *Environment {
@@ -58,7 +70,11 @@ func (e *Environment) ancestor(distance int) *Environment {
 }
 
 func (e *Environment) Print() {
-	fmt.Printf("Values: %v\n", e.values)
+	if e.globals != nil {
+		fmt.Printf("Values: %v\n", e.globals)
+	} else {
+		fmt.Printf("Values: %v\n", e.values)
+	}
 	if e.Enclosing != nil {
 		fmt.Println("-->")
 		e.Enclosing.Print()
@@ -0,0 +1,188 @@
+package errors
+
+import (
+	"fmt"
+	"glox/tokens"
+	"strings"
+	"unicode/utf8"
+)
+
+type Severity int
+
+const (
+	SeverityError Severity = iota
+	SeverityWarning
+)
+
+func (s Severity) String() string {
+	if s == SeverityWarning {
+		return "warning"
+	}
+	return "error"
+}
+
+// Code identifies the kind of a diagnostic
+type Code string
+
+const (
+	CodeScan    Code = "E0001"
+	CodeParse   Code = "E0002"
+	CodeResolve Code = "E0003"
+	CodeRuntime Code = "E0004"
+	CodeCompile Code = "E0005"
+	CodeType    Code = "E0006"
+
+	CodeUnreachableCase Code = "W0001"
+	CodeDuplicateCase   Code = "W0002"
+	CodeUnusedLocal     Code = "W0003"
+	CodeUnusedParameter Code = "W0004"
+	CodeShadowed        Code = "W0005"
+	CodeUnreachableCode Code = "W0006"
+	CodeUnreadValue     Code = "W0007"
+	CodeNativeArity     Code = "W0008"
+)
+
+// Diagnostic holds the information of a reported error
+type Diagnostic struct {
+	Severity Severity
+	Code     Code
+	Message  string
+	// Where describes the token the diagnostic refers to, Eg: " at 'foo'"
+	Where string
+	File  string
+	Line  int
+	// Column is the 1-based column of the span start within its line
+	Column int
+	Span   tokens.Span
+	// Trace holds the calls active when a runtime error happened
+	Trace []StackFrame
+}
+
+// StackFrame describes a function call
+type StackFrame struct {
+	Function string
+	// Class is set when the function is a method
+	Class string
+	// Line is the line where the function was called
+	Line int
+}
+
+func (f StackFrame) String() string {
+	if f.Class != "" {
+		return fmt.Sprintf("%s.%s()", f.Class, f.Function)
+	}
+	return fmt.Sprintf("%s()", f.Function)
+}
+
+// IsRuntime tells if the diagnostic corresponds to a runtime error
+func (d Diagnostic) IsRuntime() bool {
+	return d.Code == CodeRuntime
+}
+
+// String returns the diagnostic in the short format: the one used by the book's implementation. Warnings, which the
+// book doesn't have, include their code so they can be disabled.
+func (d Diagnostic) String() string {
+	if d.IsRuntime() {
+		return fmt.Sprintf("%s\n[line %d]%s", d.Message, d.Line, d.traceback())
+	}
+	if d.Severity == SeverityWarning {
+		return fmt.Sprintf("[line %d] Warning[%s]%s: %s", d.Line, d.Code, d.Where, d.Message)
+	}
+	return fmt.Sprintf("[line %d] Error%s: %s", d.Line, d.Where, d.Message)
+}
+
+// Render returns the diagnostic in a rustc-like format, showing the line of the provided source where the
+// diagnostic happened and underlining its span. Eg:
+//
+//	error[E0002]: Expect ';' after value.
+//	 --> script.glox:1:8
+//	  |
+//	1 | print 1
+//	  |        ^
+func (d Diagnostic) Render(source string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s[%s]: %s\n", d.Severity, d.Code, d.Message)
+
+	start := min(max(d.Span.Start, 0), len(source))
+	end := min(max(d.Span.End, start), len(source))
+	lineStart := strings.LastIndexByte(source[:start], '\n') + 1
+	lineEnd := len(source)
+	if i := strings.IndexByte(source[start:], '\n'); i >= 0 {
+		lineEnd = start + i
+	}
+	line := strings.Count(source[:start], "\n") + 1
+	column := utf8.RuneCountInString(source[lineStart:start]) + 1
+	fmt.Fprintf(&b, " --> %s:%d:%d\n", d.File, line, column)
+
+	gutter := strings.Repeat(" ", len(fmt.Sprint(line)))
+	fmt.Fprintf(&b, "%s |\n", gutter)
+	fmt.Fprintf(&b, "%d | %s\n", line, strings.TrimRight(source[lineStart:lineEnd], "\r"))
+
+	// underline up to the end of the line for multi-line spans
+	width := max(utf8.RuneCountInString(source[start:min(end, lineEnd)]), 1)
+	fmt.Fprintf(&b, "%s | %s%s", gutter, indentation(source[lineStart:start]), strings.Repeat("^", width))
+	b.WriteString(d.traceback())
+	return b.String()
+}
+
+// traceback describes the calls of the diagnostic trace, the most recent call last. Eg:
+//
+//	Traceback (most recent call last):
+//	  [line 10] in script
+//	  [line 6] in outer()
+//	  [line 2] in Point.inner()
+//
+// Runs of identical calls, Eg: in a deep recursion, are collapsed after the first maxRepeatedFrames.
+func (d Diagnostic) traceback() string {
+	if len(d.Trace) == 0 {
+		return ""
+	}
+	calls := make([]string, 0, len(d.Trace)+1)
+	caller := "script"
+	for _, frame := range d.Trace {
+		calls = append(calls, fmt.Sprintf("[line %d] in %s", frame.Line, caller))
+		caller = frame.String()
+	}
+	calls = append(calls, fmt.Sprintf("[line %d] in %s", d.Line, caller))
+
+	var b strings.Builder
+	b.WriteString("\nTraceback (most recent call last):")
+	repeated := 0
+	for n, call := range calls {
+		if n > 0 && call == calls[n-1] {
+			repeated++
+		} else {
+			writeRepeated(&b, repeated)
+			repeated = 0
+		}
+		if repeated < maxRepeatedFrames {
+			fmt.Fprintf(&b, "\n  %s", call)
+		}
+	}
+	writeRepeated(&b, repeated)
+	return b.String()
+}
+
+// maxRepeatedFrames is the number of times an identical call is shown in a traceback before collapsing the rest
+const maxRepeatedFrames = 3
+
+// writeRepeated notes the calls of a run that were not shown
+func writeRepeated(b *strings.Builder, repeated int) {
+	if hidden := repeated - maxRepeatedFrames + 1; hidden > 0 {
+		fmt.Fprintf(b, "\n  [Previous line repeated %d more times]", hidden)
+	}
+}
+
+// indentation returns the blank text needed to align a marker under the end of the provided text, keeping tabs
+// so the alignment is preserved.
+func indentation(text string) string {
+	var b strings.Builder
+	for _, r := range text {
+		if r == '\t' {
+			b.WriteRune('\t')
+		} else {
+			b.WriteRune(' ')
+		}
+	}
+	return b.String()
+}
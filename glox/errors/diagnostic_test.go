@@ -0,0 +1,98 @@
+package errors
+
+import (
+	"fmt"
+	"glox/tokens"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRender(t *testing.T) {
+	source := "var a = 1;\n\tprint a - \"b\";\n"
+	d := Diagnostic{
+		Severity: SeverityError,
+		Code:     CodeRuntime,
+		Message:  "Operands must be numbers.",
+		File:     "script.glox",
+		Span:     tokens.Span{Start: 22, End: 25},
+	}
+	expected := "error[E0004]: Operands must be numbers.\n" +
+		" --> script.glox:2:12\n" +
+		"  |\n" +
+		"2 | \tprint a - \"b\";\n" +
+		"  | \t          ^^^"
+	require.Equal(t, expected, d.Render(source))
+}
+
+func TestRenderAtEnd(t *testing.T) {
+	d := Diagnostic{Severity: SeverityWarning, Code: CodeParse, Message: "Oops.", File: "f", Span: tokens.Span{Start: 7, End: 7}}
+	expected := "warning[E0002]: Oops.\n" +
+		" --> f:1:8\n" +
+		"  |\n" +
+		"1 | print 1\n" +
+		"  |        ^"
+	require.Equal(t, expected, d.Render("print 1"))
+}
+
+func TestString(t *testing.T) {
+	d := Diagnostic{Severity: SeverityError, Code: CodeParse, Message: "Expect expression.", Where: " at ';'", Line: 3}
+	require.Equal(t, "[line 3] Error at ';': Expect expression.", d.String())
+	d = Diagnostic{Severity: SeverityError, Code: CodeRuntime, Message: "Operands must be numbers.", Line: 2}
+	require.Equal(t, "Operands must be numbers.\n[line 2]", d.String())
+	d = Diagnostic{Severity: SeverityWarning, Code: CodeUnusedLocal, Message: "Local variable 'a' is never used.", Where: " at 'a'", Line: 1}
+	require.Equal(t, "[line 1] Warning[W0003] at 'a': Local variable 'a' is never used.", d.String())
+}
+
+func TestStringCollapsesRepeatedFrames(t *testing.T) {
+	frame := StackFrame{Function: "f", Line: 2}
+	d := Diagnostic{Severity: SeverityError, Code: CodeRuntime, Message: "Stack overflow.", Line: 2, Trace: []StackFrame{{Function: "f", Line: 4}, frame, frame, frame, frame}}
+	expected := "Stack overflow.\n[line 2]\n" +
+		"Traceback (most recent call last):\n" +
+		"  [line 4] in script\n" +
+		"  [line 2] in f()\n" +
+		"  [line 2] in f()\n" +
+		"  [line 2] in f()\n" +
+		"  [Previous line repeated 2 more times]"
+	require.Equal(t, expected, d.String())
+}
+
+func TestReporterRendersTokenFile(t *testing.T) {
+	var out strings.Builder
+	r := NewReporter(&out)
+	r.SetSource("module.glox", "print nil;")
+	r.SetSource("main.glox", "import \"module.glox\" as m;")
+
+	r.AtToken(CodeParse, tokens.Token{TokenType: tokens.Nil, Lexeme: "nil", Line: 1, Column: 7, Offset: 6, File: "module.glox"}, "Oops.")
+	require.Equal(t, "module.glox", r.Diagnostics()[0].File)
+	require.Contains(t, out.String(), "1 | print nil;\n")
+
+	// diagnostics without a file refer to the current one
+	r.AtSpan(CodeScan, 1, 1, tokens.Span{Start: 0, End: 6}, "Oops.")
+	require.Equal(t, "main.glox", r.Diagnostics()[1].File)
+	require.Contains(t, out.String(), "1 | import \"module.glox\" as m;\n")
+}
+
+func TestReporterIgnoresWarnings(t *testing.T) {
+	r := NewReporter(io.Discard)
+	r.SetSource("f.glox", "var a; // glox:ignore W0003,W0005\nvar b;\nvar c;")
+	r.Comment(1, "// glox:ignore W0003,W0005")
+	at := func(line int) tokens.Token {
+		return tokens.Token{TokenType: tokens.Identifier, Lexeme: "a", Line: line, File: "f.glox"}
+	}
+
+	r.Warn(CodeUnusedLocal, at(1), "Oops.")
+	r.Warn(CodeShadowed, at(2), "Oops.")
+	r.Warn(CodeUnusedLocal, at(3), "Oops.")
+	r.Warn(CodeUnusedParameter, at(1), "Oops.")
+	// errors can't be ignored
+	r.AtToken(CodeResolve, at(1), "Oops.")
+
+	var reported []string
+	for _, d := range r.Diagnostics() {
+		reported = append(reported, fmt.Sprintf("%d %s", d.Line, d.Code))
+	}
+	require.Equal(t, []string{"3 W0003", "1 W0004", "1 E0003"}, reported)
+}
@@ -3,15 +3,17 @@ package errors
 import (
 	"fmt"
 	"glox/tokens"
-	"os"
+	"io"
+	"slices"
 )
 
-var errorFound = false
-var runtimeErrorFound = false
-
 type RuntimeError struct {
 	token   tokens.Token
 	message string
+	trace   []StackFrame
+	// value holds the value thrown by a throw statement, if any
+	value  any
+	thrown bool
 }
 
 func (e *RuntimeError) Error() string {
@@ -22,40 +24,168 @@ func (e *RuntimeError) Token() tokens.Token {
 	return e.token
 }
 
+// Trace returns the calls that were active when the error happened, the outermost first
+func (e *RuntimeError) Trace() []StackFrame {
+	return e.trace
+}
+
+func (e *RuntimeError) SetTrace(trace []StackFrame) {
+	e.trace = trace
+}
+
+// Thrown returns the value thrown by a throw statement, it returns false if the error was not thrown
+// by the program
+func (e *RuntimeError) Thrown() (any, bool) {
+	return e.value, e.thrown
+}
+
 func NewRuntimeError(token tokens.Token, message string) *RuntimeError {
 	return &RuntimeError{token: token, message: message}
 }
 
-func AtLine(line int, message string) {
-	Report(line, "", message)
+// NewThrowError returns the error produced when a program throws the provided value
+func NewThrowError(token tokens.Token, message string, value any) *RuntimeError {
+	return &RuntimeError{token: token, message: message, value: value, thrown: true}
+}
+
+type Format int
+
+const (
+	// FormatPretty renders diagnostics showing the corresponding source snippet
+	FormatPretty Format = iota
+	// FormatShort renders diagnostics in a single line, as the book's implementation does
+	FormatShort
+)
+
+// Reporter keeps track of the errors found while running a program and prints them to the provided writer.
+// Each program run should use its own reporter, so several programs can run without interfering with each other.
+type Reporter struct {
+	out     io.Writer
+	format  Format
+	file    string
+	sources map[string]string
+	// ignored holds the warning codes disabled by pragmas, by file and line
+	ignored           map[string]map[int][]Code
+	diagnostics       []Diagnostic
+	errorFound        bool
+	runtimeErrorFound bool
+}
+
+func NewReporter(out io.Writer) *Reporter {
+	return &Reporter{out: out, sources: map[string]string{}, ignored: map[string]map[int][]Code{}}
+}
+
+// SetFormat sets the format used to print diagnostics
+func (r *Reporter) SetFormat(format Format) {
+	r.format = format
+}
+
+// SetSource sets the source (and the name of the file it belongs to) being processed. Diagnostics are rendered
+// using the source of the file their token belongs to, every source set is kept for that purpose.
+func (r *Reporter) SetSource(file string, source string) {
+	r.file = file
+	r.sources[file] = source
+	r.ignored[file] = map[int][]Code{}
+}
+
+// Comment records a comment found in the provided line of the file being processed. The warnings disabled by
+// `// glox:ignore` pragmas are not reported.
+func (r *Reporter) Comment(line int, comment string) {
+	codes, isPragma := ignoredCodes(comment)
+	if !isPragma {
+		return
+	}
+	ignored := r.ignored[r.file]
+	if ignored == nil {
+		ignored = map[int][]Code{}
+		r.ignored[r.file] = ignored
+	}
+	ignored[line] = append(ignored[line], codes...)
+	ignored[line+1] = append(ignored[line+1], codes...)
+}
+
+// File returns the name of the file being processed
+func (r *Reporter) File() string {
+	return r.file
+}
+
+// SetFile sets the file being processed, its source must have been already set
+func (r *Reporter) SetFile(file string) {
+	r.file = file
+}
+
+// AtSpan reports an error happening at the provided location
+func (r *Reporter) AtSpan(code Code, line int, column int, span tokens.Span, message string) {
+	r.errorFound = true
+	r.add(Diagnostic{Severity: SeverityError, Code: code, Message: message, Line: line, Column: column, Span: span})
+}
+
+// AtToken reports an error happening at the provided token
+func (r *Reporter) AtToken(code Code, token tokens.Token, message string) {
+	r.errorFound = true
+	r.add(tokenDiagnostic(SeverityError, code, token, message))
 }
 
-func AtToken(token tokens.Token, message string) {
+// Warn reports a problem at the provided token that does not prevent the program from running
+func (r *Reporter) Warn(code Code, token tokens.Token, message string) {
+	r.add(tokenDiagnostic(SeverityWarning, code, token, message))
+}
+
+func (r *Reporter) ReportRuntimeError(e *RuntimeError) {
+	r.runtimeErrorFound = true
+	d := tokenDiagnostic(SeverityError, CodeRuntime, e.token, e.message)
+	d.Trace = e.trace
+	r.add(d)
+}
+
+func tokenDiagnostic(severity Severity, code Code, token tokens.Token, message string) Diagnostic {
+	where := fmt.Sprintf(" at '%s'", token.Lexeme)
 	if token.TokenType == tokens.Eof {
-		Report(token.Line, " at end", message)
-	} else {
-		Report(token.Line, fmt.Sprintf(" at '%s'", token.Lexeme), message)
+		where = " at end"
+	}
+	return Diagnostic{
+		Severity: severity,
+		Code:     code,
+		Message:  message,
+		Where:    where,
+		Line:     token.Line,
+		Column:   token.Column,
+		Span:     token.Span(),
+		File:     token.File,
 	}
 }
 
-func Report(line int, where string, message string) {
-	errorFound = true
-	fmt.Fprintf(os.Stderr, "[line %d] Error%s: %s\n", line, where, message)
+func (r *Reporter) add(d Diagnostic) {
+	if d.File == "" {
+		d.File = r.file
+	}
+	if d.Severity == SeverityWarning && slices.Contains(r.ignored[d.File][d.Line], d.Code) {
+		return
+	}
+	r.diagnostics = append(r.diagnostics, d)
+	if r.format == FormatShort {
+		fmt.Fprintln(r.out, d)
+	} else {
+		fmt.Fprintf(r.out, "%s\n\n", d.Render(r.sources[d.File]))
+	}
 }
 
-func ReportRuntimeError(e *RuntimeError) {
-	runtimeErrorFound = true
-	fmt.Fprintf(os.Stderr, "%s\n[line %d]", e.message, e.token.Line)
+// Diagnostics returns the diagnostics reported since the last reset
+func (r *Reporter) Diagnostics() []Diagnostic {
+	return r.diagnostics
 }
 
-func ErrorFound() bool {
-	return errorFound
+func (r *Reporter) ErrorFound() bool {
+	return r.errorFound
 }
 
-func RuntimeErrorFound() bool {
-	return runtimeErrorFound
+func (r *Reporter) RuntimeErrorFound() bool {
+	return r.runtimeErrorFound
 }
 
-func ResetError() {
-	errorFound = false
+// Reset clears the reported diagnostics and error flags
+func (r *Reporter) Reset() {
+	r.diagnostics = nil
+	r.errorFound = false
+	r.runtimeErrorFound = false
 }
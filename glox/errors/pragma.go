@@ -0,0 +1,26 @@
+package errors
+
+import (
+	"strings"
+)
+
+// ignorePragma is the comment that disables warnings, Eg: `// glox:ignore W0003 W0005`. It applies to the line it is
+// written in and to the next one, so it can be placed at the end of a line or right before it.
+const ignorePragma = "// glox:ignore"
+
+// ignoredCodes returns the warning codes disabled by the comment, it returns false if the comment is not a pragma
+func ignoredCodes(comment string) ([]Code, bool) {
+	pragma, found := strings.CutPrefix(comment, ignorePragma)
+	if !found {
+		return nil, false
+	}
+	var codes []Code
+	for _, code := range strings.FieldsFunc(pragma, isCodeSeparator) {
+		codes = append(codes, Code(code))
+	}
+	return codes, true
+}
+
+func isCodeSeparator(r rune) bool {
+	return r == ' ' || r == '\t' || r == ',' || r == '\r'
+}
@@ -36,6 +36,53 @@ func (e *Call[T]) Accept(v Visitor[T]) (T, error) {
 	return v.VisitForCall(e)
 }
 
+type CompoundAssign[T any] struct {
+	Name     tokens.Token
+	Operator tokens.Token
+	Value    Expr[T]
+	Postfix  bool
+}
+
+func (e *CompoundAssign[T]) Accept(v Visitor[T]) (T, error) {
+	return v.VisitForCompoundAssign(e)
+}
+
+type CompoundSet[T any] struct {
+	Object   Expr[T]
+	Name     tokens.Token
+	Operator tokens.Token
+	Value    Expr[T]
+	Postfix  bool
+}
+
+func (e *CompoundSet[T]) Accept(v Visitor[T]) (T, error) {
+	return v.VisitForCompoundSet(e)
+}
+
+type CompoundIndexSet[T any] struct {
+	Object   Expr[T]
+	Bracket  tokens.Token
+	Index    Expr[T]
+	Operator tokens.Token
+	Value    Expr[T]
+	Postfix  bool
+}
+
+func (e *CompoundIndexSet[T]) Accept(v Visitor[T]) (T, error) {
+	return v.VisitForCompoundIndexSet(e)
+}
+
+type Conditional[T any] struct {
+	Condition Expr[T]
+	Question  tokens.Token
+	Then      Expr[T]
+	Else      Expr[T]
+}
+
+func (e *Conditional[T]) Accept(v Visitor[T]) (T, error) {
+	return v.VisitForConditional(e)
+}
+
 type Get[T any] struct {
 	Object Expr[T]
 	Name   tokens.Token
@@ -53,6 +100,64 @@ func (e *Grouping[T]) Accept(v Visitor[T]) (T, error) {
 	return v.VisitForGrouping(e)
 }
 
+type Index[T any] struct {
+	Object  Expr[T]
+	Bracket tokens.Token
+	Index   Expr[T]
+}
+
+func (e *Index[T]) Accept(v Visitor[T]) (T, error) {
+	return v.VisitForIndex(e)
+}
+
+type IndexSet[T any] struct {
+	Object  Expr[T]
+	Bracket tokens.Token
+	Index   Expr[T]
+	Value   Expr[T]
+}
+
+func (e *IndexSet[T]) Accept(v Visitor[T]) (T, error) {
+	return v.VisitForIndexSet(e)
+}
+
+type Interpolation[T any] struct {
+	Start tokens.Token
+	Parts []Expr[T]
+}
+
+func (e *Interpolation[T]) Accept(v Visitor[T]) (T, error) {
+	return v.VisitForInterpolation(e)
+}
+
+type Lambda[T any] struct {
+	Keyword  tokens.Token
+	Function any
+}
+
+func (e *Lambda[T]) Accept(v Visitor[T]) (T, error) {
+	return v.VisitForLambda(e)
+}
+
+type List[T any] struct {
+	Bracket  tokens.Token
+	Elements []Expr[T]
+}
+
+func (e *List[T]) Accept(v Visitor[T]) (T, error) {
+	return v.VisitForList(e)
+}
+
+type Map[T any] struct {
+	Brace  tokens.Token
+	Keys   []Expr[T]
+	Values []Expr[T]
+}
+
+func (e *Map[T]) Accept(v Visitor[T]) (T, error) {
+	return v.VisitForMap(e)
+}
+
 type Literal[T any] struct {
 	Value any
 }
@@ -61,6 +166,23 @@ func (e *Literal[T]) Accept(v Visitor[T]) (T, error) {
 	return v.VisitForLiteral(e)
 }
 
+type OptionalGet[T any] struct {
+	Object Expr[T]
+	Name   tokens.Token
+}
+
+func (e *OptionalGet[T]) Accept(v Visitor[T]) (T, error) {
+	return v.VisitForOptionalGet(e)
+}
+
+type OptionalChain[T any] struct {
+	Expression Expr[T]
+}
+
+func (e *OptionalChain[T]) Accept(v Visitor[T]) (T, error) {
+	return v.VisitForOptionalChain(e)
+}
+
 type Unary[T any] struct {
 	Operator tokens.Token
 	Right    Expr[T]
@@ -80,6 +202,17 @@ func (e *Set[T]) Accept(v Visitor[T]) (T, error) {
 	return v.VisitForSet(e)
 }
 
+type Slice[T any] struct {
+	Object  Expr[T]
+	Bracket tokens.Token
+	Start   Expr[T]
+	End     Expr[T]
+}
+
+func (e *Slice[T]) Accept(v Visitor[T]) (T, error) {
+	return v.VisitForSlice(e)
+}
+
 type Super[T any] struct {
 	Keyword tokens.Token
 	Method  tokens.Token
@@ -119,11 +252,24 @@ type Visitor[T any] interface {
 	VisitForAssign(*Assign[T]) (T, error)
 	VisitForBinary(*Binary[T]) (T, error)
 	VisitForCall(*Call[T]) (T, error)
+	VisitForCompoundAssign(*CompoundAssign[T]) (T, error)
+	VisitForCompoundSet(*CompoundSet[T]) (T, error)
+	VisitForCompoundIndexSet(*CompoundIndexSet[T]) (T, error)
+	VisitForConditional(*Conditional[T]) (T, error)
 	VisitForGet(*Get[T]) (T, error)
 	VisitForGrouping(*Grouping[T]) (T, error)
+	VisitForIndex(*Index[T]) (T, error)
+	VisitForIndexSet(*IndexSet[T]) (T, error)
+	VisitForInterpolation(*Interpolation[T]) (T, error)
+	VisitForLambda(*Lambda[T]) (T, error)
+	VisitForList(*List[T]) (T, error)
+	VisitForMap(*Map[T]) (T, error)
 	VisitForLiteral(*Literal[T]) (T, error)
+	VisitForOptionalGet(*OptionalGet[T]) (T, error)
+	VisitForOptionalChain(*OptionalChain[T]) (T, error)
 	VisitForUnary(*Unary[T]) (T, error)
 	VisitForSet(*Set[T]) (T, error)
+	VisitForSlice(*Slice[T]) (T, error)
 	VisitForSuper(*Super[T]) (T, error)
 	VisitForThis(*This[T]) (T, error)
 	VisitForLogical(*Logical[T]) (T, error)
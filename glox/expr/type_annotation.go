@@ -0,0 +1,11 @@
+package expr
+
+import "glox/tokens"
+
+// TypeAnnotation is the type declared for a variable, parameter, field or function result, Eg: `num` or `Point?`.
+// Annotations are checked before running the program, they have no effect at runtime.
+type TypeAnnotation struct {
+	Name tokens.Token
+	// Optional is set when the type accepts nil as well, Eg: `str?`
+	Optional bool
+}
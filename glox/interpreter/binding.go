@@ -0,0 +1,363 @@
+package interpreter
+
+import (
+	"fmt"
+	"glox/errors"
+	"glox/tokens"
+	"math"
+	"reflect"
+	"runtime"
+	"strings"
+	"unicode"
+)
+
+// DefineNative defines a global function implemented in Go. Errors returned by fn that are not runtime errors are
+// reported at the call site.
+func (i *Interpreter) DefineNative(name string, arity int, fn func(arguments []any) (any, error)) {
+	i.defineNative(name, arity, arity, fn)
+}
+
+// DefineVariadicNative works as DefineNative, the function accepts any number of arguments from minArity on
+func (i *Interpreter) DefineVariadicNative(name string, minArity int, fn func(arguments []any) (any, error)) {
+	i.defineNative(name, minArity, NoMaxArity, fn)
+}
+
+func (i *Interpreter) defineNative(name string, arity int, maxArity int, fn func(arguments []any) (any, error)) {
+	i.defineHostGlobal(name, &nativeFunction{name: name, arity: arity, maxArity: maxArity, fn: func(_ *Interpreter, arguments []any) (any, error) {
+		return fn(arguments)
+	}})
+}
+
+// Bind defines a global variable holding the provided Go value. Functions can be called from glox, their
+// arguments and results are converted automatically, and structs are exposed as instances whose exported fields
+// and methods can be accessed by name, Eg: `point.x` or `point.X` for the field `X`.
+func (i *Interpreter) Bind(name string, value any) error {
+	v := reflect.ValueOf(value)
+	var loxValue any
+	var err error
+	if v.Kind() == reflect.Func {
+		loxValue, err = bindFunction(name, v)
+	} else {
+		loxValue, err = toLox(v)
+	}
+	if err != nil {
+		return fmt.Errorf("can't bind '%s': %w", name, err)
+	}
+	i.defineHostGlobal(name, loxValue)
+	return nil
+}
+
+// HostObject is a Go struct exposed to glox programs
+type HostObject struct {
+	value reflect.Value // pointer to the struct
+}
+
+func (o *HostObject) String() string {
+	return o.value.Elem().Type().Name() + " instance"
+}
+
+// Value returns the pointer to the Go struct
+func (o *HostObject) Value() any {
+	return o.value.Interface()
+}
+
+func (o *HostObject) Get(name tokens.Token) (any, error) {
+	goName := exportedName(name.Lexeme)
+	if field := o.value.Elem().FieldByName(goName); field.IsValid() && field.CanInterface() {
+		value, err := toLox(field)
+		if err != nil {
+			return nil, errors.NewRuntimeError(name, fmt.Sprintf("Can't read property '%s': %s.", name.Lexeme, err))
+		}
+		return value, nil
+	}
+	if method := o.value.MethodByName(goName); method.IsValid() {
+		return bindFunction(name.Lexeme, method)
+	}
+	return nil, errors.NewRuntimeError(name, fmt.Sprintf("Undefined property '%s'.", name.Lexeme))
+}
+
+func (o *HostObject) Set(name tokens.Token, value any) error {
+	field := o.value.Elem().FieldByName(exportedName(name.Lexeme))
+	if !field.IsValid() || !field.CanSet() {
+		return errors.NewRuntimeError(name, fmt.Sprintf("Undefined property '%s'.", name.Lexeme))
+	}
+	goValue, err := fromLox(value, field.Type())
+	if err != nil {
+		return errors.NewRuntimeError(name, fmt.Sprintf("Property '%s' must be %s.", name.Lexeme, err))
+	}
+	field.Set(goValue)
+	return nil
+}
+
+// exportedName returns the Go name of a property, properties can be written starting by a lowercase letter
+func exportedName(name string) string {
+	runes := []rune(name)
+	runes[0] = unicode.ToUpper(runes[0])
+	return string(runes)
+}
+
+// bindFunction returns a native function calling the provided Go function. It must return at most one value,
+// optionally followed by an error.
+func bindFunction(name string, function reflect.Value) (*nativeFunction, error) {
+	t := function.Type()
+	arity, maxArity := t.NumIn(), t.NumIn()
+	if t.IsVariadic() {
+		arity, maxArity = t.NumIn()-1, NoMaxArity
+	}
+	errorType := reflect.TypeFor[error]()
+	returnsError := t.NumOut() > 0 && t.Out(t.NumOut()-1) == errorType
+	results := t.NumOut()
+	if returnsError {
+		results--
+	}
+	if results > 1 {
+		return nil, fmt.Errorf("functions can return one value at most, along with an error")
+	}
+
+	return &nativeFunction{name: name, arity: arity, maxArity: maxArity, fn: func(_ *Interpreter, arguments []any) (any, error) {
+		in := make([]reflect.Value, len(arguments))
+		for i, argument := range arguments {
+			paramType := t.In(min(i, t.NumIn()-1))
+			if t.IsVariadic() && i >= arity {
+				paramType = paramType.Elem()
+			}
+			value, err := fromLox(argument, paramType)
+			if err != nil {
+				return nil, fmt.Errorf("Argument %d of '%s' must be %s.", i+1, name, err)
+			}
+			in[i] = value
+		}
+		out := function.Call(in)
+		if returnsError && !out[len(out)-1].IsNil() {
+			return nil, out[len(out)-1].Interface().(error)
+		}
+		if results == 0 {
+			return nil, nil
+		}
+		return toLox(out[0])
+	}}, nil
+}
+
+// toLox converts a Go value to the corresponding glox value
+func toLox(v reflect.Value) (any, error) {
+	if !v.IsValid() {
+		return nil, nil
+	}
+	switch v.Kind() {
+	case reflect.Bool:
+		return v.Bool(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(v.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), nil
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Interface:
+		if v.IsNil() {
+			return nil, nil
+		}
+		if isLoxValue(v.Elem()) {
+			return v.Elem().Interface(), nil
+		}
+		return toLox(v.Elem())
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return nil, nil
+		}
+		elements := make([]any, v.Len())
+		for i := range elements {
+			element, err := toLox(v.Index(i))
+			if err != nil {
+				return nil, err
+			}
+			elements[i] = element
+		}
+		return &LoxList{Elements: elements}, nil
+	case reflect.Map:
+		if v.IsNil() {
+			return nil, nil
+		}
+		m := NewMap()
+		iter := v.MapRange()
+		for iter.Next() {
+			key, err := toLox(iter.Key())
+			if err != nil {
+				return nil, err
+			}
+			value, err := toLox(iter.Value())
+			if err != nil {
+				return nil, err
+			}
+			if err := m.SetIndex(tokens.Token{}, key, value); err != nil {
+				return nil, err
+			}
+		}
+		return m, nil
+	case reflect.Func:
+		if v.IsNil() {
+			return nil, nil
+		}
+		return bindFunction(goFunctionName(v), v)
+	case reflect.Pointer:
+		if v.IsNil() {
+			return nil, nil
+		}
+		if isLoxValue(v) {
+			return v.Interface(), nil
+		}
+		if v.Elem().Kind() == reflect.Struct {
+			return &HostObject{value: v}, nil
+		}
+		return toLox(v.Elem())
+	case reflect.Struct:
+		// structs are copied, so changes made by the program are not visible to the host
+		pointer := reflect.New(v.Type())
+		pointer.Elem().Set(v)
+		return &HostObject{value: pointer}, nil
+	}
+	return nil, fmt.Errorf("values of type %s are not supported", v.Type())
+}
+
+// fromLox converts a glox value to a Go value of the provided type, the error describes the expected type
+func fromLox(v any, t reflect.Type) (reflect.Value, error) {
+	if v == nil || v == tokens.NilLiteral {
+		switch t.Kind() {
+		case reflect.Interface, reflect.Pointer, reflect.Slice, reflect.Map, reflect.Func:
+			return reflect.Zero(t), nil
+		}
+		return reflect.Value{}, typeError(t)
+	}
+	if reflect.TypeOf(v).AssignableTo(t) {
+		return reflect.ValueOf(v), nil
+	}
+	switch t.Kind() {
+	case reflect.Bool, reflect.String:
+		// named types, Eg: `type Color string`
+		if value := reflect.ValueOf(v); value.Kind() == t.Kind() {
+			return value.Convert(t), nil
+		}
+	case reflect.Float32, reflect.Float64:
+		if n, isNumber := v.(float64); isNumber {
+			return reflect.ValueOf(n).Convert(t), nil
+		}
+	// integers must fit in the type, they are not wrapped around
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if n, isNumber := v.(float64); isNumber && n == float64(int64(n)) && !reflect.Zero(t).OverflowInt(int64(n)) {
+			return reflect.ValueOf(int64(n)).Convert(t), nil
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if n, isNumber := v.(float64); isNumber && n >= 0 && n < math.MaxUint64 && n == math.Trunc(n) && !reflect.Zero(t).OverflowUint(uint64(n)) {
+			return reflect.ValueOf(uint64(n)).Convert(t), nil
+		}
+	case reflect.Slice:
+		if list, isList := v.(*LoxList); isList {
+			slice := reflect.MakeSlice(t, len(list.Elements), len(list.Elements))
+			for i, element := range list.Elements {
+				value, err := fromLox(element, t.Elem())
+				if err != nil {
+					return reflect.Value{}, typeError(t)
+				}
+				slice.Index(i).Set(value)
+			}
+			return slice, nil
+		}
+	case reflect.Map:
+		if m, isMap := v.(*LoxMap); isMap {
+			result := reflect.MakeMapWithSize(t, len(m.keys))
+			for _, key := range m.keys {
+				goKey, err := fromLox(key, t.Key())
+				if err != nil {
+					return reflect.Value{}, typeError(t)
+				}
+				goValue, err := fromLox(m.values[key], t.Elem())
+				if err != nil {
+					return reflect.Value{}, typeError(t)
+				}
+				result.SetMapIndex(goKey, goValue)
+			}
+			return result, nil
+		}
+	case reflect.Pointer, reflect.Struct:
+		return structFromLox(v, t)
+	}
+	return reflect.Value{}, typeError(t)
+}
+
+// structFromLox converts host objects and instances to structs or pointers to structs. The fields of instances
+// are copied into the struct fields with the same name.
+func structFromLox(v any, t reflect.Type) (reflect.Value, error) {
+	structType := t
+	if t.Kind() == reflect.Pointer {
+		structType = t.Elem()
+	}
+	if structType.Kind() != reflect.Struct {
+		return reflect.Value{}, typeError(t)
+	}
+	var pointer reflect.Value
+	switch object := v.(type) {
+	case *HostObject:
+		if object.value.Type().Elem() != structType {
+			return reflect.Value{}, typeError(t)
+		}
+		pointer = object.value
+	case *LoxInstance:
+		pointer = reflect.New(structType)
+		for name, fieldValue := range object.fields {
+			field := pointer.Elem().FieldByName(exportedName(name))
+			if !field.IsValid() || !field.CanSet() {
+				continue
+			}
+			value, err := fromLox(fieldValue, field.Type())
+			if err != nil {
+				return reflect.Value{}, typeError(t)
+			}
+			field.Set(value)
+		}
+	default:
+		return reflect.Value{}, typeError(t)
+	}
+	if t.Kind() == reflect.Pointer {
+		return pointer, nil
+	}
+	return pointer.Elem(), nil
+}
+
+// typeError describes the glox values accepted for the provided Go type
+func typeError(t reflect.Type) error {
+	switch t.Kind() {
+	case reflect.Bool:
+		return fmt.Errorf("a boolean")
+	case reflect.String:
+		return fmt.Errorf("a string")
+	case reflect.Float32, reflect.Float64:
+		return fmt.Errorf("a number")
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return fmt.Errorf("an integer")
+	case reflect.Slice:
+		return fmt.Errorf("a list")
+	case reflect.Map:
+		return fmt.Errorf("a map")
+	}
+	return fmt.Errorf("a %s instance", strings.TrimPrefix(t.String(), "*"))
+}
+
+// isLoxValue tells if the provided value is already a glox value, so it doesn't need to be converted
+func isLoxValue(v reflect.Value) bool {
+	switch v.Interface().(type) {
+	case *LoxList, *LoxMap, *LoxInstance, *LoxClass, *HostObject, GloxCallable, *LoxModule, *NativeModule, *LoxError:
+		return true
+	}
+	return false
+}
+
+// goFunctionName returns the name shown in stack traces for Go functions, Eg: `strings.ToUpper`
+func goFunctionName(v reflect.Value) string {
+	if function := runtime.FuncForPC(v.Pointer()); function != nil {
+		name := function.Name()
+		return name[strings.LastIndex(name, "/")+1:]
+	}
+	return v.Type().String()
+}
@@ -1,8 +1,26 @@
 package interpreter
 
+import "fmt"
+
+// NoMaxArity is the maximum arity of the callables accepting any number of arguments
+const NoMaxArity = -1
+
 type GloxCallable interface {
-	// Arity determines the number of expected arguments
+	// Arity determines the minimum number of expected arguments
 	Arity() int
+	// MaxArity determines the maximum number of expected arguments, NoMaxArity if there is no limit
+	MaxArity() int
 	// Call performs a call using the interpreter
 	Call(interpreter *Interpreter, arguments []any) (any, error)
 }
+
+// arityError returns the error message for calls whose number of arguments is out of the callable's arity
+func arityError(function GloxCallable, numArgs int) string {
+	switch {
+	case function.MaxArity() == function.Arity():
+		return fmt.Sprintf("Expected %d arguments but got %d.", function.Arity(), numArgs)
+	case function.MaxArity() == NoMaxArity:
+		return fmt.Sprintf("Expected at least %d arguments but got %d.", function.Arity(), numArgs)
+	}
+	return fmt.Sprintf("Expected %d to %d arguments but got %d.", function.Arity(), function.MaxArity(), numArgs)
+}
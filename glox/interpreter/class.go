@@ -10,6 +10,13 @@ type LoxClass struct {
 	Name       string
 	Superclass *LoxClass
 	Methods    map[string]*LoxFunction
+	// ClassMethods are called on the class itself, Eg: `Math.square(2)`
+	ClassMethods map[string]*LoxFunction
+	// Getters run when the property is read, Setters when it is assigned
+	Getters map[string]*LoxFunction
+	Setters map[string]*LoxFunction
+	// fields holds the properties set on the class, classes can be used as instances
+	fields map[string]any
 }
 
 func (c *LoxClass) String() string {
@@ -23,6 +30,13 @@ func (c *LoxClass) Arity() int {
 	return 0
 }
 
+func (c *LoxClass) MaxArity() int {
+	if initializer := c.FindMethod("init"); initializer != nil {
+		return initializer.MaxArity()
+	}
+	return 0
+}
+
 func (c *LoxClass) Call(interpreter *Interpreter, arguments []any) (any, error) {
 	instance := NewInstance(c)
 	if initializer := c.FindMethod("init"); initializer != nil {
@@ -35,16 +49,69 @@ func (c *LoxClass) Call(interpreter *Interpreter, arguments []any) (any, error)
 }
 
 func (c *LoxClass) FindMethod(key string) *LoxFunction {
-	method := c.Methods[key]
-	if method != nil {
-		return method
+	return c.find(key, func(class *LoxClass) map[string]*LoxFunction { return class.Methods })
+}
+
+func (c *LoxClass) FindClassMethod(key string) *LoxFunction {
+	return c.find(key, func(class *LoxClass) map[string]*LoxFunction { return class.ClassMethods })
+}
+
+// member holds the method, getter and setter a class defines for a property
+type member struct {
+	method *LoxFunction
+	getter *LoxFunction
+	setter *LoxFunction
+}
+
+// findMember looks the property up one class at a time, the members a class defines for a property override all
+// the members of its superclasses with the same name. Eg: a method overrides the getter of a superclass.
+func (c *LoxClass) findMember(key string) member {
+	for class := c; class != nil; class = class.Superclass {
+		m := member{method: class.Methods[key], getter: class.Getters[key], setter: class.Setters[key]}
+		if m.method != nil || m.getter != nil || m.setter != nil {
+			return m
+		}
 	}
-	if c.Superclass != nil {
-		return c.Superclass.FindMethod(key)
+	return member{}
+}
+
+// find looks the function up in the class and then in its superclasses
+func (c *LoxClass) find(key string, functions func(*LoxClass) map[string]*LoxFunction) *LoxFunction {
+	for class := c; class != nil; class = class.Superclass {
+		if f := functions(class)[key]; f != nil {
+			return f
+		}
 	}
 	return nil
 }
 
+func (c *LoxClass) Get(name tokens.Token) (any, error) {
+	if field, exists := c.fields[name.Lexeme]; exists {
+		return field, nil
+	}
+	if method := c.FindClassMethod(name.Lexeme); method != nil {
+		return method.Bind(c), nil
+	}
+	return nil, errors.NewRuntimeError(name, fmt.Sprintf("Undefined property '%s'.", name.Lexeme))
+}
+
+func (c *LoxClass) Set(name tokens.Token, value any) {
+	if c.fields == nil {
+		c.fields = map[string]any{}
+	}
+	c.fields[name.Lexeme] = value
+}
+
+// LoxTrait holds methods shared by classes, they are copied into the classes declared with the trait
+type LoxTrait struct {
+	Name    string
+	Methods map[string]*LoxFunction
+}
+
+func (t *LoxTrait) String() string {
+	return t.Name
+}
+
 type LoxInstance struct {
 	class  *LoxClass
 	fields map[string]any
@@ -62,7 +129,7 @@ func (i *LoxInstance) Get(name tokens.Token) (any, error) {
 	if field, exists := i.fields[name.Lexeme]; exists {
 		return field, nil
 	}
-	if method := i.class.FindMethod(name.Lexeme); method != nil {
+	if method := i.class.findMember(name.Lexeme).method; method != nil {
 		return method.Bind(i), nil
 	}
 	return nil, errors.NewRuntimeError(name, fmt.Sprintf("Undefined property '%s'.", name.Lexeme))
@@ -0,0 +1,31 @@
+package interpreter
+
+import (
+	"fmt"
+	"glox/errors"
+	"glox/tokens"
+)
+
+// LoxError is the value a catch clause gets when a runtime error happens
+type LoxError struct {
+	Message string
+	Line    int
+}
+
+func NewLoxError(e *errors.RuntimeError) *LoxError {
+	return &LoxError{Message: e.Error(), Line: e.Token().Line}
+}
+
+func (e *LoxError) String() string {
+	return e.Message
+}
+
+func (e *LoxError) Get(name tokens.Token) (any, error) {
+	switch name.Lexeme {
+	case "message":
+		return e.Message, nil
+	case "line":
+		return float64(e.Line), nil
+	}
+	return nil, errors.NewRuntimeError(name, fmt.Sprintf("Undefined property '%s'.", name.Lexeme))
+}
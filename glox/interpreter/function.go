@@ -9,20 +9,46 @@ type LoxFunction struct {
 	Declaration   *FunctionStmt
 	Closure       *environment.Environment
 	IsInitializer bool
+	// ClassName is the name of the class the function belongs to if it is a method
+	ClassName string
+	// Globals is the global environment of the module the function is declared in
+	Globals *environment.Environment
 }
 
+// Arity returns the number of parameters without a default value
 func (f *LoxFunction) Arity() int {
+	arity := 0
+	for _, defaultValue := range f.Declaration.Defaults {
+		if defaultValue == nil {
+			arity++
+		}
+	}
+	if f.Declaration.Variadic {
+		arity--
+	}
+	return arity
+}
+
+func (f *LoxFunction) MaxArity() int {
+	if f.Declaration.Variadic {
+		return NoMaxArity
+	}
 	return len(f.Declaration.Params)
 }
 
 func (f *LoxFunction) Call(interpreter *Interpreter, arguments []any) (any, error) {
 	env := environment.New(f.Closure)
-	for i, arg := range arguments {
-		env.Define(f.Declaration.Params[i].Lexeme, arg)
+	previousGlobals := interpreter.globals
+	interpreter.globals = f.Globals
+	defer func() {
+		interpreter.globals = previousGlobals
+	}()
+	if err := f.bindParameters(interpreter, env, arguments); err != nil {
+		return nil, err
 	}
 	err := interpreter.executeBlock(f.Declaration.Body, env)
 	if f.IsInitializer {
-		return f.Closure.GetAt(0, "this"), nil
+		return f.Closure.GetAt(0, 0), nil // "this" is the only variable of the closure of bound methods
 	}
 	if err != nil {
 		if returnHolder, isReturn := err.(*Return); isReturn {
@@ -33,16 +59,56 @@ func (f *LoxFunction) Call(interpreter *Interpreter, arguments []any) (any, erro
 	return nil, nil
 }
 
-func (f *LoxFunction) Bind(i *LoxInstance) *LoxFunction {
+// bindParameters defines the parameters in the function environment. Missing arguments get the default values,
+// which are evaluated in that environment, and the rest parameter gets a list with the remaining arguments.
+func (f *LoxFunction) bindParameters(interpreter *Interpreter, env *environment.Environment, arguments []any) error {
+	params := f.Declaration.Params
+	for i, param := range params {
+		switch {
+		case f.Declaration.Variadic && i == len(params)-1:
+			rest := []any{}
+			if i < len(arguments) {
+				rest = append(rest, arguments[i:]...)
+			}
+			env.Define(param.Lexeme, &LoxList{Elements: rest})
+		case i < len(arguments):
+			env.Define(param.Lexeme, arguments[i])
+		default:
+			previousEnv := interpreter.env
+			interpreter.env = env
+			value, err := interpreter.evaluate(f.Declaration.Defaults[i])
+			interpreter.env = previousEnv
+			if err != nil {
+				return err
+			}
+			env.Define(param.Lexeme, value)
+		}
+	}
+	return nil
+}
+
+// Bind returns the method bound to the provided instance, or to the class for class methods
+func (f *LoxFunction) Bind(this any) *LoxFunction {
 	env := environment.New(f.Closure)
-	env.Define("this", i)
-	return &LoxFunction{Declaration: f.Declaration, Closure: env, IsInitializer: f.IsInitializer}
+	env.Define("this", this)
+	return &LoxFunction{Declaration: f.Declaration, Closure: env, IsInitializer: f.IsInitializer, ClassName: f.ClassName, Globals: f.Globals}
 }
 
 func (f *LoxFunction) String() string {
+	if f.Declaration.Name.Lexeme == "" {
+		return "<anonymous fn>"
+	}
 	return fmt.Sprintf("<fn %s>", f.Declaration.Name.Lexeme)
 }
 
+// name returns the name of the function shown in stack traces
+func (f *LoxFunction) name() string {
+	if f.Declaration.Name.Lexeme == "" {
+		return "<anonymous>"
+	}
+	return f.Declaration.Name.Lexeme
+}
+
 // Return type represents a Golang error that holds the return value.
 // This is needed because returns are handled as error although it is merely for control-flow.
 type Return struct {
@@ -52,3 +118,24 @@ type Return struct {
 func (e *Return) Error() string {
 	return "not-really-an-error"
 }
+
+// Break is the error used to exit a loop, as Return it is only used for control-flow
+type Break struct{}
+
+func (e *Break) Error() string {
+	return "not-really-an-error"
+}
+
+// Continue is the error used to skip the rest of a loop iteration
+type Continue struct{}
+
+func (e *Continue) Error() string {
+	return "not-really-an-error"
+}
+
+// ShortCircuit is the error used to skip the rest of an optional chain when an optional access finds nil
+type ShortCircuit struct{}
+
+func (e *ShortCircuit) Error() string {
+	return "not-really-an-error"
+}
@@ -1,15 +1,25 @@
 package interpreter
 
 import (
+	"bufio"
 	"fmt"
 	"glox/environment"
 	"glox/errors"
 	"glox/expr"
 	"glox/stmt"
 	"glox/tokens"
-	"strconv"
+	"io"
+	"math"
+	"math/rand"
+	"os"
+	"slices"
+	"strings"
+	"time"
 )
 
+// maxFrames is the maximum depth of nested calls, deeper calls fail with a stack overflow error
+const maxFrames = 10000
+
 type Expr = expr.Expr[any]
 type BinaryExpr = expr.Binary[any]
 type LiteralExpr = expr.Literal[any]
@@ -23,6 +33,19 @@ type SetExpr = expr.Set[any]
 type SuperExpr = expr.Super[any]
 type ThisExpr = expr.This[any]
 type AssignExpr = expr.Assign[any]
+type CompoundAssignExpr = expr.CompoundAssign[any]
+type CompoundSetExpr = expr.CompoundSet[any]
+type CompoundIndexSetExpr = expr.CompoundIndexSet[any]
+type ListExpr = expr.List[any]
+type MapExpr = expr.Map[any]
+type IndexExpr = expr.Index[any]
+type IndexSetExpr = expr.IndexSet[any]
+type SliceExpr = expr.Slice[any]
+type LambdaExpr = expr.Lambda[any]
+type InterpolationExpr = expr.Interpolation[any]
+type ConditionalExpr = expr.Conditional[any]
+type OptionalGetExpr = expr.OptionalGet[any]
+type OptionalChainExpr = expr.OptionalChain[any]
 type ExprVisitor = expr.Visitor[any]
 
 type Stmt = stmt.Stmt[any]
@@ -32,31 +55,89 @@ type FunctionStmt = stmt.Function[any]
 type IfStmt = stmt.If[any]
 type VarStmt = stmt.Var[any]
 type ReturnStmt = stmt.Return[any]
+type ThrowStmt = stmt.Throw[any]
+type ImportStmt = stmt.Import[any]
+type TryStmt = stmt.Try[any]
 type BlockStmt = stmt.Block[any]
 type ClassStmt = stmt.Class[any]
 type WhileStmt = stmt.While[any]
+type BreakStmt = stmt.Break[any]
+type ContinueStmt = stmt.Continue[any]
+type TraitStmt = stmt.Trait[any]
+type MatchStmt = stmt.Match[any]
+type MatchCase = stmt.MatchCase[any]
 type StmtVisitor = stmt.Visitor[any]
 
+// propertyHolder is implemented by values whose properties can be accessed, Eg: `instance.property`
+type propertyHolder interface {
+	Get(name tokens.Token) (any, error)
+}
+
 type Interpreter struct {
-	env     *environment.Environment
-	globals *environment.Environment
-	locals  map[Expr]int
+	env      *environment.Environment
+	globals  *environment.Environment
+	locals   map[Expr]local
+	reporter *errors.Reporter
+	stdout   io.Writer
+	stdin    *bufio.Reader
+	frames   []errors.StackFrame
+	random   *rand.Rand
+
+	// hostGlobals holds the values defined by the host, they are globals in every program and imported module
+	hostGlobals map[string]any
+
+	loader ModuleLoader
+	// modules holds the imported modules by path
+	modules map[string]*LoxModule
+	// importing holds the paths of the modules being imported, to detect cycles
+	importing []string
+	// accessors holds the getters and setters being run, the property they define is a plain field inside them
+	accessors []accessor
+}
+
+// accessor identifies a getter or setter call: the instance and the property
+type accessor struct {
+	instance *LoxInstance
+	property string
+}
+
+func New(reporter *errors.Reporter, stdout io.Writer) Interpreter {
+	i := Interpreter{
+		locals:      map[Expr]local{},
+		reporter:    reporter,
+		stdout:      stdout,
+		stdin:       bufio.NewReader(os.Stdin),
+		random:      rand.New(rand.NewSource(time.Now().UnixNano())),
+		modules:     map[string]*LoxModule{},
+		hostGlobals: map[string]any{"string": stringModule(), "math": mathModule()},
+	}
+	i.globals = i.newGlobals()
+	i.env = i.globals
+	return i
 }
 
-func New() Interpreter {
-	env := environment.New(nil)
-	env.Define("clock", &clock{})
-	return Interpreter{env: env, globals: env, locals: map[Expr]int{}}
+// local locates a resolved local variable: the number of environments up and its slot in that environment
+type local struct {
+	depth int
+	slot  int
 }
 
-func (i *Interpreter) Interpret(statements []Stmt) {
+// Interpret executes the provided statements and returns the value of the last expression statement.
+// Runtime errors are reported and returned.
+func (i *Interpreter) Interpret(statements []Stmt) (any, error) {
+	var last any
 	for _, statement := range statements {
-		if _, err := i.execute(statement); err != nil {
+		v, err := i.execute(statement)
+		if err != nil {
 			e := err.(*errors.RuntimeError)
-			errors.ReportRuntimeError(e)
-			return
+			i.reporter.ReportRuntimeError(e)
+			return nil, e
+		}
+		if _, isExpression := statement.(*ExpressionStmt); isExpression {
+			last = v
 		}
 	}
+	return last, nil
 }
 
 func (i *Interpreter) interpret(expression Expr) (string, error) {
@@ -103,23 +184,59 @@ func (i *Interpreter) VisitForCall(c *CallExpr) (any, error) {
 	if !isCallable {
 		return nil, errors.NewRuntimeError(c.Paren, "Can only call functions and classes.")
 	}
+	return i.call(function, c.Paren, arguments)
+}
 
-	if numArgs := len(arguments); numArgs != function.Arity() {
-		return nil, errors.NewRuntimeError(c.Paren, fmt.Sprintf("Expected %d arguments but got %d.", function.Arity(), numArgs))
+// call calls the function checking the number of arguments, errors are reported at the provided token
+func (i *Interpreter) call(function GloxCallable, token tokens.Token, arguments []any) (any, error) {
+	if numArgs := len(arguments); numArgs < function.Arity() || (function.MaxArity() != NoMaxArity && numArgs > function.MaxArity()) {
+		return nil, errors.NewRuntimeError(token, arityError(function, numArgs))
+	}
+	if len(i.frames) == maxFrames {
+		runtimeError := errors.NewRuntimeError(token, "Stack overflow.")
+		runtimeError.SetTrace(slices.Clone(i.frames))
+		return nil, runtimeError
+	}
+
+	i.frames = append(i.frames, stackFrame(function, token.Line))
+	defer func() {
+		i.frames = i.frames[:len(i.frames)-1]
+	}()
+	result, err := function.Call(i, arguments)
+	if err == nil {
+		return result, nil
+	}
+	runtimeError, isRuntimeError := err.(*errors.RuntimeError)
+	if !isRuntimeError {
+		// native functions return plain errors
+		runtimeError = errors.NewRuntimeError(token, err.Error())
+	}
+	// keep the calls active when the error happened, the innermost call sees it first
+	if runtimeError.Trace() == nil {
+		runtimeError.SetTrace(slices.Clone(i.frames))
 	}
+	return nil, runtimeError
+}
 
-	return function.Call(i, arguments)
+// stackFrame describes a call to the provided function happening in the provided line
+func stackFrame(function GloxCallable, line int) errors.StackFrame {
+	switch f := function.(type) {
+	case *LoxFunction:
+		return errors.StackFrame{Function: f.name(), Class: f.ClassName, Line: line}
+	case *LoxClass:
+		return errors.StackFrame{Function: f.Name, Line: line}
+	case *nativeFunction:
+		return errors.StackFrame{Function: f.name, Line: line}
+	}
+	return errors.StackFrame{Function: fmt.Sprintf("%v", function), Line: line}
 }
 
 func (i *Interpreter) VisitForExpression(e *ExpressionStmt) (any, error) {
-	if _, err := i.evaluate(e.Expression); err != nil {
-		return nil, err
-	}
-	return nil, nil
+	return i.evaluate(e.Expression)
 }
 
 func (i *Interpreter) VisitForFunction(f *FunctionStmt) (any, error) {
-	function := LoxFunction{Declaration: f, Closure: i.env, IsInitializer: false}
+	function := LoxFunction{Declaration: f, Closure: i.env, IsInitializer: false, Globals: i.globals}
 	i.env.Define(f.Name.Lexeme, &function)
 	return nil, nil
 }
@@ -136,6 +253,119 @@ func (i *Interpreter) VisitForReturn(r *ReturnStmt) (any, error) {
 	return nil, &Return{Value: value}
 }
 
+func (i *Interpreter) VisitForThrow(t *ThrowStmt) (any, error) {
+	value, err := i.evaluate(t.Value)
+	if err != nil {
+		return nil, err
+	}
+	return nil, errors.NewThrowError(t.Keyword, "Uncaught exception: "+stringify(value), value)
+}
+
+func (i *Interpreter) VisitForTry(t *TryStmt) (any, error) {
+	err := i.executeBlock(t.Body, environment.New(i.env))
+	if runtimeError, isRuntimeError := err.(*errors.RuntimeError); isRuntimeError && t.CatchName != nil {
+		value, thrown := runtimeError.Thrown()
+		if !thrown {
+			value = NewLoxError(runtimeError)
+		}
+		env := environment.New(i.env)
+		env.Define(t.CatchName.Lexeme, value)
+		err = i.executeBlock(t.CatchBody, env)
+	}
+	// finally is executed for any error, including the ones used for control flow such as returns
+	if t.FinallyBody != nil {
+		if finallyErr := i.executeBlock(t.FinallyBody, environment.New(i.env)); finallyErr != nil {
+			return nil, finallyErr
+		}
+	}
+	return nil, err
+}
+
+func (i *Interpreter) VisitForMatch(m *MatchStmt) (any, error) {
+	value, err := i.evaluate(m.Value)
+	if err != nil {
+		return nil, err
+	}
+	for _, matchCase := range m.Cases {
+		matched, err := i.matchesCase(matchCase, value)
+		if err != nil {
+			return nil, err
+		}
+		if !matched {
+			continue
+		}
+		if ran, err := i.runCase(matchCase, value); ran || err != nil {
+			return nil, err
+		}
+	}
+	return nil, nil
+}
+
+// matchesCase tells if the value matches any pattern of the case, default cases match every value. The pattern of
+// a case binding a variable must be a class.
+func (i *Interpreter) matchesCase(matchCase *MatchCase, value any) (bool, error) {
+	if matchCase.IsDefault() {
+		return true, nil
+	}
+	for _, p := range matchCase.Patterns {
+		pattern, err := i.evaluate(p)
+		if err != nil {
+			return false, err
+		}
+		if _, isClass := pattern.(*LoxClass); matchCase.Binding != nil && !isClass {
+			name := patternName(p)
+			return false, errors.NewRuntimeError(name, fmt.Sprintf("'%s' is not a class.", name.Lexeme))
+		}
+		if matches(value, pattern) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// patternName returns the name of a class pattern, Eg: `Point` in `shapes.Point`
+func patternName(pattern Expr) tokens.Token {
+	if get, isGet := pattern.(*GetExpr); isGet {
+		return get.Name
+	}
+	return pattern.(*VariableExpr).Name
+}
+
+// matches tells if the value matches the pattern, instances match their class and its superclasses
+func matches(value any, pattern any) bool {
+	if class, isClass := pattern.(*LoxClass); isClass {
+		if instance, isInstance := value.(*LoxInstance); isInstance {
+			for c := instance.class; c != nil; c = c.Superclass {
+				if c == class {
+					return true
+				}
+			}
+			return false
+		}
+	}
+	return isEqual(value, pattern)
+}
+
+// runCase executes the body of the case if its guard allows it, both see the variable bound to the matched value
+func (i *Interpreter) runCase(matchCase *MatchCase, value any) (bool, error) {
+	previous := i.env
+	defer func() {
+		i.env = previous
+	}()
+	if matchCase.Binding != nil {
+		i.env = environment.New(previous)
+		i.env.Define(matchCase.Binding.Lexeme, value)
+	}
+	if matchCase.Guard != nil {
+		guard, err := i.evaluate(matchCase.Guard)
+		if err != nil || !isTruthy(guard) {
+			return false, err
+		}
+	}
+	_, err := i.execute(matchCase.Body)
+	return true, err
+}
+
 func (i *Interpreter) VisitForIf(s *IfStmt) (any, error) {
 	condition, err := i.evaluate(s.Condition)
 	if err != nil {
@@ -156,11 +386,16 @@ func (i *Interpreter) VisitForLogical(l *LogicalExpr) (any, error) {
 		return nil, err
 	}
 
-	if l.Operator.TokenType == tokens.Or {
+	switch l.Operator.TokenType {
+	case tokens.Or:
 		if isTruthy(left) {
 			return left, nil
 		}
-	} else { // And
+	case tokens.QuestionQuestion:
+		if !isNil(left) {
+			return left, nil
+		}
+	default: // And
 		if !isTruthy(left) {
 			return left, nil
 		}
@@ -168,6 +403,17 @@ func (i *Interpreter) VisitForLogical(l *LogicalExpr) (any, error) {
 	return i.evaluate(l.Right)
 }
 
+func (i *Interpreter) VisitForConditional(c *ConditionalExpr) (any, error) {
+	condition, err := i.evaluate(c.Condition)
+	if err != nil {
+		return nil, err
+	}
+	if isTruthy(condition) {
+		return i.evaluate(c.Then)
+	}
+	return i.evaluate(c.Else)
+}
+
 func (i *Interpreter) VisitForWhile(w *WhileStmt) (any, error) {
 	for {
 		condition, err := i.evaluate(w.Condition)
@@ -178,19 +424,36 @@ func (i *Interpreter) VisitForWhile(w *WhileStmt) (any, error) {
 			return nil, nil
 		}
 		_, err = i.execute(w.Body)
-		if err != nil {
+		switch err.(type) {
+		case nil, *Continue:
+		case *Break:
+			return nil, nil
+		default:
 			return nil, err
 		}
+		if w.Increment != nil {
+			if _, err := i.evaluate(w.Increment); err != nil {
+				return nil, err
+			}
+		}
 	}
 }
 
+func (i *Interpreter) VisitForBreak(b *BreakStmt) (any, error) {
+	return nil, &Break{}
+}
+
+func (i *Interpreter) VisitForContinue(c *ContinueStmt) (any, error) {
+	return nil, &Continue{}
+}
+
 func (i *Interpreter) VisitForPrint(p *PrintStmt) (any, error) {
 	v, err := i.evaluate(p.Expression)
 	if err != nil {
 		return nil, err
 	}
 
-	fmt.Println(stringify(v))
+	fmt.Fprintln(i.stdout, stringify(v))
 	return nil, nil
 }
 
@@ -199,8 +462,6 @@ func (i *Interpreter) VisitForBlock(b *BlockStmt) (any, error) {
 }
 
 func (i *Interpreter) VisitForClass(c *ClassStmt) (any, error) {
-	i.env.Define(c.Name.Lexeme, nil)
-
 	var superClass *LoxClass
 	if c.SuperClass != nil {
 		s, err := i.evaluate(c.SuperClass)
@@ -218,27 +479,79 @@ func (i *Interpreter) VisitForClass(c *ClassStmt) (any, error) {
 		i.env = env
 	}
 
-	methods := map[string]*LoxFunction{}
+	methods, err := i.traitMethods(c)
+	if err != nil {
+		return nil, err
+	}
 	for _, method := range c.Methods {
 		isInitializer := false
 		if method.Name.Lexeme == "init" {
 			isInitializer = true
 		}
-		f := &LoxFunction{Declaration: method, Closure: i.env, IsInitializer: isInitializer}
+		f := &LoxFunction{Declaration: method, Closure: i.env, IsInitializer: isInitializer, ClassName: c.Name.Lexeme, Globals: i.globals}
 		methods[method.Name.Lexeme] = f
 	}
 
-	class := &LoxClass{Name: c.Name.Lexeme, Superclass: superClass, Methods: methods}
+	class := &LoxClass{
+		Name:         c.Name.Lexeme,
+		Superclass:   superClass,
+		Methods:      methods,
+		ClassMethods: i.methods(c.ClassMethods, c.Name.Lexeme),
+		Getters:      i.methods(c.Getters, c.Name.Lexeme),
+		Setters:      i.methods(c.Setters, c.Name.Lexeme),
+	}
 	if c.SuperClass != nil {
 		i.env = i.env.Enclosing
 	}
 
-	if err := i.env.Assign(c.Name, class); err != nil {
-		return nil, err
+	// methods only reach the class name once they are called, so it can be defined after creating them
+	i.env.Define(c.Name.Lexeme, class)
+	return nil, nil
+}
+
+// traitMethods returns the methods of the traits used by the class. A method can only be defined once among the
+// traits and the class.
+func (i *Interpreter) traitMethods(c *ClassStmt) (map[string]*LoxFunction, error) {
+	methods := map[string]*LoxFunction{}
+	owners := map[string]string{}
+	for _, method := range c.Methods {
+		owners[method.Name.Lexeme] = fmt.Sprintf("class '%s'", c.Name.Lexeme)
+	}
+	for _, variable := range c.Traits {
+		value, err := i.evaluate(variable)
+		if err != nil {
+			return nil, err
+		}
+		trait, isTrait := value.(*LoxTrait)
+		if !isTrait {
+			return nil, errors.NewRuntimeError(variable.Name, fmt.Sprintf("'%s' is not a trait.", variable.Name.Lexeme))
+		}
+		for name, method := range trait.Methods {
+			if owner, defined := owners[name]; defined {
+				return nil, errors.NewRuntimeError(variable.Name, fmt.Sprintf("Trait '%s' redefines method '%s' of %s.", trait.Name, name, owner))
+			}
+			owners[name] = fmt.Sprintf("trait '%s'", trait.Name)
+			methods[name] = method
+		}
 	}
+	return methods, nil
+}
+
+func (i *Interpreter) VisitForTrait(t *TraitStmt) (any, error) {
+	trait := &LoxTrait{Name: t.Name.Lexeme, Methods: i.methods(t.Methods, t.Name.Lexeme)}
+	i.env.Define(t.Name.Lexeme, trait)
 	return nil, nil
 }
 
+// methods returns the functions of the provided declarations, by name
+func (i *Interpreter) methods(declarations []*FunctionStmt, className string) map[string]*LoxFunction {
+	methods := map[string]*LoxFunction{}
+	for _, method := range declarations {
+		methods[method.Name.Lexeme] = &LoxFunction{Declaration: method, Closure: i.env, ClassName: className, Globals: i.globals}
+	}
+	return methods
+}
+
 func (i *Interpreter) VisitForThis(t *ThisExpr) (any, error) {
 	return i.lookUpVariable(t.Keyword, t)
 }
@@ -261,38 +574,289 @@ func (i *Interpreter) VisitForGet(g *GetExpr) (any, error) {
 	if err != nil {
 		return nil, err
 	}
-	if loxInstance, isInstance := object.(*LoxInstance); isInstance {
-		return loxInstance.Get(g.Name)
-	}
-	return nil, errors.NewRuntimeError(g.Name, "Only instances have properties.")
+	return i.getProperty(object, g.Name)
 }
 
-func (i *Interpreter) VisitForSet(s *SetExpr) (any, error) {
-	object, err := i.evaluate(s.Object)
+func (i *Interpreter) VisitForOptionalGet(g *OptionalGetExpr) (any, error) {
+	object, err := i.evaluate(g.Object)
 	if err != nil {
 		return nil, err
 	}
-	if loxInstance, isInstance := object.(*LoxInstance); isInstance {
-		value, err := i.evaluate(s.Value)
+	if isNil(object) {
+		return nil, &ShortCircuit{}
+	}
+	return i.getProperty(object, g.Name)
+}
+
+func (i *Interpreter) VisitForOptionalChain(c *OptionalChainExpr) (any, error) {
+	value, err := i.evaluate(c.Expression)
+	if _, isShortCircuit := err.(*ShortCircuit); isShortCircuit {
+		return nil, nil
+	}
+	return value, err
+}
+
+// getProperty returns the property of the object, running the getter if there is one. Getters take precedence
+// over fields, except inside the getters and setters of the property.
+func (i *Interpreter) getProperty(object any, name tokens.Token) (any, error) {
+	if instance, isInstance := object.(*LoxInstance); isInstance && !i.inAccessor(instance, name.Lexeme) {
+		if getter := instance.class.findMember(name.Lexeme).getter; getter != nil {
+			return i.callAccessor(instance, name, getter, nil)
+		}
+	}
+	if holder, hasProperties := object.(propertyHolder); hasProperties {
+		return holder.Get(name)
+	}
+	return nil, errors.NewRuntimeError(name, "Only instances have properties.")
+}
+
+func (i *Interpreter) VisitForLambda(l *LambdaExpr) (any, error) {
+	return &LoxFunction{Declaration: stmt.LambdaFunction(l), Closure: i.env, Globals: i.globals}, nil
+}
+
+func (i *Interpreter) VisitForInterpolation(e *InterpolationExpr) (any, error) {
+	var result strings.Builder
+	for _, part := range e.Parts {
+		v, err := i.evaluate(part)
+		if err != nil {
+			return nil, err
+		}
+		result.WriteString(stringify(v))
+	}
+	return result.String(), nil
+}
+
+func (i *Interpreter) VisitForList(l *ListExpr) (any, error) {
+	elements := make([]any, 0, len(l.Elements))
+	for _, element := range l.Elements {
+		v, err := i.evaluate(element)
 		if err != nil {
 			return nil, err
 		}
-		loxInstance.Set(s.Name, value)
+		elements = append(elements, v)
+	}
+	return &LoxList{Elements: elements}, nil
+}
+
+func (i *Interpreter) VisitForMap(m *MapExpr) (any, error) {
+	result := NewMap()
+	for n := range m.Keys {
+		key, err := i.evaluate(m.Keys[n])
+		if err != nil {
+			return nil, err
+		}
+		value, err := i.evaluate(m.Values[n])
+		if err != nil {
+			return nil, err
+		}
+		if err := result.SetIndex(m.Brace, key, value); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+func (i *Interpreter) VisitForIndex(e *IndexExpr) (any, error) {
+	object, err := i.evaluate(e.Object)
+	if err != nil {
+		return nil, err
+	}
+	index, err := i.evaluate(e.Index)
+	if err != nil {
+		return nil, err
+	}
+	if container, isIndexable := object.(indexable); isIndexable {
+		return container.GetIndex(e.Bracket, index)
+	}
+	return nil, errors.NewRuntimeError(e.Bracket, "Only lists and maps can be indexed.")
+}
+
+func (i *Interpreter) VisitForIndexSet(e *IndexSetExpr) (any, error) {
+	object, err := i.evaluate(e.Object)
+	if err != nil {
+		return nil, err
+	}
+	index, err := i.evaluate(e.Index)
+	if err != nil {
+		return nil, err
+	}
+	value, err := i.evaluate(e.Value)
+	if err != nil {
+		return nil, err
+	}
+	if container, isIndexable := object.(indexable); isIndexable {
+		if err := container.SetIndex(e.Bracket, index, value); err != nil {
+			return nil, err
+		}
 		return value, nil
 	}
-	return nil, errors.NewRuntimeError(s.Name, "Only instances have fields.")
+	return nil, errors.NewRuntimeError(e.Bracket, "Only lists and maps can be indexed.")
+}
+
+func (i *Interpreter) VisitForCompoundIndexSet(e *CompoundIndexSetExpr) (any, error) {
+	object, err := i.evaluate(e.Object)
+	if err != nil {
+		return nil, err
+	}
+	index, err := i.evaluate(e.Index)
+	if err != nil {
+		return nil, err
+	}
+	container, isIndexable := object.(indexable)
+	if !isIndexable {
+		return nil, errors.NewRuntimeError(e.Bracket, "Only lists and maps can be indexed.")
+	}
+	current, err := container.GetIndex(e.Bracket, index)
+	if err != nil {
+		return nil, err
+	}
+	value, err := i.compoundValue(current, e.Operator, e.Value)
+	if err != nil {
+		return nil, err
+	}
+	if err := container.SetIndex(e.Bracket, index, value); err != nil {
+		return nil, err
+	}
+	if e.Postfix {
+		return current, nil
+	}
+	return value, nil
+}
+
+func (i *Interpreter) VisitForSlice(e *SliceExpr) (any, error) {
+	object, err := i.evaluate(e.Object)
+	if err != nil {
+		return nil, err
+	}
+	var start, end any
+	if e.Start != nil {
+		if start, err = i.evaluate(e.Start); err != nil {
+			return nil, err
+		}
+	}
+	if e.End != nil {
+		if end, err = i.evaluate(e.End); err != nil {
+			return nil, err
+		}
+	}
+	if list, isList := object.(*LoxList); isList {
+		return list.Slice(e.Bracket, start, end)
+	}
+	return nil, errors.NewRuntimeError(e.Bracket, "Only lists can be sliced.")
+}
+
+func (i *Interpreter) VisitForSet(s *SetExpr) (any, error) {
+	object, err := i.evaluate(s.Object)
+	if err != nil {
+		return nil, err
+	}
+	if !hasFields(object) {
+		return nil, errors.NewRuntimeError(s.Name, "Only instances have fields.")
+	}
+	value, err := i.evaluate(s.Value)
+	if err != nil {
+		return nil, err
+	}
+	return value, i.setProperty(object, s.Name, value)
+}
+
+func (i *Interpreter) VisitForCompoundSet(s *CompoundSetExpr) (any, error) {
+	object, err := i.evaluate(s.Object)
+	if err != nil {
+		return nil, err
+	}
+	if !hasFields(object) {
+		return nil, errors.NewRuntimeError(s.Name, "Only instances have fields.")
+	}
+	current, err := i.getProperty(object, s.Name)
+	if err != nil {
+		return nil, err
+	}
+	value, err := i.compoundValue(current, s.Operator, s.Value)
+	if err != nil {
+		return nil, err
+	}
+	if err := i.setProperty(object, s.Name, value); err != nil {
+		return nil, err
+	}
+	if s.Postfix {
+		return current, nil
+	}
+	return value, nil
+}
+
+// hasFields tells if properties can be assigned to the object
+func hasFields(object any) bool {
+	switch object.(type) {
+	case *LoxInstance, *LoxClass, *HostObject:
+		return true
+	}
+	return false
+}
+
+// setProperty assigns the property of the object, running the setter if there is one. Properties with a getter
+// and no setter can't be assigned, except inside the getter.
+func (i *Interpreter) setProperty(object any, name tokens.Token, value any) error {
+	switch object := object.(type) {
+	case *LoxInstance:
+		if !i.inAccessor(object, name.Lexeme) {
+			member := object.class.findMember(name.Lexeme)
+			if member.setter != nil {
+				_, err := i.callAccessor(object, name, member.setter, []any{value})
+				return err
+			}
+			if member.getter != nil {
+				return errors.NewRuntimeError(name, fmt.Sprintf("Can't assign to property '%s', it has a getter and no setter.", name.Lexeme))
+			}
+		}
+		object.Set(name, value)
+	case *LoxClass:
+		object.Set(name, value)
+	case *HostObject:
+		return object.Set(name, value)
+	}
+	return nil
+}
+
+// callAccessor calls the getter or setter of the property of the instance
+func (i *Interpreter) callAccessor(instance *LoxInstance, name tokens.Token, function *LoxFunction, arguments []any) (any, error) {
+	i.accessors = append(i.accessors, accessor{instance: instance, property: name.Lexeme})
+	defer func() {
+		i.accessors = i.accessors[:len(i.accessors)-1]
+	}()
+	return i.call(function.Bind(instance), name, arguments)
+}
+
+// inAccessor tells if the innermost call running is a getter or setter of the property of the instance
+func (i *Interpreter) inAccessor(instance *LoxInstance, property string) bool {
+	return len(i.accessors) > 0 && i.accessors[len(i.accessors)-1] == accessor{instance: instance, property: property}
+}
+
+// compoundValue applies the binary operator of a compound assignment or increment to the current value of the target
+func (i *Interpreter) compoundValue(current any, operator tokens.Token, value Expr) (any, error) {
+	right, err := i.evaluate(value)
+	if err != nil {
+		return nil, err
+	}
+	return i.binary(tokens.BinaryOperator(operator), current, right)
 }
 
 func (i *Interpreter) VisitForSuper(s *SuperExpr) (any, error) {
-	distance := i.locals[s]
-	// TODO: check panic ./examples/superclass.glox
-	superclass := i.env.GetAt(distance, "super").(*LoxClass)
-	object := i.env.GetAt(distance-1, "this").(*LoxInstance)
-	method := superclass.FindMethod(s.Method.Lexeme)
-	if method == nil {
-		return nil, errors.NewRuntimeError(s.Method, fmt.Sprintf("Undefined property '%s'.", s.Method.Lexeme))
+	super := i.locals[s]
+	superclass := i.env.GetAt(super.depth, super.slot).(*LoxClass)
+	// "this" is the only variable of the environment right below "super"
+	this := i.env.GetAt(super.depth-1, 0)
+	if _, isClass := this.(*LoxClass); isClass {
+		// super in class methods
+		if method := superclass.FindClassMethod(s.Method.Lexeme); method != nil {
+			return method.Bind(this), nil
+		}
+	} else if member := superclass.findMember(s.Method.Lexeme); member.getter != nil {
+		return i.callAccessor(this.(*LoxInstance), s.Method, member.getter, nil)
+	} else if member.method != nil {
+		return member.method.Bind(this), nil
 	}
-	return method.Bind(object), nil
+	return nil, errors.NewRuntimeError(s.Method, fmt.Sprintf("Undefined property '%s'.", s.Method.Lexeme))
 }
 
 func (i *Interpreter) VisitForVariable(v *VariableExpr) (any, error) {
@@ -304,13 +868,34 @@ func (i *Interpreter) VisitForAssign(a *AssignExpr) (any, error) {
 	if err != nil {
 		return nil, err
 	}
-	distance, exits := i.locals[a]
+	l, exits := i.locals[a]
 	if !exits {
 		if err := i.globals.Assign(a.Name, value); err != nil {
 			return nil, err
 		}
+		return value, nil
+	}
+	i.env.AssignAt(l.depth, l.slot, value)
+	return value, nil
+}
+
+func (i *Interpreter) VisitForCompoundAssign(a *CompoundAssignExpr) (any, error) {
+	current, err := i.lookUpVariable(a.Name, a)
+	if err != nil {
+		return nil, err
+	}
+	value, err := i.compoundValue(current, a.Operator, a.Value)
+	if err != nil {
+		return nil, err
+	}
+	if l, exists := i.locals[a]; exists {
+		i.env.AssignAt(l.depth, l.slot, value)
+	} else if err := i.globals.Assign(a.Name, value); err != nil {
+		return nil, err
+	}
+	if a.Postfix {
+		return current, nil
 	}
-	i.env.AssignAt(distance, a.Name, value)
 	return value, nil
 }
 
@@ -331,35 +916,94 @@ func (i *Interpreter) VisitForBinary(binary *BinaryExpr) (any, error) {
 	if err != nil {
 		return nil, err
 	}
+	return i.binary(binary.Operator, left, right)
+}
 
-	switch binary.Operator.TokenType {
+// binary applies the operator to the operands, operators can be overloaded by the class of the left operand
+func (i *Interpreter) binary(operator tokens.Token, left, right any) (any, error) {
+	if instance, isInstance := left.(*LoxInstance); isInstance {
+		if result, overloaded, err := i.callOperator(instance, operator, right); overloaded {
+			return result, err
+		}
+	}
+
+	switch operator.TokenType {
 	case tokens.Plus:
-		return sum(binary.Operator, left, right)
+		return sum(operator, left, right)
 	case tokens.Minus:
-		return numOperation(binary.Operator, left, right, func(l, r float64) any { return l - r })
+		return numOperation(operator, left, right, func(l, r float64) any { return l - r })
 	case tokens.Slash:
-		return divide(binary.Operator, left, right)
+		return divide(operator, left, right, func(l, r float64) float64 { return l / r })
 	case tokens.Star:
-		return numOperation(binary.Operator, left, right, func(l, r float64) any { return l * r })
+		return numOperation(operator, left, right, func(l, r float64) any { return l * r })
+	case tokens.Percent:
+		return divide(operator, left, right, math.Mod)
+	case tokens.TildeSlash:
+		return divide(operator, left, right, func(l, r float64) float64 { return math.Trunc(l / r) })
 
 	case tokens.Greater:
-		return numOperation(binary.Operator, left, right, func(l, r float64) any { return l > r })
+		return numOperation(operator, left, right, func(l, r float64) any { return l > r })
 	case tokens.GreaterEqual:
-		return numOperation(binary.Operator, left, right, func(l, r float64) any { return l >= r })
+		return numOperation(operator, left, right, func(l, r float64) any { return l >= r })
 	case tokens.Less:
-		return numOperation(binary.Operator, left, right, func(l, r float64) any { return l < r })
+		return numOperation(operator, left, right, func(l, r float64) any { return l < r })
 	case tokens.LessEqual:
-		return numOperation(binary.Operator, left, right, func(l, r float64) any { return l <= r })
+		return numOperation(operator, left, right, func(l, r float64) any { return l <= r })
 
-	case tokens.EqualEqual: // Equality works the same as lox in go
-		return left == right, nil
+	case tokens.EqualEqual:
+		return isEqual(left, right), nil
 	case tokens.BangEqual:
-		return left != right, nil
+		return !isEqual(left, right), nil
 
 	}
 	return nil, nil // unreachable
 }
 
+// operatorMethods holds the names of the methods overloading the binary operators, `!=` is the negation of `==`
+var operatorMethods = map[tokens.TokenType]string{
+	tokens.Plus:         "__add__",
+	tokens.Minus:        "__sub__",
+	tokens.Star:         "__mul__",
+	tokens.Slash:        "__div__",
+	tokens.Percent:      "__mod__",
+	tokens.TildeSlash:   "__idiv__",
+	tokens.Less:         "__lt__",
+	tokens.LessEqual:    "__le__",
+	tokens.Greater:      "__gt__",
+	tokens.GreaterEqual: "__ge__",
+	tokens.EqualEqual:   "__eq__",
+	tokens.BangEqual:    "__eq__",
+}
+
+// callOperator calls the method of the instance overloading the operator, if it is defined. The right operand is
+// the argument of the call.
+func (i *Interpreter) callOperator(instance *LoxInstance, operator tokens.Token, right any) (result any, overloaded bool, err error) {
+	method := instance.class.FindMethod(operatorMethods[operator.TokenType])
+	if method == nil {
+		return nil, false, nil
+	}
+	result, err = i.call(method.Bind(instance), operator, []any{right})
+	if err != nil {
+		return nil, true, err
+	}
+	if operator.TokenType == tokens.BangEqual {
+		return !isTruthy(result), true, nil
+	}
+	return result, true, nil
+}
+
+// isEqual compares values as lox does: nil is only equal to nil, and instances are equal if they are the same
+// instance unless they overload `==`.
+func isEqual(a any, b any) bool {
+	if a == tokens.NilLiteral {
+		a = nil
+	}
+	if b == tokens.NilLiteral {
+		b = nil
+	}
+	return a == b
+}
+
 func (i *Interpreter) VisitForUnary(unary *UnaryExpr) (any, error) {
 	right, err := i.evaluate(unary.Right)
 	if err != nil {
@@ -383,16 +1027,16 @@ func (i *Interpreter) evaluate(expression Expr) (any, error) {
 	return expression.Accept(i)
 }
 
-func (i *Interpreter) Resolve(expression Expr, dept int) {
-	i.locals[expression] = dept
+func (i *Interpreter) Resolve(expression Expr, dept int, slot int) {
+	i.locals[expression] = local{depth: dept, slot: slot}
 }
 
 func (i *Interpreter) lookUpVariable(name tokens.Token, expression Expr) (any, error) {
-	distance, exists := i.locals[expression]
+	l, exists := i.locals[expression]
 	if !exists {
 		return i.globals.Get(name)
 	}
-	return i.env.GetAt(distance, name.Lexeme), nil
+	return i.env.GetAt(l.depth, l.slot), nil
 }
 
 // asNumber returns the number representation of the provided value or an error
@@ -427,9 +1071,13 @@ func numOperation(op tokens.Token, a, b any, f func(l, r float64) any) (any, err
 	return f(l, r), nil
 }
 
+func isNil(v any) bool {
+	return v == nil || v == tokens.NilLiteral
+}
+
 // isTruthy considers anything but nil or false value as true
 func isTruthy(v any) bool {
-	if v == tokens.NilLiteral {
+	if isNil(v) {
 		return false
 	}
 	if value, isBool := v.(bool); isBool {
@@ -454,7 +1102,8 @@ func sum(op tokens.Token, left any, right any) (any, error) {
 	return nil, errors.NewRuntimeError(op, "Operands must be two numbers or two strings.")
 }
 
-func divide(op tokens.Token, left any, right any) (any, error) {
+// divide performs the division operations '/', '%' and '~/', which fail if the divisor is zero
+func divide(op tokens.Token, left any, right any, f func(l, r float64) float64) (any, error) {
 	l, r, err := asNumbers(op, left, right)
 	if err != nil {
 		return nil, err
@@ -462,7 +1111,15 @@ func divide(op tokens.Token, left any, right any) (any, error) {
 	if r == .0 {
 		return nil, errors.NewRuntimeError(op, "Cannot divide by zero.")
 	}
-	return l / r, nil
+	return f(l, r), nil
+}
+
+// stringifyElement returns the string representation of a value contained in a collection, strings are quoted
+func stringifyElement(v any) string {
+	if s, isString := v.(string); isString {
+		return `"` + s + `"`
+	}
+	return stringify(v)
 }
 
 // stringify returns the string representation of the provided value taking care of special cases for nil and numbers.
@@ -471,7 +1128,7 @@ func stringify(v any) string {
 		return "nil"
 	}
 	if f, ok := v.(float64); ok {
-		strconv.FormatFloat(f, 'f', -1, 64)
+		return tokens.FormatNumber(f)
 	}
 	return fmt.Sprintf("%v", v)
 }
@@ -2,9 +2,11 @@ package interpreter
 
 import (
 	"fmt"
+	"glox/errors"
 	"glox/parser"
 	"glox/scanner"
 	"glox/tokens"
+	"io"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -43,7 +45,7 @@ func TestInterpreterExpressions(t *testing.T) {
 			t.Parallel()
 			expression := buildExpression(t, tc.input)
 
-			i := New()
+			i := New(errors.NewReporter(io.Discard), io.Discard)
 			result, err := i.interpret(expression)
 			require.NoError(t, err)
 			require.Equal(t, tc.expected, result)
@@ -77,7 +79,7 @@ func TestInterpreterExpressionErrors(t *testing.T) {
 		t.Run(tc.input, func(t *testing.T) {
 			t.Parallel()
 			expression := buildExpression(t, tc.input)
-			i := New()
+			i := New(errors.NewReporter(io.Discard), io.Discard)
 			result, err := i.interpret(expression)
 			t.Logf("Result: %v", result)
 			require.Error(t, err)
@@ -86,9 +88,10 @@ func TestInterpreterExpressionErrors(t *testing.T) {
 }
 
 func buildExpression(t *testing.T, input string) Expr {
-	scanner := scanner.NewScanner(input)
+	reporter := errors.NewReporter(io.Discard)
+	scanner := scanner.NewScanner(input, reporter)
 	scanner.ScanTokens()
-	parser := parser.NewParser[any](scanner.Tokens())
+	parser := parser.NewParser[any](scanner.Tokens(), reporter)
 	expression, err := parser.Expression()
 	require.NoError(t, err)
 	return expression
@@ -0,0 +1,147 @@
+package interpreter
+
+import (
+	"fmt"
+	"glox/errors"
+	"glox/tokens"
+	"math"
+	"strings"
+)
+
+// indexable is implemented by values supporting the index operator, Eg: `list[0]`
+type indexable interface {
+	GetIndex(bracket tokens.Token, index any) (any, error)
+	SetIndex(bracket tokens.Token, index any, value any) error
+}
+
+// LoxList is the value of list literals, Eg: `[1, 2, 3]`. Negative indexes count from the end of the list.
+type LoxList struct {
+	Elements []any
+}
+
+func (l *LoxList) String() string {
+	elements := make([]string, len(l.Elements))
+	for i, element := range l.Elements {
+		elements[i] = stringifyElement(element)
+	}
+	return "[" + strings.Join(elements, ", ") + "]"
+}
+
+func (l *LoxList) GetIndex(bracket tokens.Token, index any) (any, error) {
+	i, err := listIndex(index, len(l.Elements))
+	if err != nil {
+		return nil, errors.NewRuntimeError(bracket, err.Error())
+	}
+	return l.Elements[i], nil
+}
+
+func (l *LoxList) SetIndex(bracket tokens.Token, index any, value any) error {
+	i, err := listIndex(index, len(l.Elements))
+	if err != nil {
+		return errors.NewRuntimeError(bracket, err.Error())
+	}
+	l.Elements[i] = value
+	return nil
+}
+
+// Slice returns a new list holding the elements from start (included) to end (excluded). Nil bounds refer to
+// the beginning and the end of the list and out of range bounds are clamped.
+func (l *LoxList) Slice(bracket tokens.Token, start any, end any) (*LoxList, error) {
+	from, err := sliceBound(start, 0, len(l.Elements))
+	if err != nil {
+		return nil, errors.NewRuntimeError(bracket, err.Error())
+	}
+	to, err := sliceBound(end, len(l.Elements), len(l.Elements))
+	if err != nil {
+		return nil, errors.NewRuntimeError(bracket, err.Error())
+	}
+	to = max(from, to)
+	return &LoxList{Elements: append([]any{}, l.Elements[from:to]...)}, nil
+}
+
+func (l *LoxList) Get(name tokens.Token) (any, error) {
+	switch name.Lexeme {
+	case "len":
+		return builtinMethod("len", 0, func(arguments []any) (any, error) {
+			return float64(len(l.Elements)), nil
+		}), nil
+	case "push":
+		return builtinMethod("push", 1, func(arguments []any) (any, error) {
+			l.Elements = append(l.Elements, arguments[0])
+			return nil, nil
+		}), nil
+	case "pop":
+		return builtinMethod("pop", 0, func(arguments []any) (any, error) {
+			if len(l.Elements) == 0 {
+				return nil, fmt.Errorf("Can't pop from an empty list.")
+			}
+			last := l.Elements[len(l.Elements)-1]
+			l.Elements = l.Elements[:len(l.Elements)-1]
+			return last, nil
+		}), nil
+	case "insert":
+		return builtinMethod("insert", 2, func(arguments []any) (any, error) {
+			i, err := integer(arguments[0])
+			if err != nil {
+				return nil, err
+			}
+			// inserting at the end is allowed, negative indexes count from the last element
+			if i != len(l.Elements) {
+				if i, err = listIndex(arguments[0], len(l.Elements)); err != nil {
+					return nil, err
+				}
+			}
+			l.Elements = append(l.Elements[:i], append([]any{arguments[1]}, l.Elements[i:]...)...)
+			return nil, nil
+		}), nil
+	case "remove":
+		return builtinMethod("remove", 1, func(arguments []any) (any, error) {
+			i, err := listIndex(arguments[0], len(l.Elements))
+			if err != nil {
+				return nil, err
+			}
+			removed := l.Elements[i]
+			l.Elements = append(l.Elements[:i], l.Elements[i+1:]...)
+			return removed, nil
+		}), nil
+	}
+	return nil, errors.NewRuntimeError(name, fmt.Sprintf("Undefined property '%s'.", name.Lexeme))
+}
+
+// listIndex returns the position corresponding to the provided index in a list of the provided length
+func listIndex(index any, length int) (int, error) {
+	i, err := integer(index)
+	if err != nil {
+		return 0, err
+	}
+	if i < 0 {
+		i += length
+	}
+	if i < 0 || i >= length {
+		return 0, fmt.Errorf("List index out of range.")
+	}
+	return i, nil
+}
+
+// sliceBound returns the position corresponding to a slice bound, nil bounds return the provided default
+func sliceBound(bound any, defaultBound int, length int) (int, error) {
+	if bound == nil {
+		return defaultBound, nil
+	}
+	i, err := integer(bound)
+	if err != nil {
+		return 0, err
+	}
+	if i < 0 {
+		i += length
+	}
+	return min(max(i, 0), length), nil
+}
+
+func integer(v any) (int, error) {
+	f, isNumber := v.(float64)
+	if !isNumber || f != math.Trunc(f) {
+		return 0, fmt.Errorf("List index must be an integer.")
+	}
+	return int(f), nil
+}
@@ -0,0 +1,109 @@
+package interpreter
+
+import (
+	"fmt"
+	"glox/errors"
+	"glox/tokens"
+	"slices"
+	"strings"
+)
+
+// LoxMap is the value of map literals, Eg: `{"a": 1}`. Keys can be strings, numbers, booleans or nil, and they
+// keep their insertion order.
+type LoxMap struct {
+	keys   []any
+	values map[any]any
+}
+
+func NewMap() *LoxMap {
+	return &LoxMap{values: map[any]any{}}
+}
+
+func (m *LoxMap) String() string {
+	entries := make([]string, len(m.keys))
+	for i, key := range m.keys {
+		entries[i] = stringifyElement(key) + ": " + stringifyElement(m.values[key])
+	}
+	return "{" + strings.Join(entries, ", ") + "}"
+}
+
+func (m *LoxMap) GetIndex(bracket tokens.Token, index any) (any, error) {
+	key, err := mapKey(index)
+	if err != nil {
+		return nil, errors.NewRuntimeError(bracket, err.Error())
+	}
+	value, exists := m.values[key]
+	if !exists {
+		return nil, errors.NewRuntimeError(bracket, fmt.Sprintf("Undefined key %s.", stringifyElement(key)))
+	}
+	return value, nil
+}
+
+func (m *LoxMap) SetIndex(bracket tokens.Token, index any, value any) error {
+	key, err := mapKey(index)
+	if err != nil {
+		return errors.NewRuntimeError(bracket, err.Error())
+	}
+	if _, exists := m.values[key]; !exists {
+		m.keys = append(m.keys, key)
+	}
+	m.values[key] = value
+	return nil
+}
+
+func (m *LoxMap) Get(name tokens.Token) (any, error) {
+	switch name.Lexeme {
+	case "len":
+		return builtinMethod("len", 0, func(arguments []any) (any, error) {
+			return float64(len(m.keys)), nil
+		}), nil
+	case "keys":
+		return builtinMethod("keys", 0, func(arguments []any) (any, error) {
+			return &LoxList{Elements: slices.Clone(m.keys)}, nil
+		}), nil
+	case "values":
+		return builtinMethod("values", 0, func(arguments []any) (any, error) {
+			values := make([]any, len(m.keys))
+			for i, key := range m.keys {
+				values[i] = m.values[key]
+			}
+			return &LoxList{Elements: values}, nil
+		}), nil
+	case "has":
+		return builtinMethod("has", 1, func(arguments []any) (any, error) {
+			key, err := mapKey(arguments[0])
+			if err != nil {
+				return nil, err
+			}
+			_, exists := m.values[key]
+			return exists, nil
+		}), nil
+	case "delete":
+		// delete returns whether the key existed
+		return builtinMethod("delete", 1, func(arguments []any) (any, error) {
+			key, err := mapKey(arguments[0])
+			if err != nil {
+				return nil, err
+			}
+			if _, exists := m.values[key]; !exists {
+				return false, nil
+			}
+			delete(m.values, key)
+			m.keys = slices.DeleteFunc(m.keys, func(k any) bool { return k == key })
+			return true, nil
+		}), nil
+	}
+	return nil, errors.NewRuntimeError(name, fmt.Sprintf("Undefined property '%s'.", name.Lexeme))
+}
+
+// mapKey checks that the provided value can be used as key, nil values are normalized so there is a single
+// nil key
+func mapKey(v any) (any, error) {
+	switch v.(type) {
+	case nil, tokens.NilLiteralType:
+		return tokens.NilLiteral, nil
+	case string, float64, bool:
+		return v, nil
+	}
+	return nil, fmt.Errorf("Map keys must be strings, numbers, booleans or nil.")
+}
@@ -0,0 +1,140 @@
+package interpreter
+
+import (
+	"fmt"
+	"glox/environment"
+	"glox/errors"
+	"glox/tokens"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// ModuleLoader reads, parses and resolves the module stored in the provided path
+type ModuleLoader interface {
+	LoadModule(path string) ([]Stmt, error)
+}
+
+// LoxModule is the value of an imported module. It exposes the globals the module defines, except those whose
+// name starts with an underscore. The natives and host globals visible from the module are not exported.
+type LoxModule struct {
+	Name    string
+	Path    string
+	globals *environment.Environment
+	// exports holds the names declared by the top-level statements of the module
+	exports map[string]bool
+}
+
+func (m *LoxModule) String() string {
+	return fmt.Sprintf("<module %s>", m.Name)
+}
+
+func (m *LoxModule) Get(name tokens.Token) (any, error) {
+	if m.exports[name.Lexeme] && !strings.HasPrefix(name.Lexeme, "_") {
+		if value, err := m.globals.Get(name); err == nil {
+			return value, nil
+		}
+	}
+	return nil, errors.NewRuntimeError(name, fmt.Sprintf("Module '%s' does not export '%s'.", m.Name, name.Lexeme))
+}
+
+// SetModuleLoader sets the loader used by import statements
+func (i *Interpreter) SetModuleLoader(loader ModuleLoader) {
+	i.loader = loader
+}
+
+func (i *Interpreter) VisitForImport(s *ImportStmt) (any, error) {
+	module, err := i.importModule(s)
+	if err != nil {
+		return nil, err
+	}
+	i.env.Define(s.Name.Lexeme, module)
+	return nil, nil
+}
+
+// importModule returns the module imported by the provided statement. Modules are executed the first time
+// they are imported, their paths are relative to the importing file.
+func (i *Interpreter) importModule(s *ImportStmt) (*LoxModule, error) {
+	if i.loader == nil {
+		return nil, errors.NewRuntimeError(s.Keyword, "Modules can't be imported in this context.")
+	}
+	path, err := filepath.Abs(filepath.Join(filepath.Dir(s.Keyword.File), s.Path.Literal.(string)))
+	if err != nil {
+		return nil, errors.NewRuntimeError(s.Path, err.Error())
+	}
+	if module, loaded := i.modules[path]; loaded {
+		return &LoxModule{Name: s.Name.Lexeme, Path: path, globals: module.globals, exports: module.exports}, nil
+	}
+
+	// the file importing the first module is the root of the import chain
+	if len(i.importing) == 0 {
+		importer, err := filepath.Abs(s.Keyword.File)
+		if err != nil {
+			return nil, errors.NewRuntimeError(s.Path, err.Error())
+		}
+		i.importing = append(i.importing, importer)
+		defer func() {
+			i.importing = i.importing[:0]
+		}()
+	}
+	if start := slices.Index(i.importing, path); start >= 0 {
+		return nil, errors.NewRuntimeError(s.Path, fmt.Sprintf("Import cycle: %s.", i.importChain(start, path)))
+	}
+
+	statements, err := i.loader.LoadModule(path)
+	if err != nil {
+		return nil, errors.NewRuntimeError(s.Path, fmt.Sprintf("Could not import module '%s': %s.", s.Path.Literal, err))
+	}
+
+	i.importing = append(i.importing, path)
+	previousEnv, previousGlobals := i.env, i.globals
+	i.globals = i.newGlobals()
+	i.env = i.globals
+	defer func() {
+		i.importing = i.importing[:len(i.importing)-1]
+		i.env, i.globals = previousEnv, previousGlobals
+	}()
+
+	module := &LoxModule{Name: s.Name.Lexeme, Path: path, globals: i.globals, exports: declaredNames(statements)}
+	for _, statement := range statements {
+		if _, err := i.execute(statement); err != nil {
+			return nil, err
+		}
+	}
+	i.modules[path] = module
+	return module, nil
+}
+
+// declaredNames returns the names of the variables, functions, classes, traits and modules declared by the statements
+func declaredNames(statements []Stmt) map[string]bool {
+	names := map[string]bool{}
+	for _, statement := range statements {
+		switch s := statement.(type) {
+		case *VarStmt:
+			names[s.Name.Lexeme] = true
+		case *FunctionStmt:
+			names[s.Name.Lexeme] = true
+		case *ClassStmt:
+			names[s.Name.Lexeme] = true
+		case *TraitStmt:
+			names[s.Name.Lexeme] = true
+		case *ImportStmt:
+			names[s.Name.Lexeme] = true
+		}
+	}
+	return names
+}
+
+// importChain describes the imports from the provided position of the import chain to path, Eg: `a.glox ->
+// b.glox -> a.glox`. Paths are shown relative to the root of the chain.
+func (i *Interpreter) importChain(start int, path string) string {
+	root := filepath.Dir(i.importing[0])
+	chain := []string{}
+	for _, p := range append(slices.Clone(i.importing[start:]), path) {
+		if relative, err := filepath.Rel(root, p); err == nil {
+			p = relative
+		}
+		chain = append(chain, p)
+	}
+	return strings.Join(chain, " -> ")
+}
@@ -12,6 +12,42 @@ func (c *clock) Call(interpreter *Interpreter, arguments []any) (any, error) {
 	return float64(time.Now().UnixMilli()), nil
 }
 
+func (c *clock) MaxArity() int {
+	return 0
+}
+
 func (c *clock) String() string {
 	return "<native fn>"
 }
+
+// nativeFunction is a function implemented in Go. Returned errors that are not runtime errors are reported at
+// the call site.
+type nativeFunction struct {
+	name     string
+	arity    int
+	maxArity int
+	fn       func(interpreter *Interpreter, arguments []any) (any, error)
+}
+
+func (f *nativeFunction) Arity() int {
+	return f.arity
+}
+
+func (f *nativeFunction) MaxArity() int {
+	return f.maxArity
+}
+
+func (f *nativeFunction) Call(interpreter *Interpreter, arguments []any) (any, error) {
+	return f.fn(interpreter, arguments)
+}
+
+func (f *nativeFunction) String() string {
+	return "<native fn>"
+}
+
+// builtinMethod returns a method of a built-in value, fn usually captures the value
+func builtinMethod(name string, arity int, fn func(arguments []any) (any, error)) *nativeFunction {
+	return &nativeFunction{name: name, arity: arity, maxArity: arity, fn: func(_ *Interpreter, arguments []any) (any, error) {
+		return fn(arguments)
+	}}
+}
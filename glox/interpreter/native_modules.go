@@ -0,0 +1,107 @@
+package interpreter
+
+import (
+	"fmt"
+	"glox/environment"
+	"glox/errors"
+	"glox/tokens"
+)
+
+// NativeModule groups native functions under a namespace, Eg: `math.sqrt(2)`
+type NativeModule struct {
+	Name    string
+	members map[string]any
+}
+
+func NewNativeModule(name string) *NativeModule {
+	return &NativeModule{Name: name, members: map[string]any{}}
+}
+
+// Define adds a native function to the module
+func (m *NativeModule) Define(name string, arity int, fn func(interpreter *Interpreter, arguments []any) (any, error)) {
+	m.members[name] = &nativeFunction{name: m.Name + "." + name, arity: arity, maxArity: arity, fn: fn}
+}
+
+// DefineVariadic adds a native function accepting any number of arguments, from minArity on
+func (m *NativeModule) DefineVariadic(name string, minArity int, fn func(interpreter *Interpreter, arguments []any) (any, error)) {
+	m.members[name] = &nativeFunction{name: m.Name + "." + name, arity: minArity, maxArity: NoMaxArity, fn: fn}
+}
+
+func (m *NativeModule) String() string {
+	return fmt.Sprintf("<module %s>", m.Name)
+}
+
+func (m *NativeModule) Get(name tokens.Token) (any, error) {
+	if member, exists := m.members[name.Lexeme]; exists {
+		return member, nil
+	}
+	return nil, errors.NewRuntimeError(name, fmt.Sprintf("Module '%s' does not export '%s'.", m.Name, name.Lexeme))
+}
+
+// RegisterModule makes the provided module available as a global variable named after it, in the programs run
+// by the interpreter and in the modules they import.
+func (i *Interpreter) RegisterModule(module *NativeModule) {
+	i.defineHostGlobal(module.Name, module)
+}
+
+// defineHostGlobal defines a global variable in the current program and in the modules imported from now on
+func (i *Interpreter) defineHostGlobal(name string, value any) {
+	i.hostGlobals[name] = value
+	i.globals.Define(name, value)
+}
+
+// newGlobals returns a global environment holding the built-in functions and the values defined by the host
+func (i *Interpreter) newGlobals() *environment.Environment {
+	env := environment.NewGlobals()
+	env.Define("clock", &clock{})
+	for _, builtin := range builtins {
+		env.Define(builtin.name, builtin)
+	}
+	for name, value := range i.hostGlobals {
+		env.Define(name, value)
+	}
+	return env
+}
+
+// argument returns the argument in the provided position, checking its type
+func argument[T any](function string, arguments []any, position int) (T, error) {
+	value, isType := arguments[position].(T)
+	if !isType {
+		return value, fmt.Errorf("Argument %d of '%s' must be %s.", position+1, function, typeDescription(value))
+	}
+	return value, nil
+}
+
+func typeDescription(v any) string {
+	switch v.(type) {
+	case string:
+		return "a string"
+	case float64:
+		return "a number"
+	case bool:
+		return "a boolean"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+// Natives returns the native functions available to the programs run by the interpreter, by name. The functions of
+// native modules are named after their module, Eg: "math.sqrt".
+func (i *Interpreter) Natives() map[string]GloxCallable {
+	natives := map[string]GloxCallable{"clock": &clock{}}
+	for _, builtin := range builtins {
+		natives[builtin.name] = builtin
+	}
+	for name, value := range i.hostGlobals {
+		switch value := value.(type) {
+		case GloxCallable:
+			natives[name] = value
+		case *NativeModule:
+			for member, memberValue := range value.members {
+				if function, isCallable := memberValue.(GloxCallable); isCallable {
+					natives[name+"."+member] = function
+				}
+			}
+		}
+	}
+	return natives
+}
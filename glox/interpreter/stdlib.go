@@ -0,0 +1,235 @@
+package interpreter
+
+import (
+	"bufio"
+	"fmt"
+	"glox/tokens"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// builtins are the native functions available as global variables
+var builtins = []*nativeFunction{
+	{name: "type", arity: 1, maxArity: 1, fn: func(_ *Interpreter, arguments []any) (any, error) {
+		return typeOf(arguments[0]), nil
+	}},
+	{name: "str", arity: 1, maxArity: 1, fn: func(_ *Interpreter, arguments []any) (any, error) {
+		return stringify(arguments[0]), nil
+	}},
+	{name: "num", arity: 1, maxArity: 1, fn: func(_ *Interpreter, arguments []any) (any, error) {
+		switch v := arguments[0].(type) {
+		case float64:
+			return v, nil
+		case string:
+			if n, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil {
+				return n, nil
+			}
+		}
+		return nil, fmt.Errorf("Can't convert %s to a number.", stringifyElement(arguments[0]))
+	}},
+	{name: "input", arity: 0, maxArity: 0, fn: func(interpreter *Interpreter, _ []any) (any, error) {
+		line, err := interpreter.stdin.ReadString('\n')
+		if err == io.EOF && line == "" {
+			return nil, nil
+		}
+		if err != nil && err != io.EOF {
+			return nil, fmt.Errorf("Could not read input: %s.", err)
+		}
+		return strings.TrimRight(line, "\r\n"), nil
+	}},
+}
+
+// typeOf returns the name of the type of the provided value
+func typeOf(v any) string {
+	switch v.(type) {
+	case nil, tokens.NilLiteralType:
+		return "nil"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case *LoxClass:
+		return "class"
+	case *LoxTrait:
+		return "trait"
+	case *LoxInstance, *HostObject:
+		return "instance"
+	case *LoxList:
+		return "list"
+	case *LoxMap:
+		return "map"
+	case *LoxModule, *NativeModule:
+		return "module"
+	case *LoxError:
+		return "error"
+	case GloxCallable:
+		return "function"
+	}
+	return "unknown"
+}
+
+// SetStdin sets the reader input() reads lines from
+func (i *Interpreter) SetStdin(stdin io.Reader) {
+	if reader, isBuffered := stdin.(*bufio.Reader); isBuffered {
+		i.stdin = reader
+		return
+	}
+	i.stdin = bufio.NewReader(stdin)
+}
+
+func stringModule() *NativeModule {
+	m := NewNativeModule("string")
+	m.Define("len", 1, func(_ *Interpreter, arguments []any) (any, error) {
+		s, err := argument[string]("string.len", arguments, 0)
+		if err != nil {
+			return nil, err
+		}
+		return float64(utf8.RuneCountInString(s)), nil
+	})
+	m.Define("substr", 3, func(_ *Interpreter, arguments []any) (any, error) {
+		// substr(s, start, end) returns the characters from start (included) to end (excluded)
+		s, err := argument[string]("string.substr", arguments, 0)
+		if err != nil {
+			return nil, err
+		}
+		runes := []rune(s)
+		start, err := sliceBound(arguments[1], 0, len(runes))
+		if err != nil {
+			return nil, err
+		}
+		end, err := sliceBound(arguments[2], len(runes), len(runes))
+		if err != nil {
+			return nil, err
+		}
+		return string(runes[start:max(start, end)]), nil
+	})
+	m.Define("split", 2, func(_ *Interpreter, arguments []any) (any, error) {
+		s, err := argument[string]("string.split", arguments, 0)
+		if err != nil {
+			return nil, err
+		}
+		separator, err := argument[string]("string.split", arguments, 1)
+		if err != nil {
+			return nil, err
+		}
+		parts := strings.Split(s, separator)
+		elements := make([]any, len(parts))
+		for i, part := range parts {
+			elements[i] = part
+		}
+		return &LoxList{Elements: elements}, nil
+	})
+	m.Define("upper", 1, func(_ *Interpreter, arguments []any) (any, error) {
+		s, err := argument[string]("string.upper", arguments, 0)
+		if err != nil {
+			return nil, err
+		}
+		return strings.ToUpper(s), nil
+	})
+	m.Define("lower", 1, func(_ *Interpreter, arguments []any) (any, error) {
+		s, err := argument[string]("string.lower", arguments, 0)
+		if err != nil {
+			return nil, err
+		}
+		return strings.ToLower(s), nil
+	})
+	m.Define("indexOf", 2, func(_ *Interpreter, arguments []any) (any, error) {
+		// indexOf returns the position (in characters) of the first occurrence of substring, or -1
+		s, err := argument[string]("string.indexOf", arguments, 0)
+		if err != nil {
+			return nil, err
+		}
+		substring, err := argument[string]("string.indexOf", arguments, 1)
+		if err != nil {
+			return nil, err
+		}
+		index := strings.Index(s, substring)
+		if index < 0 {
+			return -1.0, nil
+		}
+		return float64(utf8.RuneCountInString(s[:index])), nil
+	})
+	m.Define("replace", 3, func(_ *Interpreter, arguments []any) (any, error) {
+		// replace replaces every occurrence of old by new
+		s, err := argument[string]("string.replace", arguments, 0)
+		if err != nil {
+			return nil, err
+		}
+		old, err := argument[string]("string.replace", arguments, 1)
+		if err != nil {
+			return nil, err
+		}
+		replacement, err := argument[string]("string.replace", arguments, 2)
+		if err != nil {
+			return nil, err
+		}
+		return strings.ReplaceAll(s, old, replacement), nil
+	})
+	return m
+}
+
+func mathModule() *NativeModule {
+	m := NewNativeModule("math")
+	m.Define("sqrt", 1, numberFunction("math.sqrt", math.Sqrt))
+	m.Define("floor", 1, numberFunction("math.floor", math.Floor))
+	m.Define("pow", 2, func(_ *Interpreter, arguments []any) (any, error) {
+		base, err := argument[float64]("math.pow", arguments, 0)
+		if err != nil {
+			return nil, err
+		}
+		exponent, err := argument[float64]("math.pow", arguments, 1)
+		if err != nil {
+			return nil, err
+		}
+		return math.Pow(base, exponent), nil
+	})
+	m.DefineVariadic("min", 1, reduceNumbers("math.min", math.Min))
+	m.DefineVariadic("max", 1, reduceNumbers("math.max", math.Max))
+	// random returns a number in [0, 1), seed makes the sequence reproducible
+	m.Define("random", 0, func(interpreter *Interpreter, _ []any) (any, error) {
+		return interpreter.random.Float64(), nil
+	})
+	m.Define("seed", 1, func(interpreter *Interpreter, arguments []any) (any, error) {
+		seed, err := argument[float64]("math.seed", arguments, 0)
+		if err != nil {
+			return nil, err
+		}
+		interpreter.random.Seed(int64(seed))
+		return nil, nil
+	})
+	return m
+}
+
+// reduceNumbers returns a native function combining its number arguments with f
+func reduceNumbers(name string, f func(float64, float64) float64) func(*Interpreter, []any) (any, error) {
+	return func(_ *Interpreter, arguments []any) (any, error) {
+		result, err := argument[float64](name, arguments, 0)
+		if err != nil {
+			return nil, err
+		}
+		for i := 1; i < len(arguments); i++ {
+			n, err := argument[float64](name, arguments, i)
+			if err != nil {
+				return nil, err
+			}
+			result = f(result, n)
+		}
+		return result, nil
+	}
+}
+
+// numberFunction returns a native function applying f to its number argument
+func numberFunction(name string, f func(float64) float64) func(*Interpreter, []any) (any, error) {
+	return func(_ *Interpreter, arguments []any) (any, error) {
+		n, err := argument[float64](name, arguments, 0)
+		if err != nil {
+			return nil, err
+		}
+		return f(n), nil
+	}
+}
@@ -2,12 +2,11 @@ package main
 
 import (
 	"bufio"
+	"errors"
+	"flag"
 	"fmt"
-	"glox/errors"
-	"glox/interpreter"
-	"glox/parser"
-	"glox/resolver"
-	"glox/scanner"
+	gloxErrors "glox/errors"
+	"glox/runtime"
 	"os"
 )
 
@@ -15,63 +14,97 @@ func main() {
 
 	chap05Hack(false) // switch to true to run chap05 hack only
 
-	switch len(os.Args) {
+	errorFormat := flag.String("error-format", "pretty", "format used to print errors: 'pretty' or 'short'")
+	backend := flag.String("backend", "tree", "backend used to run programs: 'tree' or 'vm'")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: glox [options] [script]")
+		fmt.Fprintln(os.Stderr, "       glox [options] lint script")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	loxRuntime := runtime.New(os.Stdout, os.Stderr)
+	switch *errorFormat {
+	case "pretty":
+		loxRuntime.SetErrorFormat(gloxErrors.FormatPretty)
+	case "short":
+		loxRuntime.SetErrorFormat(gloxErrors.FormatShort)
+	default:
+		flag.Usage()
+		os.Exit(64)
+	}
+	switch *backend {
+	case "tree":
+		loxRuntime.SetBackend(runtime.BackendTree)
+	case "vm":
+		loxRuntime.SetBackend(runtime.BackendVM)
+	default:
+		flag.Usage()
+		os.Exit(64)
+	}
+
+	switch flag.NArg() {
+	case 0:
+		runPrompt(loxRuntime)
 	case 1:
-		runPrompt()
+		runFile(loxRuntime, flag.Arg(0))
 	case 2:
-		runFile(os.Args[1])
+		if flag.Arg(0) != "lint" {
+			flag.Usage()
+			os.Exit(64)
+		}
+		lintFile(loxRuntime, flag.Arg(1))
 	default:
-		fmt.Fprintln(os.Stderr, "Usage: glox [script]")
+		flag.Usage()
 		os.Exit(64)
 	}
 }
 
-func runFile(path string) {
+func runFile(loxRuntime *runtime.Runtime, path string) {
 	bytes, err := os.ReadFile(path)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Could not read file in %q: %s\n", path, err)
 		os.Exit(64)
 	}
-	run(string(bytes))
-	if errors.ErrorFound() {
+	_, _, err = loxRuntime.EvalSource(path, string(bytes))
+	if errors.Is(err, runtime.ErrCompile) {
 		os.Exit(65)
 	}
-	if errors.RuntimeErrorFound() {
+	if err != nil {
 		os.Exit(70)
 	}
 }
 
-func runPrompt() {
+// lintFile reports the problems found in the provided script without running it. It exits with status 1 if any
+// warning is reported.
+func lintFile(loxRuntime *runtime.Runtime, path string) {
+	bytes, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Could not read file in %q: %s\n", path, err)
+		os.Exit(64)
+	}
+	diagnostics, err := loxRuntime.Lint(path, string(bytes))
+	if err != nil {
+		os.Exit(65)
+	}
+	if len(diagnostics) > 0 {
+		os.Exit(1)
+	}
+}
+
+func runPrompt(loxRuntime *runtime.Runtime) {
 	reader := bufio.NewReader(os.Stdin)
-	for {
+	// input() reads from the same buffer as the prompt
+	loxRuntime.SetStdin(reader)
+	// each line gets its own name, so errors in functions defined in previous lines show the right source
+	for n := 1; ; n++ {
 		fmt.Print("> ")
 		line, err := reader.ReadString('\n')
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error reading input: %s", err)
 			os.Exit(64)
 		}
-		run(line)
-		errors.ResetError()
+		_, _, _ = loxRuntime.EvalSource(fmt.Sprintf("<stdin:%d>", n), line) // errors are already reported
 	}
 
 }
-
-func run(source string) {
-	scanner := scanner.NewScanner(source)
-	scanner.ScanTokens()
-	token_list := scanner.Tokens()
-	parser := parser.NewParser[any](token_list)
-	statements, _ := parser.Parse()
-	if errors.ErrorFound() {
-		return
-	}
-	loxInterpreter := interpreter.New()
-	resolver := resolver.NewResolver(&loxInterpreter)
-	if err := resolver.ResolveStatements(statements); err != nil {
-		return // TODO: check if this is reachable
-	}
-	if errors.ErrorFound() {
-		return
-	}
-	loxInterpreter.Interpret(statements)
-}
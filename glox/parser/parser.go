@@ -7,56 +7,79 @@ import (
 	"glox/expr"
 	"glox/stmt"
 	"glox/tokens"
+	"strings"
 )
 
 const ARGUMENTS_LIMIT = 255
 
 var ErrParse = errors.New("parse Error")
 
+// ParseError holds the information of an error found while parsing
+type ParseError struct {
+	Token   tokens.Token
+	Message string
+	Span    tokens.Span
+}
+
+func (e *ParseError) Error() string {
+	return e.Message
+}
+
+func (e *ParseError) Unwrap() error {
+	return ErrParse
+}
+
 type Parser[T any] struct {
-	tokens  []tokens.Token
-	current int
+	tokens   []tokens.Token
+	current  int
+	reporter *gloxErrors.Reporter
+	errors   []ParseError
 }
 
-func NewParser[T any](token_list []tokens.Token) Parser[T] {
+func NewParser[T any](token_list []tokens.Token, reporter *gloxErrors.Reporter) Parser[T] {
 	return Parser[T]{
-		tokens:  token_list,
-		current: 0,
+		tokens:   token_list,
+		current:  0,
+		reporter: reporter,
 	}
 }
 
-func (p *Parser[T]) Parse() ([]stmt.Stmt[T], error) {
+// Parse returns the statements that could be parsed along with the errors found. The parser recovers from
+// errors by synchronizing at the next statement, so all the errors in the source are collected.
+func (p *Parser[T]) Parse() ([]stmt.Stmt[T], []ParseError) {
 	statements := []stmt.Stmt[T]{}
 	for !p.isAtEnd() {
-		statement := p.declaration()
-		statements = append(statements, statement)
+		if statement := p.declaration(); statement != nil {
+			statements = append(statements, statement)
+		}
 	}
-	return statements, nil
+	return statements, p.errors
 }
 
+// declaration returns the next declaration or nil if it contains errors
 func (p *Parser[T]) declaration() stmt.Stmt[T] {
 	// Get a regular statement if no other declaration matches
 	statementGetter := p.statement
 
 	if p.match(tokens.Class) {
-		stmt, err := p.classDeclaration()
-		if err != nil {
-			gloxErrors.AtToken(p.previous(), fmt.Sprintf("%s", err))
-			return nil
-		}
-		return stmt
-	}
-
-	if p.match(tokens.Fun) {
+		statementGetter = p.classDeclaration
+	} else if p.match(tokens.Trait) {
+		statementGetter = p.traitDeclaration
+	} else if p.check(tokens.Fun) && p.peekNext().TokenType != tokens.LeftParen {
+		// `fun (` starts an anonymous function in an expression statement
+		p.advance()
 		statementGetter = func() (stmt.Stmt[T], error) {
 			return p.function("function")
 		}
 	} else if p.match(tokens.Var) {
 		statementGetter = p.varDeclaration
+	} else if p.match(tokens.Import) {
+		statementGetter = p.importDeclaration
 	}
 
 	statement, err := statementGetter()
 	if err != nil {
+		p.recordError(err)
 		// Synchronize if we found any parsing error
 		p.synchronize()
 		return nil
@@ -64,6 +87,27 @@ func (p *Parser[T]) declaration() stmt.Stmt[T] {
 	return statement
 }
 
+func (p *Parser[T]) importDeclaration() (stmt.Stmt[T], error) {
+	// import "path/to/module.glox" as name;
+	keyword := p.previous()
+	path, err := p.consume(tokens.String, "Expect module path after 'import'.")
+	if err != nil {
+		return nil, err
+	}
+	if !p.checkContextual("as") {
+		return nil, parseError(p.peek(), "Expect 'as' after module path.")
+	}
+	p.advance() // as
+	name, err := p.consume(tokens.Identifier, "Expect module name after 'as'.")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.consume(tokens.Semicolon, "Expect ';' after import."); err != nil {
+		return nil, err
+	}
+	return &stmt.Import[T]{Keyword: keyword, Path: path, Name: name}, nil
+}
+
 func (p *Parser[T]) classDeclaration() (stmt.Stmt[T], error) {
 	name, err := p.consume(tokens.Identifier, "Expect class name.")
 	if err != nil {
@@ -79,10 +123,49 @@ func (p *Parser[T]) classDeclaration() (stmt.Stmt[T], error) {
 		superClass = &expr.Variable[T]{Name: name}
 	}
 
+	traits := []*expr.Variable[T]{}
+	if p.checkContextual("with") {
+		p.advance() // with
+		for {
+			name, err := p.consume(tokens.Identifier, "Expect trait name.")
+			if err != nil {
+				return nil, err
+			}
+			traits = append(traits, &expr.Variable[T]{Name: name})
+			if !p.match(tokens.Comma) {
+				break
+			}
+		}
+	}
+
 	_, err = p.consume(tokens.LeftBrace, "Expect '{' before class body.")
 	if err != nil {
 		return nil, err
 	}
+	class := &stmt.Class[T]{Name: name, SuperClass: superClass, Traits: traits, Methods: []*stmt.Function[T]{}}
+	for !p.check(tokens.RightBrace) && !p.isAtEnd() {
+		if err := p.classMember(class); err != nil {
+			return nil, err
+		}
+	}
+	_, err = p.consume(tokens.RightBrace, "Expect '}' after class body.")
+	if err != nil {
+		return nil, err
+	}
+	return class, nil
+
+}
+
+// traitDeclaration parses a trait, the methods it declares are copied into the classes using it.
+// Eg: `trait Printable { print() { ... } }` used as `class A with Printable {}`.
+func (p *Parser[T]) traitDeclaration() (stmt.Stmt[T], error) {
+	name, err := p.consume(tokens.Identifier, "Expect trait name.")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.consume(tokens.LeftBrace, "Expect '{' before trait body."); err != nil {
+		return nil, err
+	}
 	methods := []*stmt.Function[T]{}
 	for !p.check(tokens.RightBrace) && !p.isAtEnd() {
 		f, err := p.function("method")
@@ -91,12 +174,70 @@ func (p *Parser[T]) classDeclaration() (stmt.Stmt[T], error) {
 		}
 		methods = append(methods, f)
 	}
-	_, err = p.consume(tokens.RightBrace, "Expect '}' after class body.")
-	if err != nil {
+	if _, err := p.consume(tokens.RightBrace, "Expect '}' after trait body."); err != nil {
 		return nil, err
 	}
-	return &stmt.Class[T]{Name: name, SuperClass: superClass, Methods: methods}, nil
+	return &stmt.Trait[T]{Name: name, Methods: methods}, nil
+}
 
+// classMember parses a method and adds it to the class. Besides regular methods, a class can have class methods,
+// Eg: `class create() {}`, getters, Eg: `area { ... }` or `area: num { ... }`, setters, Eg: `set name(value) { ... }`,
+// and typed fields, Eg: `x: num;`.
+func (p *Parser[T]) classMember(class *stmt.Class[T]) error {
+	switch {
+	case p.check(tokens.Identifier) && p.peekNext().TokenType == tokens.Colon:
+		name := p.advance()
+		p.advance() // :
+		memberType, err := p.typeAnnotation()
+		if err != nil {
+			return err
+		}
+		if p.match(tokens.Semicolon) {
+			class.Fields = append(class.Fields, &stmt.Field{Name: name, Type: memberType})
+			return nil
+		}
+		if _, err := p.consume(tokens.LeftBrace, "Expect ';' after field type or '{' before getter body."); err != nil {
+			return err
+		}
+		body, err := p.block()
+		if err != nil {
+			return err
+		}
+		getter := &stmt.Function[T]{Name: name, Params: []tokens.Token{}, ParamTypes: []*expr.TypeAnnotation{}, Defaults: []expr.Expr[T]{}, ReturnType: memberType, Body: body}
+		class.Getters = append(class.Getters, getter)
+	case p.match(tokens.Class):
+		f, err := p.function("method")
+		if err != nil {
+			return err
+		}
+		class.ClassMethods = append(class.ClassMethods, f)
+	case p.check(tokens.Identifier) && p.peekNext().TokenType == tokens.LeftBrace:
+		name := p.advance()
+		p.advance() // {
+		body, err := p.block()
+		if err != nil {
+			return err
+		}
+		getter := &stmt.Function[T]{Name: name, Params: []tokens.Token{}, ParamTypes: []*expr.TypeAnnotation{}, Defaults: []expr.Expr[T]{}, Body: body}
+		class.Getters = append(class.Getters, getter)
+	case p.check(tokens.Identifier) && p.peek().Lexeme == "set" && p.peekNext().TokenType == tokens.Identifier:
+		p.advance() // set
+		f, err := p.function("setter")
+		if err != nil {
+			return err
+		}
+		if len(f.Params) != 1 || f.Variadic || f.Defaults[0] != nil {
+			return parseError(f.Name, "Setters must have exactly one parameter.")
+		}
+		class.Setters = append(class.Setters, f)
+	default:
+		f, err := p.function("method")
+		if err != nil {
+			return err
+		}
+		class.Methods = append(class.Methods, f)
+	}
+	return nil
 }
 
 func (p *Parser[T]) varDeclaration() (stmt.Stmt[T], error) {
@@ -104,6 +245,12 @@ func (p *Parser[T]) varDeclaration() (stmt.Stmt[T], error) {
 	if err != nil {
 		return nil, err
 	}
+	var varType *expr.TypeAnnotation
+	if p.match(tokens.Colon) {
+		if varType, err = p.typeAnnotation(); err != nil {
+			return nil, err
+		}
+	}
 	var initializer expr.Expr[T]
 	if p.match(tokens.Equal) {
 		initializer, err = p.Expression()
@@ -114,7 +261,7 @@ func (p *Parser[T]) varDeclaration() (stmt.Stmt[T], error) {
 	if _, err := p.consume(tokens.Semicolon, "Expect ';' after variable declaration."); err != nil {
 		return nil, err
 	}
-	return &stmt.Var[T]{Name: name, Initializer: initializer}, nil
+	return &stmt.Var[T]{Name: name, Type: varType, Initializer: initializer}, nil
 }
 
 func (p *Parser[T]) statement() (stmt.Stmt[T], error) {
@@ -124,6 +271,18 @@ func (p *Parser[T]) statement() (stmt.Stmt[T], error) {
 	if p.match(tokens.Return) {
 		return p.returnStatement()
 	}
+	if p.match(tokens.Throw) {
+		return p.throwStatement()
+	}
+	if p.match(tokens.Break, tokens.Continue) {
+		return p.loopJumpStatement()
+	}
+	if p.match(tokens.Try) {
+		return p.tryStatement()
+	}
+	if p.match(tokens.Match) {
+		return p.matchStatement()
+	}
 	if p.match(tokens.Print) {
 		return p.printStatement()
 	}
@@ -143,52 +302,183 @@ func (p *Parser[T]) statement() (stmt.Stmt[T], error) {
 	return p.expressionStatement()
 }
 
-func (p *Parser[T]) function(functionType string) (f *stmt.Function[T], err error) {
+// parameters parses a parameter list, up to the closing parenthesis, into the provided function. Parameters can
+// have a type, Eg: `a: num`, and a default value, Eg: `b = 2`, and the last one can collect the remaining
+// arguments, Eg: `...rest`.
+func (p *Parser[T]) parameters(f *stmt.Function[T]) error {
+	f.Params, f.ParamTypes, f.Defaults = []tokens.Token{}, []*expr.TypeAnnotation{}, []expr.Expr[T]{}
+	if !p.check(tokens.RightParen) {
+		for {
+			if len(f.Params) >= 255 {
+				return parseError(p.peek(), "Can't have more than 255 parameters.")
+			}
+			if f.Variadic {
+				return parseError(p.peek(), "Rest parameter must be the last one.")
+			}
+			f.Variadic = p.match(tokens.Ellipsis)
+			param, err := p.consume(tokens.Identifier, "Expect parameter name.")
+			if err != nil {
+				return err
+			}
+			var paramType *expr.TypeAnnotation
+			if p.match(tokens.Colon) {
+				if paramType, err = p.typeAnnotation(); err != nil {
+					return err
+				}
+			}
+			var defaultValue expr.Expr[T]
+			if !f.Variadic && p.match(tokens.Equal) {
+				if defaultValue, err = p.Expression(); err != nil {
+					return err
+				}
+			} else if !f.Variadic && len(f.Defaults) > 0 && f.Defaults[len(f.Defaults)-1] != nil {
+				return parseError(param, "Expect default value after parameters with default values.")
+			}
+			f.Params = append(f.Params, param)
+			f.ParamTypes = append(f.ParamTypes, paramType)
+			f.Defaults = append(f.Defaults, defaultValue)
+			if !p.match(tokens.Comma) {
+				break
+			}
+		}
+	}
+	// )
+	if _, err := p.consume(tokens.RightParen, "Expect ')' after parameters."); err != nil {
+		return err
+	}
+	return nil
+}
+
+// returnType parses the optional type annotation of the function result, Eg: `: num`
+func (p *Parser[T]) returnType(f *stmt.Function[T]) (err error) {
+	if p.match(tokens.Colon) {
+		f.ReturnType, err = p.typeAnnotation()
+	}
+	return err
+}
+
+// typeAnnotation parses a type name, followed by '?' if it also accepts nil, Eg: `Point?`
+func (p *Parser[T]) typeAnnotation() (*expr.TypeAnnotation, error) {
+	if !p.match(tokens.Identifier, tokens.Nil, tokens.Fun) {
+		return nil, parseError(p.peek(), "Expect type name.")
+	}
+	return &expr.TypeAnnotation{Name: p.previous(), Optional: p.match(tokens.Question)}, nil
+}
+
+func (p *Parser[T]) function(functionType string) (*stmt.Function[T], error) {
 	// function name
 	name, err := p.consume(tokens.Identifier, fmt.Sprintf("Expect %s name.", functionType))
 	if err != nil {
-		return f, err
+		return nil, err
 	}
 	// (
 	_, err = p.consume(tokens.LeftParen, fmt.Sprintf("Expect '(' after %s name.", functionType))
 	if err != nil {
-		return f, err
+		return nil, err
 	}
+	f := &stmt.Function[T]{Name: name}
 	// parameters
-	parameters := []tokens.Token{}
-	if !p.check(tokens.RightParen) {
-		param, err := p.consume(tokens.Identifier, "Expect parameter name.")
-		if err != nil {
-			return f, err
-		}
-		parameters = append(parameters, param)
-		for p.match(tokens.Comma) {
-			if len(parameters) >= 255 {
-				return f, parseError(p.peek(), "Can't have more than 255 parameters.")
-			}
-			param, err := p.consume(tokens.Identifier, "Expect parameter name.")
-			if err != nil {
-				return f, err
-			}
-			parameters = append(parameters, param)
-		}
+	if err := p.parameters(f); err != nil {
+		return nil, err
 	}
-	// )
-	_, err = p.consume(tokens.RightParen, "Expect ')' after parameters.")
-	if err != nil {
-		return f, err
+	if err := p.returnType(f); err != nil {
+		return nil, err
 	}
 	// {
 	_, err = p.consume(tokens.LeftBrace, fmt.Sprintf("Expect '{' before %s body.", functionType))
 	if err != nil {
-		return f, err
+		return nil, err
 	}
 	// function body
+	if f.Body, err = p.block(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// lambda parses an anonymous function, Eg: `fun (a, b) { return a + b; }`
+func (p *Parser[T]) lambda() (expr.Expr[T], error) {
+	keyword := p.previous()
+	if _, err := p.consume(tokens.LeftParen, "Expect '(' after 'fun'."); err != nil {
+		return nil, err
+	}
+	function := &stmt.Function[T]{Name: anonymousName(keyword)}
+	if err := p.parameters(function); err != nil {
+		return nil, err
+	}
+	if err := p.returnType(function); err != nil {
+		return nil, err
+	}
+	if _, err := p.consume(tokens.LeftBrace, "Expect '{' before function body."); err != nil {
+		return nil, err
+	}
 	body, err := p.block()
 	if err != nil {
-		return f, err
+		return nil, err
+	}
+	function.Body = body
+	return &expr.Lambda[T]{Keyword: keyword, Function: function}, nil
+}
+
+// arrowFunction parses the short form of anonymous functions, returning the value of an expression,
+// Eg: `(a, b) => a + b` or `x => x * 2`
+func (p *Parser[T]) arrowFunction() (expr.Expr[T], error) {
+	start := p.peek()
+	function := &stmt.Function[T]{Name: anonymousName(start)}
+	if p.match(tokens.Identifier) {
+		function.Params, function.ParamTypes, function.Defaults = []tokens.Token{p.previous()}, []*expr.TypeAnnotation{nil}, []expr.Expr[T]{nil}
+	} else {
+		p.advance() // (
+		if err := p.parameters(function); err != nil {
+			return nil, err
+		}
+	}
+	arrow, err := p.consume(tokens.Arrow, "Expect '=>' after parameters.")
+	if err != nil {
+		return nil, err
+	}
+	value, err := p.Expression()
+	if err != nil {
+		return nil, err
+	}
+	function.Body = []stmt.Stmt[T]{&stmt.Return[T]{Keyword: arrow, Value: value}}
+	return &expr.Lambda[T]{Keyword: arrow, Function: function}, nil
+}
+
+// isArrowFunction tells if the parenthesis at the current position starts the parameters of an arrow function
+func (p *Parser[T]) isArrowFunction() bool {
+	// braces are tracked too, default values can hold maps and functions, Eg: `(a = {}, b = fun () { ... }) => a`
+	depth, braces := 0, 0
+	for i := p.current; i < len(p.tokens); i++ {
+		switch p.tokens[i].TokenType {
+		case tokens.LeftParen:
+			depth++
+		case tokens.RightParen:
+			depth--
+			if depth == 0 {
+				return i+1 < len(p.tokens) && p.tokens[i+1].TokenType == tokens.Arrow
+			}
+		case tokens.LeftBrace:
+			braces++
+		case tokens.RightBrace:
+			braces--
+			if braces < 0 {
+				return false
+			}
+		case tokens.Semicolon:
+			if braces == 0 {
+				return false
+			}
+		case tokens.Eof:
+			return false
+		}
 	}
-	return &stmt.Function[T]{Name: name, Params: parameters, Body: body}, nil
+	return false
+}
+
+// anonymousName returns the name of anonymous functions: an empty identifier located at the provided token
+func anonymousName(at tokens.Token) tokens.Token {
+	return tokens.Token{TokenType: tokens.Identifier, Line: at.Line, Column: at.Column, Offset: at.Offset, File: at.File}
 }
 
 func (p *Parser[T]) returnStatement() (stmt.Stmt[T], error) {
@@ -208,7 +498,194 @@ func (p *Parser[T]) returnStatement() (stmt.Stmt[T], error) {
 	return &stmt.Return[T]{Keyword: keyword, Value: value}, nil
 }
 
+func (p *Parser[T]) throwStatement() (stmt.Stmt[T], error) {
+	keyword := p.previous()
+	value, err := p.Expression()
+	if err != nil {
+		return nil, err
+	}
+	_, err = p.consume(tokens.Semicolon, "Expect ';' after thrown value.")
+	if err != nil {
+		return nil, err
+	}
+	return &stmt.Throw[T]{Keyword: keyword, Value: value}, nil
+}
+
+// loopJumpStatement parses `break;` and `continue;`
+func (p *Parser[T]) loopJumpStatement() (stmt.Stmt[T], error) {
+	keyword := p.previous()
+	if _, err := p.consume(tokens.Semicolon, fmt.Sprintf("Expect ';' after '%s'.", keyword.Lexeme)); err != nil {
+		return nil, err
+	}
+	if keyword.TokenType == tokens.Break {
+		return &stmt.Break[T]{Keyword: keyword}, nil
+	}
+	return &stmt.Continue[T]{Keyword: keyword}, nil
+}
+
+func (p *Parser[T]) tryStatement() (stmt.Stmt[T], error) {
+	// try { ... } catch (e) { ... } finally { ... }
+	keyword := p.previous()
+	if _, err := p.consume(tokens.LeftBrace, "Expect '{' after 'try'."); err != nil {
+		return nil, err
+	}
+	body, err := p.block()
+	if err != nil {
+		return nil, err
+	}
+	try := &stmt.Try[T]{Keyword: keyword, Body: body}
+
+	if p.match(tokens.Catch) {
+		if _, err := p.consume(tokens.LeftParen, "Expect '(' after 'catch'."); err != nil {
+			return nil, err
+		}
+		name, err := p.consume(tokens.Identifier, "Expect error variable name.")
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.consume(tokens.RightParen, "Expect ')' after error variable name."); err != nil {
+			return nil, err
+		}
+		if _, err := p.consume(tokens.LeftBrace, "Expect '{' before catch body."); err != nil {
+			return nil, err
+		}
+		catchBody, err := p.block()
+		if err != nil {
+			return nil, err
+		}
+		try.CatchName = &name
+		try.CatchBody = catchBody
+	}
+
+	if p.match(tokens.Finally) {
+		if _, err := p.consume(tokens.LeftBrace, "Expect '{' after 'finally'."); err != nil {
+			return nil, err
+		}
+		finallyBody, err := p.block()
+		if err != nil {
+			return nil, err
+		}
+		try.FinallyBody = finallyBody
+	}
+
+	if try.CatchName == nil && try.FinallyBody == nil {
+		return nil, parseError(p.peek(), "Expect 'catch' or 'finally' after try block.")
+	}
+	return try, nil
+}
+
+func (p *Parser[T]) matchStatement() (stmt.Stmt[T], error) {
+	// match (value) { case 1, 2 => ...; case Point p if (p.x > 0) => ...; default => ... }
+	keyword := p.previous()
+	if _, err := p.consume(tokens.LeftParen, "Expect '(' after 'match'."); err != nil {
+		return nil, err
+	}
+	value, err := p.Expression()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.consume(tokens.RightParen, "Expect ')' after match value."); err != nil {
+		return nil, err
+	}
+	if _, err := p.consume(tokens.LeftBrace, "Expect '{' before match cases."); err != nil {
+		return nil, err
+	}
+	cases := []*stmt.MatchCase[T]{}
+	for !p.check(tokens.RightBrace) && !p.isAtEnd() {
+		matchCase, err := p.matchCase()
+		if err != nil {
+			return nil, err
+		}
+		cases = append(cases, matchCase)
+	}
+	if _, err := p.consume(tokens.RightBrace, "Expect '}' after match cases."); err != nil {
+		return nil, err
+	}
+	return &stmt.Match[T]{Keyword: keyword, Value: value, Cases: cases}, nil
+}
+
+func (p *Parser[T]) matchCase() (*stmt.MatchCase[T], error) {
+	matchCase := &stmt.MatchCase[T]{}
+	switch {
+	case p.match(tokens.Default):
+		matchCase.Keyword = p.previous()
+	case p.match(tokens.Case):
+		matchCase.Keyword = p.previous()
+		for {
+			pattern, err := p.pattern()
+			if err != nil {
+				return nil, err
+			}
+			matchCase.Patterns = append(matchCase.Patterns, pattern)
+			if _, isLiteral := pattern.(*expr.Literal[T]); !isLiteral && p.match(tokens.Identifier) {
+				binding := p.previous()
+				matchCase.Binding = &binding
+			}
+			if !p.check(tokens.Comma) {
+				break
+			}
+			if matchCase.Binding != nil {
+				return nil, parseError(p.peek(), "Can't bind a variable in a case with several patterns.")
+			}
+			p.advance()
+		}
+		if p.match(tokens.If) {
+			if _, err := p.consume(tokens.LeftParen, "Expect '(' after 'if'."); err != nil {
+				return nil, err
+			}
+			guard, err := p.Expression()
+			if err != nil {
+				return nil, err
+			}
+			if _, err := p.consume(tokens.RightParen, "Expect ')' after case guard."); err != nil {
+				return nil, err
+			}
+			matchCase.Guard = guard
+		}
+	default:
+		return nil, parseError(p.peek(), "Expect 'case' or 'default'.")
+	}
+	if _, err := p.consume(tokens.Arrow, "Expect '=>' before case body."); err != nil {
+		return nil, err
+	}
+	body, err := p.statement()
+	if err != nil {
+		return nil, err
+	}
+	matchCase.Body = body
+	return matchCase, nil
+}
+
+// pattern parses the pattern of a match case: a literal or a class name, Eg: `-1`, `"x"` or `shapes.Point`
+func (p *Parser[T]) pattern() (expr.Expr[T], error) {
+	switch {
+	case p.match(tokens.Number, tokens.String):
+		return &expr.Literal[T]{Value: p.previous().Literal}, nil
+	case p.check(tokens.Minus) && p.peekNext().TokenType == tokens.Number:
+		p.advance()
+		return &expr.Literal[T]{Value: -p.advance().Literal.(float64)}, nil
+	case p.match(tokens.True):
+		return &expr.Literal[T]{Value: true}, nil
+	case p.match(tokens.False):
+		return &expr.Literal[T]{Value: false}, nil
+	case p.match(tokens.Nil):
+		return &expr.Literal[T]{Value: tokens.NilLiteral}, nil
+	case p.match(tokens.Identifier):
+		var pattern expr.Expr[T] = &expr.Variable[T]{Name: p.previous()}
+		for p.match(tokens.Dot) {
+			name, err := p.consume(tokens.Identifier, "Expect property name after '.'.")
+			if err != nil {
+				return nil, err
+			}
+			pattern = &expr.Get[T]{Object: pattern, Name: name}
+		}
+		return pattern, nil
+	}
+	return nil, parseError(p.peek(), "Expect a literal or a class name as pattern.")
+}
+
 func (p *Parser[T]) ifStatement() (stmt.Stmt[T], error) {
+	keyword := p.previous()
 	_, err := p.consume(tokens.LeftParen, "Expect '(' after 'if'.")
 	if err != nil {
 		return nil, err
@@ -233,10 +710,11 @@ func (p *Parser[T]) ifStatement() (stmt.Stmt[T], error) {
 			return nil, err
 		}
 	}
-	return &stmt.If[T]{Condition: condition, ThenBranch: thenBranch, ElseBranch: elseBranch}, nil
+	return &stmt.If[T]{Keyword: keyword, Condition: condition, ThenBranch: thenBranch, ElseBranch: elseBranch}, nil
 }
 
 func (p *Parser[T]) whileStatemet() (stmt.Stmt[T], error) {
+	keyword := p.previous()
 	_, err := p.consume(tokens.LeftParen, "Expect '(' after 'while'.")
 	if err != nil {
 		return nil, err
@@ -253,12 +731,13 @@ func (p *Parser[T]) whileStatemet() (stmt.Stmt[T], error) {
 	if err != nil {
 		return nil, err
 	}
-	return &stmt.While[T]{Condition: condition, Body: body}, nil
+	return &stmt.While[T]{Keyword: keyword, Condition: condition, Body: body}, nil
 }
 
 // forStatement syntactic sugar to support for syntax
 func (p *Parser[T]) forStatement() (stmt.Stmt[T], error) {
 	// for(var i = 0; i < 10; i++)
+	keyword := p.previous()
 	_, err := p.consume(tokens.LeftParen, "Expect '(' after 'for'.")
 	if err != nil {
 		return nil, err
@@ -309,16 +788,12 @@ func (p *Parser[T]) forStatement() (stmt.Stmt[T], error) {
 		return nil, err
 	}
 
-	// add the initializer statement if any
-	if increment != nil {
-		statements := []stmt.Stmt[T]{body, &stmt.Expression[T]{Expression: increment}}
-		body = &stmt.Block[T]{Statements: statements}
-	}
 	// if no condition set true
 	if condition == nil {
 		condition = &expr.Literal[T]{Value: true}
 	}
-	body = &stmt.While[T]{Condition: condition, Body: body}
+	// the increment is kept apart from the body, so it also runs after `continue`
+	body = &stmt.While[T]{Keyword: keyword, Condition: condition, Body: body, Increment: increment}
 
 	// add the increment statement if any (before the while)
 	if initializer != nil {
@@ -330,6 +805,7 @@ func (p *Parser[T]) forStatement() (stmt.Stmt[T], error) {
 }
 
 func (p *Parser[T]) printStatement() (stmt.Stmt[T], error) {
+	keyword := p.previous()
 	value, err := p.Expression()
 	if err != nil {
 		return nil, err
@@ -337,7 +813,7 @@ func (p *Parser[T]) printStatement() (stmt.Stmt[T], error) {
 	if _, err := p.consume(tokens.Semicolon, "Expect ';' after value."); err != nil {
 		return nil, err
 	}
-	return &stmt.Print[T]{Expression: value}, nil
+	return &stmt.Print[T]{Keyword: keyword, Expression: value}, nil
 }
 
 func (p *Parser[T]) expressionStatement() (stmt.Stmt[T], error) {
@@ -356,7 +832,7 @@ func (p *Parser[T]) Expression() (expr.Expr[T], error) {
 }
 
 func (p *Parser[T]) assignment() (expr.Expr[T], error) {
-	expression, err := p.or()
+	expression, err := p.conditional()
 	if err != nil {
 		return nil, err
 	}
@@ -372,17 +848,41 @@ func (p *Parser[T]) assignment() (expr.Expr[T], error) {
 			return &expr.Assign[T]{Name: name, Value: value}, nil
 		} else if getExpr, isGet := expression.(*expr.Get[T]); isGet {
 			return &expr.Set[T]{Name: getExpr.Name, Object: getExpr.Object, Value: value}, nil
+		} else if indexExpr, isIndex := expression.(*expr.Index[T]); isIndex {
+			return &expr.IndexSet[T]{Object: indexExpr.Object, Bracket: indexExpr.Bracket, Index: indexExpr.Index, Value: value}, nil
 		}
 		return nil, parseError(equals, "Invalid assignment target.")
 	}
+	if p.match(tokens.PlusEqual, tokens.MinusEqual, tokens.StarEqual, tokens.SlashEqual, tokens.PercentEqual) {
+		operator := p.previous()
+		value, err := p.assignment()
+		if err != nil {
+			return nil, err
+		}
+		return p.compoundAssignment(expression, operator, value, false)
+	}
 	return expression, nil
 }
 
+// compoundAssignment builds the assignment applying the operator to the target, Eg: `a.b += 1`, `a[i] += 1` or `a++`
+func (p *Parser[T]) compoundAssignment(target expr.Expr[T], operator tokens.Token, value expr.Expr[T], postfix bool) (expr.Expr[T], error) {
+	switch target := target.(type) {
+	case *expr.Variable[T]:
+		return &expr.CompoundAssign[T]{Name: target.Name, Operator: operator, Value: value, Postfix: postfix}, nil
+	case *expr.Get[T]:
+		return &expr.CompoundSet[T]{Object: target.Object, Name: target.Name, Operator: operator, Value: value, Postfix: postfix}, nil
+	case *expr.Index[T]:
+		return &expr.CompoundIndexSet[T]{Object: target.Object, Bracket: target.Bracket, Index: target.Index, Operator: operator, Value: value, Postfix: postfix}, nil
+	}
+	return nil, parseError(operator, "Invalid assignment target.")
+}
+
 func (p *Parser[T]) block() ([]stmt.Stmt[T], error) {
 	statements := []stmt.Stmt[T]{}
 	for (!p.check(tokens.RightBrace)) && !p.isAtEnd() {
-		statement := p.declaration()
-		statements = append(statements, statement)
+		if statement := p.declaration(); statement != nil {
+			statements = append(statements, statement)
+		}
 	}
 	_, err := p.consume(tokens.RightBrace, "Expect '}' after block.")
 	if err != nil {
@@ -444,9 +944,9 @@ func (p *Parser[T]) factor() (expr.Expr[T], error) {
 	if err != nil {
 		return nil, err
 	}
-	for p.match(tokens.Slash, tokens.Star) {
+	for p.match(tokens.Slash, tokens.Star, tokens.Percent, tokens.TildeSlash) {
 		operator := p.previous()
-		right, err := p.factor()
+		right, err := p.unary()
 		if err != nil {
 			return nil, err
 		}
@@ -464,7 +964,22 @@ func (p *Parser[T]) unary() (expr.Expr[T], error) {
 		}
 		return &expr.Unary[T]{Operator: operator, Right: right}, nil
 	}
-	return p.call()
+	if p.match(tokens.PlusPlus, tokens.MinusMinus) {
+		operator := p.previous()
+		target, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return p.compoundAssignment(target, operator, &expr.Literal[T]{Value: 1.0}, false)
+	}
+	expression, err := p.call()
+	if err != nil {
+		return nil, err
+	}
+	if p.match(tokens.PlusPlus, tokens.MinusMinus) {
+		return p.compoundAssignment(expression, p.previous(), &expr.Literal[T]{Value: 1.0}, true)
+	}
+	return expression, nil
 }
 
 func (p *Parser[T]) call() (expr.Expr[T], error) {
@@ -473,6 +988,7 @@ func (p *Parser[T]) call() (expr.Expr[T], error) {
 		return nil, err
 	}
 
+	optional := false
 	for {
 		if p.match(tokens.LeftParen) {
 			expression, err = p.finishCall(expression)
@@ -486,13 +1002,60 @@ func (p *Parser[T]) call() (expr.Expr[T], error) {
 				return nil, err
 			}
 			expression = &expr.Get[T]{Name: name, Object: expression}
+		} else if p.match(tokens.QuestionDot) {
+			name, err := p.consume(tokens.Identifier, "Expect property name after '?.'.")
+			if err != nil {
+				return nil, err
+			}
+			expression = &expr.OptionalGet[T]{Name: name, Object: expression}
+			optional = true
+		} else if p.match(tokens.LeftBracket) {
+			expression, err = p.finishIndex(expression)
+			if err != nil {
+				return nil, err
+			}
 		} else {
 			break
 		}
 	}
+	if optional {
+		// the whole chain is skipped when an optional access finds nil
+		return &expr.OptionalChain[T]{Expression: expression}, nil
+	}
 	return expression, nil
 }
 
+// finishIndex parses an index, Eg: `list[0]`, or a slice where both bounds are optional, Eg: `list[1:]`
+func (p *Parser[T]) finishIndex(object expr.Expr[T]) (expr.Expr[T], error) {
+	bracket := p.previous()
+	var start expr.Expr[T]
+	if !p.check(tokens.Colon) {
+		index, err := p.Expression()
+		if err != nil {
+			return nil, err
+		}
+		if !p.check(tokens.Colon) {
+			if _, err := p.consume(tokens.RightBracket, "Expect ']' after index."); err != nil {
+				return nil, err
+			}
+			return &expr.Index[T]{Object: object, Bracket: bracket, Index: index}, nil
+		}
+		start = index
+	}
+	p.advance() // colon
+	var end expr.Expr[T]
+	if !p.check(tokens.RightBracket) {
+		var err error
+		if end, err = p.Expression(); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := p.consume(tokens.RightBracket, "Expect ']' after slice."); err != nil {
+		return nil, err
+	}
+	return &expr.Slice[T]{Object: object, Bracket: bracket, Start: start, End: end}, nil
+}
+
 func (p *Parser[T]) finishCall(callee expr.Expr[T]) (expr.Expr[T], error) {
 	arguments := []expr.Expr[T]{}
 	if !p.check(tokens.RightParen) {
@@ -525,6 +1088,8 @@ func (p *Parser[T]) primary() (expr.Expr[T], error) {
 		return &expr.Literal[T]{Value: false}, nil
 	case p.match(tokens.True):
 		return &expr.Literal[T]{Value: true}, nil
+	case p.check(tokens.Identifier) && p.peekNext().TokenType == tokens.Arrow, p.check(tokens.LeftParen) && p.isArrowFunction():
+		return p.arrowFunction()
 	case p.match(tokens.Identifier):
 		name := p.previous()
 		return &expr.Variable[T]{Name: name}, nil
@@ -532,6 +1097,8 @@ func (p *Parser[T]) primary() (expr.Expr[T], error) {
 		return &expr.Literal[T]{Value: tokens.NilLiteral}, nil
 	case p.match(tokens.Number, tokens.String):
 		return &expr.Literal[T]{Value: p.previous().Literal}, nil
+	case p.match(tokens.Interpolation):
+		return p.interpolation()
 	case p.match(tokens.This):
 		return &expr.This[T]{Keyword: p.previous()}, nil
 	case p.match(tokens.Super):
@@ -544,6 +1111,12 @@ func (p *Parser[T]) primary() (expr.Expr[T], error) {
 			return nil, err
 		}
 		return &expr.Super[T]{Keyword: previous, Method: method}, nil
+	case p.match(tokens.Fun):
+		return p.lambda()
+	case p.match(tokens.LeftBracket):
+		return p.list()
+	case p.match(tokens.LeftBrace):
+		return p.mapLiteral()
 	case p.match(tokens.LeftParen):
 		expression, err := p.Expression()
 		if err != nil {
@@ -559,6 +1132,135 @@ func (p *Parser[T]) primary() (expr.Expr[T], error) {
 	return nil, parseError(p.peek(), "Expect expression.")
 }
 
+// interpolation parses a string with embedded expressions, Eg: "Hello ${name}!". The scanner emits an Interpolation
+// token with the text before each expression and a String token with the text after the last one.
+func (p *Parser[T]) interpolation() (expr.Expr[T], error) {
+	start := p.previous()
+	parts := []expr.Expr[T]{}
+	addText := func(text string) {
+		if text != "" {
+			parts = append(parts, &expr.Literal[T]{Value: text})
+		}
+	}
+	addText(start.Literal.(string))
+	for {
+		// the string resumed after an interpolation starts at its closing '}', so it follows empty ones, Eg: `"${}"`
+		if p.check(tokens.String) || p.check(tokens.Interpolation) {
+			if strings.HasPrefix(p.peek().Lexeme, "}") {
+				return nil, parseError(p.peek(), "Expect expression.")
+			}
+		}
+		value, err := p.Expression()
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, value)
+		if len(parts) >= ARGUMENTS_LIMIT {
+			return nil, parseError(p.peek(), fmt.Sprintf("Can't have more than %d parts in a string interpolation.", ARGUMENTS_LIMIT))
+		}
+		if !p.match(tokens.Interpolation) {
+			break
+		}
+		addText(p.previous().Literal.(string))
+	}
+	end, err := p.consume(tokens.String, "Expect '}' after interpolated expression.")
+	if err != nil {
+		return nil, err
+	}
+	addText(end.Literal.(string))
+	return &expr.Interpolation[T]{Start: start, Parts: parts}, nil
+}
+
+// list parses the elements of a list literal, a trailing comma is allowed
+func (p *Parser[T]) list() (expr.Expr[T], error) {
+	bracket := p.previous()
+	elements := []expr.Expr[T]{}
+	for !p.check(tokens.RightBracket) {
+		element, err := p.Expression()
+		if err != nil {
+			return nil, err
+		}
+		elements = append(elements, element)
+		if !p.match(tokens.Comma) {
+			break
+		}
+	}
+	if _, err := p.consume(tokens.RightBracket, "Expect ']' after list elements."); err != nil {
+		return nil, err
+	}
+	return &expr.List[T]{Bracket: bracket, Elements: elements}, nil
+}
+
+// mapLiteral parses the entries of a map literal, Eg: `{"a": 1, "b": 2}`, a trailing comma is allowed
+func (p *Parser[T]) mapLiteral() (expr.Expr[T], error) {
+	brace := p.previous()
+	keys := []expr.Expr[T]{}
+	values := []expr.Expr[T]{}
+	for !p.check(tokens.RightBrace) {
+		key, err := p.Expression()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.consume(tokens.Colon, "Expect ':' after map key."); err != nil {
+			return nil, err
+		}
+		value, err := p.Expression()
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+		values = append(values, value)
+		if !p.match(tokens.Comma) {
+			break
+		}
+	}
+	if _, err := p.consume(tokens.RightBrace, "Expect '}' after map entries."); err != nil {
+		return nil, err
+	}
+	return &expr.Map[T]{Brace: brace, Keys: keys, Values: values}, nil
+}
+
+// conditional parses `condition ? then : else`, it is right associative: `a ? b : c ? d : e` is `a ? b : (c ? d : e)`
+func (p *Parser[T]) conditional() (expr.Expr[T], error) {
+	condition, err := p.coalesce()
+	if err != nil {
+		return nil, err
+	}
+	if !p.match(tokens.Question) {
+		return condition, nil
+	}
+	question := p.previous()
+	thenBranch, err := p.assignment()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.consume(tokens.Colon, "Expect ':' after then branch of conditional expression."); err != nil {
+		return nil, err
+	}
+	elseBranch, err := p.assignment()
+	if err != nil {
+		return nil, err
+	}
+	return &expr.Conditional[T]{Condition: condition, Question: question, Then: thenBranch, Else: elseBranch}, nil
+}
+
+// coalesce parses `a ?? b`, which evaluates to b only if a is nil
+func (p *Parser[T]) coalesce() (expr.Expr[T], error) {
+	expression, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	for p.match(tokens.QuestionQuestion) {
+		operator := p.previous()
+		right, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		expression = &expr.Logical[T]{Left: expression, Operator: operator, Right: right}
+	}
+	return expression, nil
+}
+
 func (p *Parser[T]) or() (expr.Expr[T], error) {
 	expression, err := p.and()
 	if err != nil {
@@ -612,6 +1314,12 @@ func (p *Parser[T]) check(tokenType tokens.TokenType) bool {
 	return p.peek().TokenType == tokenType
 }
 
+// checkContextual tells if the next token is the provided contextual keyword, an identifier that is only a keyword
+// in some positions, Eg: `as` in imports
+func (p *Parser[T]) checkContextual(lexeme string) bool {
+	return p.check(tokens.Identifier) && p.peek().Lexeme == lexeme
+}
+
 func (p *Parser[T]) advance() tokens.Token {
 	if !p.isAtEnd() {
 		p.current++
@@ -627,6 +1335,14 @@ func (p *Parser[T]) peek() tokens.Token {
 	return p.tokens[p.current]
 }
 
+// peekNext returns the token after the current one
+func (p *Parser[T]) peekNext() tokens.Token {
+	if p.isAtEnd() {
+		return p.peek()
+	}
+	return p.tokens[p.current+1]
+}
+
 func (p *Parser[T]) previous() tokens.Token {
 	return p.tokens[p.current-1]
 }
@@ -646,7 +1362,8 @@ func (p *Parser[T]) synchronize() {
 			return
 		}
 		switch p.peek().TokenType {
-		case tokens.Class, tokens.Fun, tokens.Var, tokens.For, tokens.If, tokens.While, tokens.Print, tokens.Return:
+		case tokens.Class, tokens.Trait, tokens.Fun, tokens.Var, tokens.For, tokens.If, tokens.While, tokens.Print, tokens.Return,
+			tokens.Throw, tokens.Try, tokens.Import, tokens.Break, tokens.Continue, tokens.Match:
 			return
 		}
 		p.advance()
@@ -654,6 +1371,15 @@ func (p *Parser[T]) synchronize() {
 }
 
 func parseError(token tokens.Token, message string) error {
-	gloxErrors.AtToken(token, message)
-	return ErrParse
+	return &ParseError{Token: token, Message: message, Span: token.Span()}
+}
+
+// recordError keeps track of the provided error and reports it
+func (p *Parser[T]) recordError(err error) {
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) {
+		parseErr = &ParseError{Token: p.peek(), Message: err.Error(), Span: p.peek().Span()}
+	}
+	p.errors = append(p.errors, *parseErr)
+	p.reporter.AtToken(gloxErrors.CodeParse, parseErr.Token, parseErr.Message)
 }
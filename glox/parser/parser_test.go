@@ -0,0 +1,300 @@
+package parser
+
+import (
+	"glox/errors"
+	"glox/expr"
+	"glox/scanner"
+	"glox/stmt"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func parse(t *testing.T, source string) ([]stmt.Stmt[any], []ParseError, *errors.Reporter) {
+	reporter := errors.NewReporter(io.Discard)
+	s := scanner.NewScanner(source, reporter)
+	s.ScanTokens()
+	require.False(t, reporter.ErrorFound())
+	parser := NewParser[any](s.Tokens(), reporter)
+	statements, parseErrors := parser.Parse()
+	return statements, parseErrors, reporter
+}
+
+func TestParse(t *testing.T) {
+	statements, parseErrors, _ := parse(t, "var a = 1; { print a; } class A < B { m() { return 1; } }")
+	require.Empty(t, parseErrors)
+	require.Len(t, statements, 3)
+}
+
+func TestParseCollectsErrors(t *testing.T) {
+	source := `var a = 1
+print a;
+fun f( { }
+class { }
+print (1 + 2;
+{ var b = ; print b; }
+print "ok";
+`
+	statements, parseErrors, reporter := parse(t, source)
+
+	messages := []string{}
+	lines := []int{}
+	for _, e := range parseErrors {
+		messages = append(messages, e.Message)
+		lines = append(lines, e.Token.Line)
+		require.ErrorIs(t, &e, ErrParse)
+		require.Equal(t, e.Token.Span(), e.Span)
+	}
+	require.Equal(t, []string{
+		"Expect ';' after variable declaration.",
+		"Expect parameter name.",
+		"Expect class name.",
+		"Expect ')' after expression",
+		"Expect expression.",
+	}, messages)
+	require.Equal(t, []int{2, 3, 4, 5, 6}, lines)
+	require.Len(t, reporter.Diagnostics(), 5)
+
+	// only valid statements are returned, even inside blocks
+	require.Len(t, statements, 2)
+	block, isBlock := statements[0].(*stmt.Block[any])
+	require.True(t, isBlock)
+	require.Len(t, block.Statements, 1)
+	require.IsType(t, &stmt.Print[any]{}, statements[1])
+}
+
+func TestParseLists(t *testing.T) {
+	statements, parseErrors, _ := parse(t, "[1, [2],]; a[0]; a[1:]; a[:2]; a[:]; a[0] = 1;")
+	require.Empty(t, parseErrors)
+	expressions := []any{}
+	for _, s := range statements {
+		expressions = append(expressions, s.(*stmt.Expression[any]).Expression)
+	}
+
+	list := expressions[0].(*expr.List[any])
+	require.Len(t, list.Elements, 2)
+	require.IsType(t, &expr.List[any]{}, list.Elements[1])
+	require.IsType(t, &expr.Index[any]{}, expressions[1])
+
+	slice := expressions[2].(*expr.Slice[any])
+	require.NotNil(t, slice.Start)
+	require.Nil(t, slice.End)
+	slice = expressions[3].(*expr.Slice[any])
+	require.Nil(t, slice.Start)
+	require.NotNil(t, slice.End)
+	slice = expressions[4].(*expr.Slice[any])
+	require.Nil(t, slice.Start)
+	require.Nil(t, slice.End)
+
+	require.IsType(t, &expr.IndexSet[any]{}, expressions[5])
+
+	_, parseErrors, _ = parse(t, "a[0;")
+	require.Len(t, parseErrors, 1)
+	require.Equal(t, "Expect ']' after index.", parseErrors[0].Message)
+}
+
+func TestParseMaps(t *testing.T) {
+	statements, parseErrors, _ := parse(t, `var m = {"a": 1, 2: {},}; {}`)
+	require.Empty(t, parseErrors)
+	require.Len(t, statements, 2)
+	m := statements[0].(*stmt.Var[any]).Initializer.(*expr.Map[any])
+	require.Len(t, m.Keys, 2)
+	require.Len(t, m.Values, 2)
+	require.IsType(t, &expr.Map[any]{}, m.Values[1])
+	// braces starting a statement are still blocks
+	require.IsType(t, &stmt.Block[any]{}, statements[1])
+
+	_, parseErrors, _ = parse(t, `var m = {"a" 1};`)
+	require.Len(t, parseErrors, 1)
+	require.Equal(t, "Expect ':' after map key.", parseErrors[0].Message)
+}
+
+func TestParseImport(t *testing.T) {
+	statements, parseErrors, _ := parse(t, `import "lib/module.glox" as module;`)
+	require.Empty(t, parseErrors)
+	require.Len(t, statements, 1)
+	i := statements[0].(*stmt.Import[any])
+	require.Equal(t, "lib/module.glox", i.Path.Literal)
+	require.Equal(t, "module", i.Name.Lexeme)
+
+	_, parseErrors, _ = parse(t, `import "module.glox";`)
+	require.Len(t, parseErrors, 1)
+	require.Equal(t, "Expect 'as' after module path.", parseErrors[0].Message)
+
+	// 'as' is only a keyword in imports
+	statements, parseErrors, _ = parse(t, `import "module.glox" as as; var as = as;`)
+	require.Empty(t, parseErrors)
+	require.Len(t, statements, 2)
+	require.Equal(t, "as", statements[0].(*stmt.Import[any]).Name.Lexeme)
+}
+
+func TestParseParameters(t *testing.T) {
+	statements, parseErrors, _ := parse(t, `fun f(a, b = 1, ...rest) {}`)
+	require.Empty(t, parseErrors)
+	f := statements[0].(*stmt.Function[any])
+	require.Len(t, f.Params, 3)
+	require.Nil(t, f.Defaults[0])
+	require.NotNil(t, f.Defaults[1])
+	require.True(t, f.Variadic)
+
+	errorCases := map[string]string{
+		`fun f(...rest, a) {}`: "Rest parameter must be the last one.",
+		`fun f(a = 1, b) {}`:   "Expect default value after parameters with default values.",
+		`fun f(...) {}`:        "Expect parameter name.",
+	}
+	for source, expected := range errorCases {
+		_, parseErrors, _ := parse(t, source)
+		require.Len(t, parseErrors, 1, source)
+		require.Equal(t, expected, parseErrors[0].Message, source)
+	}
+}
+
+func TestParseLambdas(t *testing.T) {
+	sources := []string{
+		`var f = fun (a, b = 1) { return a + b; };`,
+		`var f = (a, ...rest) => a;`,
+		`var f = x => x;`,
+		`fun (x) {}(1);`,
+		`var f = (a = {}) => a;`,
+		`var f = (a = {"k": 1}, b = fun () { return 1; }) => a;`,
+		`print (fun () { return 1; })();`,
+	}
+	for _, source := range sources {
+		statements, parseErrors, _ := parse(t, source)
+		require.Empty(t, parseErrors, source)
+		require.Len(t, statements, 1, source)
+	}
+
+	errorCases := map[string]string{
+		`var f = fun a() {};`: "Expect '(' after 'fun'.",
+		`var f = fun () 1;`:   "Expect '{' before function body.",
+		`var f = (a) => ;`:    "Expect expression.",
+	}
+	for source, expected := range errorCases {
+		_, parseErrors, _ := parse(t, source)
+		require.NotEmpty(t, parseErrors, source)
+		require.Equal(t, expected, parseErrors[0].Message, source)
+	}
+}
+
+func TestParseClassMembers(t *testing.T) {
+	statements, parseErrors, _ := parse(t, `class A { init() {} class create() {} name { return 1; } set name(value) {} set(a) {} }`)
+	require.Empty(t, parseErrors)
+	class := statements[0].(*stmt.Class[any])
+	require.Len(t, class.Methods, 2)
+	require.Len(t, class.ClassMethods, 1)
+	require.Len(t, class.Getters, 1)
+	require.Len(t, class.Setters, 1)
+
+	_, parseErrors, _ = parse(t, `class A { set name(a, b) {} }`)
+	require.Len(t, parseErrors, 1)
+	require.Equal(t, "Setters must have exactly one parameter.", parseErrors[0].Message)
+}
+
+func TestParseConditionals(t *testing.T) {
+	expression := func(source string) expr.Expr[any] {
+		statements, parseErrors, _ := parse(t, source)
+		require.Empty(t, parseErrors, source)
+		return statements[0].(*stmt.Expression[any]).Expression
+	}
+
+	// right associative, and lower precedence than `??` and `or`
+	conditional := expression(`a or b ? c : d ?? e ? f : g;`).(*expr.Conditional[any])
+	require.IsType(t, &expr.Logical[any]{}, conditional.Condition)
+	nested := conditional.Else.(*expr.Conditional[any])
+	coalesce := nested.Condition.(*expr.Logical[any])
+	require.Equal(t, "??", coalesce.Operator.Lexeme)
+
+	// the optional chain covers the calls and accesses after `?.`
+	chain := expression(`a.b?.c.d();`).(*expr.OptionalChain[any])
+	call := chain.Expression.(*expr.Call[any])
+	get := call.Callee.(*expr.Get[any])
+	optional := get.Object.(*expr.OptionalGet[any])
+	require.IsType(t, &expr.Get[any]{}, optional.Object)
+
+	errorCases := map[string]string{
+		`a ? b;`:     "Expect ':' after then branch of conditional expression.",
+		`a?.;`:       "Expect property name after '?.'.",
+		`a?.b = 1;`:  "Invalid assignment target.",
+		`a?.b += 1;`: "Invalid assignment target.",
+	}
+	for source, expected := range errorCases {
+		_, parseErrors, _ := parse(t, source)
+		require.NotEmpty(t, parseErrors, source)
+		require.Equal(t, expected, parseErrors[0].Message, source)
+	}
+}
+
+func TestParseMatch(t *testing.T) {
+	statements, parseErrors, _ := parse(t, `match (v) { case 1, "a", -2, nil => print 1; case m.Point p if (p.x > 0) => {} default => v = 1; }`)
+	require.Empty(t, parseErrors)
+	match := statements[0].(*stmt.Match[any])
+	require.Len(t, match.Cases, 3)
+	require.Len(t, match.Cases[0].Patterns, 4)
+	require.Equal(t, -2.0, match.Cases[0].Patterns[2].(*expr.Literal[any]).Value)
+	require.Equal(t, "p", match.Cases[1].Binding.Lexeme)
+	require.NotNil(t, match.Cases[1].Guard)
+	require.True(t, match.Cases[2].IsDefault())
+
+	errorCases := map[string]string{
+		`match v { }`:                           "Expect '(' after 'match'.",
+		`match (v) { print 1; }`:                "Expect 'case' or 'default'.",
+		`match (v) { case 1 print 1; }`:         "Expect '=>' before case body.",
+		`match (v) { case a + 1 => print 1; }`:  "Expect '=>' before case body.",
+		`match (v) { case (1) => print 1; }`:    "Expect a literal or a class name as pattern.",
+		`match (v) { case A a, B => print 1; }`: "Can't bind a variable in a case with several patterns.",
+		`match (v) { case A if a => print 1; }`: "Expect '(' after 'if'.",
+	}
+	for source, expected := range errorCases {
+		_, parseErrors, _ := parse(t, source)
+		require.NotEmpty(t, parseErrors, source)
+		require.Equal(t, expected, parseErrors[0].Message, source)
+	}
+}
+
+func TestParseTypeAnnotations(t *testing.T) {
+	source := `var a: num = 1;
+var b: Point?;
+fun f(x: str, y, z: bool = true): list {}
+var g = (x: num) => x;
+class Point { x: num; y: num?; area: num { return 0; } init(x) {} }`
+	statements, parseErrors, _ := parse(t, source)
+	require.Empty(t, parseErrors)
+
+	a := statements[0].(*stmt.Var[any])
+	require.Equal(t, "num", a.Type.Name.Lexeme)
+	require.False(t, a.Type.Optional)
+	b := statements[1].(*stmt.Var[any])
+	require.Equal(t, "Point", b.Type.Name.Lexeme)
+	require.True(t, b.Type.Optional)
+
+	f := statements[2].(*stmt.Function[any])
+	require.Equal(t, "str", f.ParamTypes[0].Name.Lexeme)
+	require.Nil(t, f.ParamTypes[1])
+	require.Equal(t, "bool", f.ParamTypes[2].Name.Lexeme)
+	require.NotNil(t, f.Defaults[2])
+	require.Equal(t, "list", f.ReturnType.Name.Lexeme)
+
+	lambda := statements[3].(*stmt.Var[any]).Initializer.(*expr.Lambda[any])
+	require.Equal(t, "num", stmt.LambdaFunction(lambda).ParamTypes[0].Name.Lexeme)
+
+	class := statements[4].(*stmt.Class[any])
+	require.Len(t, class.Fields, 2)
+	require.True(t, class.Fields[1].Type.Optional)
+	require.Equal(t, "num", class.Getters[0].ReturnType.Name.Lexeme)
+	require.Len(t, class.Methods, 1)
+
+	errorCases := map[string]string{
+		`var a: = 1;`:        "Expect type name.",
+		`fun f(a: 1) {}`:     "Expect type name.",
+		`class A { x: num }`: "Expect ';' after field type or '{' before getter body.",
+		`fun f(): num? ;`:    "Expect '{' before function body.",
+		`var a: num 1;`:      "Expect ';' after variable declaration.",
+	}
+	for source, expected := range errorCases {
+		_, parseErrors, _ := parse(t, source)
+		require.NotEmpty(t, parseErrors, source)
+		require.Equal(t, expected, parseErrors[0].Message, source)
+	}
+}
@@ -7,6 +7,7 @@ const (
 	FunctionTypeFunction
 	FunctionTypeMethod
 	FunctionTypeInitializer
+	FunctionTypeLambda
 )
 
 type ClassType int
@@ -15,4 +16,5 @@ const (
 	ClassTypeNone ClassType = iota
 	ClassTypeClass
 	ClassTypeSubclass
+	ClassTypeTrait
 )
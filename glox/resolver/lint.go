@@ -0,0 +1,322 @@
+package resolver
+
+import (
+	"fmt"
+	"glox/errors"
+	"glox/expr"
+	"glox/stmt"
+	"glox/tokens"
+	"sort"
+	"strings"
+)
+
+// NoMaxArity is the maximum arity of the callables accepting any number of arguments
+const NoMaxArity = -1
+
+// Callable describes the arguments accepted by a native function
+type Callable interface {
+	Arity() int
+	// MaxArity returns NoMaxArity if there is no limit
+	MaxArity() int
+}
+
+type variableKind int
+
+const (
+	variableLocal variableKind = iota
+	variableParameter
+	variableSynthetic
+)
+
+// EnableLint makes the resolver warn about unused variables and parameters, shadowed variables, unreachable code,
+// values assigned and never read and calls to the provided natives with the wrong number of arguments.
+func (r *Resolver) EnableLint(natives map[string]Callable) {
+	r.lint = true
+	r.natives = natives
+}
+
+type warning struct {
+	code    errors.Code
+	token   tokens.Token
+	message string
+}
+
+// warn records a warning, variables are checked when their scope ends so warnings are not found in source order
+func (r *Resolver) warn(code errors.Code, token tokens.Token, message string) {
+	r.warnings = append(r.warnings, warning{code: code, token: token, message: message})
+}
+
+// reportWarnings reports the warnings recorded by their position in the source
+func (r *Resolver) reportWarnings() {
+	sort.SliceStable(r.warnings, func(i, j int) bool {
+		a, b := r.warnings[i].token, r.warnings[j].token
+		return a.Line < b.Line || a.Line == b.Line && a.Column < b.Column
+	})
+	for _, w := range r.warnings {
+		r.reporter.Warn(w.code, w.token, w.message)
+	}
+	r.warnings = nil
+}
+
+// declareGlobals records the names declared at the top level, so calls to them are not checked as natives
+func (r *Resolver) declareGlobals(statements []stmt.Stmt[any]) {
+	for _, statement := range statements {
+		switch s := statement.(type) {
+		case *stmt.Var[any]:
+			r.globals[s.Name.Lexeme] = true
+		case *stmt.Function[any]:
+			r.globals[s.Name.Lexeme] = true
+		case *stmt.Class[any]:
+			r.globals[s.Name.Lexeme] = true
+		case *stmt.Trait[any]:
+			r.globals[s.Name.Lexeme] = true
+		case *stmt.Import[any]:
+			r.globals[s.Name.Lexeme] = true
+		}
+	}
+}
+
+// read marks the local variable as used
+func (r *Resolver) read(local *variable) {
+	if local == nil {
+		return
+	}
+	local.read = true
+	local.captured = local.captured || local.functionDepth != r.functionDepth
+	local.lastWrite = nil
+}
+
+// write records an assignment to the local variable, and warns if it overwrites a value that is never read.
+// Assignments from nested functions or from loops the variable is not declared in could be read later on, they are
+// not tracked.
+func (r *Resolver) write(local *variable, name tokens.Token) {
+	if local == nil {
+		return
+	}
+	if local.lastWrite != nil && !local.captured && local.lastWriteBranch == r.branches && r.tryDepth == 0 {
+		r.warn(errors.CodeUnreadValue, *local.lastWrite, fmt.Sprintf("Value assigned to '%s' is never read.", local.name.Lexeme))
+	}
+	local.lastWrite = nil
+	if local.functionDepth == r.functionDepth && local.loopDepth == r.loopDepth {
+		local.lastWrite = &name
+		local.lastWriteBranch = r.branches
+	}
+}
+
+// checkShadowing warns if the variable being declared hides a local variable of an enclosing scope
+func (r *Resolver) checkShadowing(name tokens.Token) {
+	for i := r.scopes.Size() - 2; i >= 0; i-- {
+		if local, exists := r.scopes.Get(i)[name.Lexeme]; exists && local.kind != variableSynthetic {
+			r.warn(errors.CodeShadowed, name, fmt.Sprintf("'%s' shadows the variable declared in line %d.", name.Lexeme, local.name.Line))
+			return
+		}
+	}
+}
+
+// checkUnused warns about the variables of the scope being closed that are never read, and about the values
+// assigned to them that are not read afterward. Variables whose name starts with '_' are not checked.
+func (r *Resolver) checkUnused(s scope) {
+	locals := make([]*variable, 0, len(s))
+	for _, local := range s {
+		if local.kind != variableSynthetic && !strings.HasPrefix(local.name.Lexeme, "_") {
+			locals = append(locals, local)
+		}
+	}
+	sort.Slice(locals, func(i, j int) bool { return locals[i].slot < locals[j].slot })
+	for _, local := range locals {
+		switch {
+		case !local.read && local.kind == variableParameter:
+			r.warn(errors.CodeUnusedParameter, local.name, fmt.Sprintf("Parameter '%s' is never used.", local.name.Lexeme))
+		case !local.read:
+			r.warn(errors.CodeUnusedLocal, local.name, fmt.Sprintf("Local variable '%s' is never used.", local.name.Lexeme))
+		case local.lastWrite != nil && !local.captured:
+			r.warn(errors.CodeUnreadValue, *local.lastWrite, fmt.Sprintf("Value assigned to '%s' is never read.", local.name.Lexeme))
+		}
+	}
+}
+
+// checkUnreachable warns at the next statement if the statement is a return, throw, break or continue statement,
+// as the statements following it are never executed. It returns true if it warns.
+func (r *Resolver) checkUnreachable(statement stmt.Stmt[any], next stmt.Stmt[any]) bool {
+	var keyword tokens.Token
+	switch s := statement.(type) {
+	case *stmt.Return[any]:
+		keyword = s.Keyword
+	case *stmt.Throw[any]:
+		keyword = s.Keyword
+	case *stmt.Break[any]:
+		keyword = s.Keyword
+	case *stmt.Continue[any]:
+		keyword = s.Keyword
+	default:
+		return false
+	}
+	token, found := statementToken(next)
+	if !found {
+		token = keyword
+	}
+	r.warn(errors.CodeUnreachableCode, token, fmt.Sprintf("Unreachable code after '%s'.", keyword.Lexeme))
+	return true
+}
+
+// statementToken returns the first token of the statement, it returns false if the statement doesn't keep it,
+// Eg: an expression statement starting with a literal
+func statementToken(statement stmt.Stmt[any]) (tokens.Token, bool) {
+	switch s := statement.(type) {
+	case *stmt.Block[any]:
+		if len(s.Statements) > 0 {
+			return statementToken(s.Statements[0])
+		}
+	case *stmt.Break[any]:
+		return s.Keyword, true
+	case *stmt.Class[any]:
+		return s.Name, true
+	case *stmt.Continue[any]:
+		return s.Keyword, true
+	case *stmt.Expression[any]:
+		return expressionToken(s.Expression)
+	case *stmt.Function[any]:
+		return s.Name, true
+	case *stmt.If[any]:
+		return s.Keyword, true
+	case *stmt.Import[any]:
+		return s.Keyword, true
+	case *stmt.Match[any]:
+		return s.Keyword, true
+	case *stmt.Print[any]:
+		return s.Keyword, true
+	case *stmt.Return[any]:
+		return s.Keyword, true
+	case *stmt.Throw[any]:
+		return s.Keyword, true
+	case *stmt.Trait[any]:
+		return s.Name, true
+	case *stmt.Try[any]:
+		return s.Keyword, true
+	case *stmt.Var[any]:
+		return s.Name, true
+	case *stmt.While[any]:
+		return s.Keyword, true
+	}
+	return tokens.Token{}, false
+}
+
+// expressionToken returns the first token of the expression, it returns false if the expression doesn't keep it
+func expressionToken(expression expr.Expr[any]) (tokens.Token, bool) {
+	switch e := expression.(type) {
+	case *expr.Assign[any]:
+		return e.Name, true
+	case *expr.Binary[any]:
+		return expressionToken(e.Left)
+	case *expr.Call[any]:
+		return expressionToken(e.Callee)
+	case *expr.CompoundAssign[any]:
+		// prefix increments start with the operator, Eg: `++a`
+		if e.Operator.Offset < e.Name.Offset {
+			return e.Operator, true
+		}
+		return e.Name, true
+	case *expr.CompoundSet[any]:
+		if object, found := expressionToken(e.Object); found && object.Offset < e.Operator.Offset {
+			return object, true
+		}
+		return e.Operator, true
+	case *expr.Conditional[any]:
+		return expressionToken(e.Condition)
+	case *expr.Get[any]:
+		return expressionToken(e.Object)
+	case *expr.Grouping[any]:
+		return expressionToken(e.Expression)
+	case *expr.CompoundIndexSet[any]:
+		if object, found := expressionToken(e.Object); found && object.Offset < e.Operator.Offset {
+			return object, true
+		}
+		return e.Operator, true
+	case *expr.Index[any]:
+		return expressionToken(e.Object)
+	case *expr.IndexSet[any]:
+		return expressionToken(e.Object)
+	case *expr.Interpolation[any]:
+		return e.Start, true
+	case *expr.Lambda[any]:
+		return e.Keyword, true
+	case *expr.List[any]:
+		return e.Bracket, true
+	case *expr.Logical[any]:
+		return expressionToken(e.Left)
+	case *expr.Map[any]:
+		return e.Brace, true
+	case *expr.OptionalChain[any]:
+		return expressionToken(e.Expression)
+	case *expr.OptionalGet[any]:
+		return expressionToken(e.Object)
+	case *expr.Set[any]:
+		return expressionToken(e.Object)
+	case *expr.Slice[any]:
+		return expressionToken(e.Object)
+	case *expr.Super[any]:
+		return e.Keyword, true
+	case *expr.This[any]:
+		return e.Keyword, true
+	case *expr.Unary[any]:
+		return e.Operator, true
+	case *expr.Variable[any]:
+		return e.Name, true
+	}
+	return tokens.Token{}, false
+}
+
+// checkNativeCall warns if the call refers to a native function and the number of arguments is not valid
+func (r *Resolver) checkNativeCall(c *expr.Call[any]) {
+	name := r.nativeName(c.Callee)
+	native, isNative := r.natives[name]
+	if !isNative {
+		return
+	}
+	count := len(c.Arguments)
+	if count >= native.Arity() && (native.MaxArity() == NoMaxArity || count <= native.MaxArity()) {
+		return
+	}
+	r.warn(errors.CodeNativeArity, c.Paren, fmt.Sprintf("'%s' expects %s but got %d.", name, describeArity(native), count))
+}
+
+// nativeName returns the name a native function would have if the callee refers to one, Eg: "math.sqrt"
+func (r *Resolver) nativeName(callee expr.Expr[any]) string {
+	switch callee := callee.(type) {
+	case *expr.Variable[any]:
+		if !r.isDeclared(callee.Name.Lexeme) {
+			return callee.Name.Lexeme
+		}
+	case *expr.Get[any]:
+		if module, isVariable := callee.Object.(*expr.Variable[any]); isVariable && !r.isDeclared(module.Name.Lexeme) {
+			return module.Name.Lexeme + "." + callee.Name.Lexeme
+		}
+	}
+	return ""
+}
+
+// isDeclared tells if the program declares a variable with the provided name that is visible from the current scope
+func (r *Resolver) isDeclared(name string) bool {
+	for i := range r.scopes.Size() {
+		if _, exists := r.scopes.Get(i)[name]; exists {
+			return true
+		}
+	}
+	return r.globals[name]
+}
+
+func describeArity(c Callable) string {
+	arguments := func(n int) string {
+		if n == 1 {
+			return "1 argument"
+		}
+		return fmt.Sprintf("%d arguments", n)
+	}
+	switch {
+	case c.MaxArity() == c.Arity():
+		return arguments(c.Arity())
+	case c.MaxArity() == NoMaxArity:
+		return "at least " + arguments(c.Arity())
+	}
+	return fmt.Sprintf("%d to %s", c.Arity(), arguments(c.MaxArity()))
+}
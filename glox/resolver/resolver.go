@@ -1,32 +1,81 @@
 package resolver
 
 import (
+	"fmt"
 	"glox/errors"
 	"glox/expr"
-	"glox/interpreter"
 	"glox/stmt"
 	"glox/tokens"
+	"slices"
+	"strconv"
 )
 
+// Locals receives the scope distance and the slot of every resolved local variable. The tree-walking
+// interpreter uses it to look variables up.
+type Locals interface {
+	Resolve(expression expr.Expr[any], depth int, slot int)
+}
+
+// variable holds the resolution state of a local variable
+type variable struct {
+	defined bool
+	// slot is the position of the variable in its scope, variables are numbered in declaration order
+	slot int
+	// trait is the declaration of the trait if the variable holds one
+	trait *stmt.Trait[any]
+
+	// the fields below are only used by the linter
+	name tokens.Token
+	kind variableKind
+	read bool
+	// captured tells if the variable is read from a nested function
+	captured bool
+	// lastWrite is the last assignment to the variable if the value has not been read yet
+	lastWrite *tokens.Token
+	// lastWriteBranch is the number of branches found before lastWrite
+	lastWriteBranch int
+	// functionDepth and loopDepth locate the declaration
+	functionDepth int
+	loopDepth     int
+}
+
+type scope map[string]*variable
+
 type Resolver struct {
-	Interpreter         *interpreter.Interpreter
-	scopes              Stack[map[string]bool]
+	locals              Locals
+	scopes              Stack[scope]
 	currentFunctionType FunctionType
 	currentClassType    ClassType
-}
-
-func NewResolver(i *interpreter.Interpreter) Resolver {
-	return Resolver{Interpreter: i, scopes: Stack[map[string]bool]{}, currentFunctionType: FunctionTypeNone, currentClassType: ClassTypeNone}
+	// traits holds the trait declarations found at the top level, by name, to check the classes using them. Traits
+	// declared in local scopes are kept in their variable.
+	traits map[string]*stmt.Trait[any]
+	// loopDepth is the number of loops enclosing the current statement within the current function
+	loopDepth int
+	// functionDepth is the number of functions enclosing the current statement
+	functionDepth int
+	reporter      *errors.Reporter
+
+	lint bool
+	// natives holds the native functions whose calls are checked by the linter, by name
+	natives map[string]Callable
+	// globals holds the names declared at the top level of the program
+	globals map[string]bool
+	// branches counts the conditionally executed code found so far, assignments with a branch between them can't
+	// overwrite each other's value
+	branches int
+	// tryDepth is the number of try statements enclosing the current statement, any call in them could jump to the
+	// catch block before the next assignment
+	tryDepth int
+	// warnings holds the warnings found so far, they are reported in source order once the statements are resolved
+	warnings []warning
+}
+
+func NewResolver(locals Locals, reporter *errors.Reporter) Resolver {
+	return Resolver{locals: locals, scopes: Stack[scope]{}, currentFunctionType: FunctionTypeNone, currentClassType: ClassTypeNone, traits: map[string]*stmt.Trait[any]{}, reporter: reporter, globals: map[string]bool{}}
 }
 
 func (r *Resolver) VisitForBlock(s *stmt.Block[any]) (any, error) {
-	r.beginScope()
-	if err := r.ResolveStatements(s.Statements); err != nil {
-		return nil, err
-	}
-	r.endScope()
-	return nil, nil
-
+	return nil, r.resolveBlock(s.Statements)
 }
 
 func (r *Resolver) VisitForExpression(e *stmt.Expression[any]) (any, error) {
@@ -46,10 +95,12 @@ func (r *Resolver) VisitForIf(s *stmt.If[any]) (any, error) {
 	if err := r.resolveExpr(s.Condition); err != nil {
 		return nil, err
 	}
+	r.branches++
 	if err := r.resolveStmt(s.ThenBranch); err != nil {
 		return nil, err
 	}
 	if s.ElseBranch != nil {
+		r.branches++
 		if err := r.resolveStmt(s.ElseBranch); err != nil {
 			return nil, err
 		}
@@ -57,17 +108,23 @@ func (r *Resolver) VisitForIf(s *stmt.If[any]) (any, error) {
 	return nil, nil
 }
 
+func (r *Resolver) VisitForImport(s *stmt.Import[any]) (any, error) {
+	r.declare(s.Name)
+	r.define(s.Name)
+	return nil, nil
+}
+
 func (r *Resolver) VisitForPrint(s *stmt.Print[any]) (any, error) {
 	return nil, r.resolveExpr(s.Expression)
 }
 
 func (r *Resolver) VisitForReturn(s *stmt.Return[any]) (any, error) {
 	if r.currentFunctionType == FunctionTypeNone {
-		errors.AtToken(s.Keyword, "Can't return from top-level code.")
+		r.reporter.AtToken(errors.CodeResolve, s.Keyword, "Can't return from top-level code.")
 		return nil, nil
 	}
 	if s.Value != nil && r.currentFunctionType == FunctionTypeInitializer {
-		errors.AtToken(s.Keyword, "Can't return a value from an initializer.")
+		r.reporter.AtToken(errors.CodeResolve, s.Keyword, "Can't return a value from an initializer.")
 	}
 	if s.Value != nil {
 		return nil, r.resolveExpr(s.Value)
@@ -75,6 +132,102 @@ func (r *Resolver) VisitForReturn(s *stmt.Return[any]) (any, error) {
 	return nil, nil
 }
 
+func (r *Resolver) VisitForThrow(s *stmt.Throw[any]) (any, error) {
+	return nil, r.resolveExpr(s.Value)
+}
+
+func (r *Resolver) VisitForTry(s *stmt.Try[any]) (any, error) {
+	r.tryDepth++
+	err := r.resolveBlock(s.Body)
+	r.tryDepth--
+	if err != nil {
+		return nil, err
+	}
+	r.branches++
+	if s.CatchName != nil {
+		r.beginScope()
+		r.declare(*s.CatchName)
+		r.define(*s.CatchName)
+		if err := r.resolveStmtList(s.CatchBody); err != nil {
+			return nil, err
+		}
+		r.endScope()
+	}
+	if s.FinallyBody != nil {
+		if err := r.resolveBlock(s.FinallyBody); err != nil {
+			return nil, err
+		}
+	}
+	return nil, nil
+}
+
+func (r *Resolver) VisitForMatch(m *stmt.Match[any]) (any, error) {
+	if err := r.resolveExpr(m.Value); err != nil {
+		return nil, err
+	}
+	r.checkCases(m.Cases)
+	for _, matchCase := range m.Cases {
+		r.branches++
+		for _, pattern := range matchCase.Patterns {
+			if err := r.resolveExpr(pattern); err != nil {
+				return nil, err
+			}
+		}
+		if matchCase.Binding != nil {
+			r.beginScope()
+			r.declare(*matchCase.Binding)
+			r.define(*matchCase.Binding)
+		}
+		if matchCase.Guard != nil {
+			if err := r.resolveExpr(matchCase.Guard); err != nil {
+				return nil, err
+			}
+		}
+		if err := r.resolveStmt(matchCase.Body); err != nil {
+			return nil, err
+		}
+		if matchCase.Binding != nil {
+			r.endScope()
+		}
+	}
+	return nil, nil
+}
+
+// checkCases warns about the cases that can't be reached: the ones after a default case and the literals already
+// matched by a previous case without guard
+func (r *Resolver) checkCases(cases []*stmt.MatchCase[any]) {
+	literals := map[any]bool{}
+	for n, matchCase := range cases {
+		if n > 0 && cases[n-1].IsDefault() {
+			r.warn(errors.CodeUnreachableCase, matchCase.Keyword, fmt.Sprintf("Unreachable '%s', the previous 'default' matches every value.", matchCase.Keyword.Lexeme))
+			return
+		}
+		for _, pattern := range matchCase.Patterns {
+			literal, isLiteral := pattern.(*expr.Literal[any])
+			if !isLiteral {
+				continue
+			}
+			if literals[literal.Value] {
+				r.warn(errors.CodeDuplicateCase, matchCase.Keyword, fmt.Sprintf("Duplicate case %s, a previous case matches it.", describeLiteral(literal.Value)))
+			}
+			if matchCase.Guard == nil {
+				literals[literal.Value] = true
+			}
+		}
+	}
+}
+
+// describeLiteral returns the literal as it is written in the source code
+func describeLiteral(v any) string {
+	switch v := v.(type) {
+	case string:
+		return strconv.Quote(v)
+	case float64:
+		return tokens.FormatNumber(v)
+	}
+	return fmt.Sprint(v)
+}
+
 func (r *Resolver) VisitForVar(s *stmt.Var[any]) (any, error) {
 	r.declare(s.Name)
 	if s.Initializer != nil {
@@ -90,9 +243,28 @@ func (r *Resolver) VisitForWhile(s *stmt.While[any]) (any, error) {
 	if err := r.resolveExpr(s.Condition); err != nil {
 		return nil, err
 	}
+	r.loopDepth++
+	defer func() { r.loopDepth-- }()
 	if err := r.resolveStmt(s.Body); err != nil {
 		return nil, err
 	}
+	if s.Increment != nil {
+		return nil, r.resolveExpr(s.Increment)
+	}
+	return nil, nil
+}
+
+func (r *Resolver) VisitForBreak(s *stmt.Break[any]) (any, error) {
+	if r.loopDepth == 0 {
+		r.reporter.AtToken(errors.CodeResolve, s.Keyword, "Can't use 'break' outside of a loop.")
+	}
+	return nil, nil
+}
+
+func (r *Resolver) VisitForContinue(s *stmt.Continue[any]) (any, error) {
+	if r.loopDepth == 0 {
+		r.reporter.AtToken(errors.CodeResolve, s.Keyword, "Can't use 'continue' outside of a loop.")
+	}
 	return nil, nil
 }
 
@@ -110,6 +282,74 @@ func (r *Resolver) VisitForSet(s *expr.Set[any]) (any, error) {
 	return nil, nil
 }
 
+func (r *Resolver) VisitForLambda(l *expr.Lambda[any]) (any, error) {
+	return nil, r.resolveFunction(stmt.LambdaFunction(l), FunctionTypeLambda)
+}
+
+func (r *Resolver) VisitForInterpolation(i *expr.Interpolation[any]) (any, error) {
+	for _, part := range i.Parts {
+		if err := r.resolveExpr(part); err != nil {
+			return nil, err
+		}
+	}
+	return nil, nil
+}
+
+func (r *Resolver) VisitForList(l *expr.List[any]) (any, error) {
+	for _, element := range l.Elements {
+		if err := r.resolveExpr(element); err != nil {
+			return nil, err
+		}
+	}
+	return nil, nil
+}
+
+func (r *Resolver) VisitForMap(m *expr.Map[any]) (any, error) {
+	for i := range m.Keys {
+		if err := r.resolveExpr(m.Keys[i]); err != nil {
+			return nil, err
+		}
+		if err := r.resolveExpr(m.Values[i]); err != nil {
+			return nil, err
+		}
+	}
+	return nil, nil
+}
+
+func (r *Resolver) VisitForIndex(i *expr.Index[any]) (any, error) {
+	if err := r.resolveExpr(i.Object); err != nil {
+		return nil, err
+	}
+	return nil, r.resolveExpr(i.Index)
+}
+
+func (r *Resolver) VisitForIndexSet(i *expr.IndexSet[any]) (any, error) {
+	if err := r.resolveExpr(i.Object); err != nil {
+		return nil, err
+	}
+	if err := r.resolveExpr(i.Index); err != nil {
+		return nil, err
+	}
+	return nil, r.resolveExpr(i.Value)
+}
+
+func (r *Resolver) VisitForSlice(s *expr.Slice[any]) (any, error) {
+	if err := r.resolveExpr(s.Object); err != nil {
+		return nil, err
+	}
+	if s.Start != nil {
+		if err := r.resolveExpr(s.Start); err != nil {
+			return nil, err
+		}
+	}
+	if s.End != nil {
+		if err := r.resolveExpr(s.End); err != nil {
+			return nil, err
+		}
+	}
+	return nil, nil
+}
+
 func (r *Resolver) VisitForClass(c *stmt.Class[any]) (any, error) {
 	enclosingClassType := r.currentClassType
 	r.currentClassType = ClassTypeClass
@@ -117,20 +357,26 @@ func (r *Resolver) VisitForClass(c *stmt.Class[any]) (any, error) {
 	r.define(c.Name)
 
 	if c.SuperClass != nil {
-		r.beginScope()
-		r.scopes.Peek()["super"] = true
 		if c.SuperClass.Name == c.Name {
-			errors.AtToken(c.SuperClass.Name, "A class can't inherit from itself.")
+			r.reporter.AtToken(errors.CodeResolve, c.SuperClass.Name, "A class can't inherit from itself.")
 		}
 		r.currentClassType = ClassTypeSubclass
+		// the superclass is evaluated before creating the scope holding "super"
 		if err := r.resolveExpr(c.SuperClass); err != nil {
 			return nil, err
 		}
+		r.beginScope()
+		r.defineSynthetic("super")
+	}
+	for _, trait := range c.Traits {
+		if err := r.resolveExpr(trait); err != nil {
+			return nil, err
+		}
 	}
+	r.checkTraitConflicts(c)
 
 	r.beginScope()
-	scope := r.scopes.Peek()
-	scope["this"] = true
+	r.defineSynthetic("this")
 
 	for _, method := range c.Methods {
 		declaration := FunctionTypeMethod
@@ -141,6 +387,12 @@ func (r *Resolver) VisitForClass(c *stmt.Class[any]) (any, error) {
 			return nil, nil
 		}
 	}
+	// in class methods "this" refers to the class
+	for _, method := range slices.Concat(c.ClassMethods, c.Getters, c.Setters) {
+		if err := r.resolveFunction(method, FunctionTypeMethod); err != nil {
+			return nil, nil
+		}
+	}
 	r.endScope()
 	if c.SuperClass != nil {
 		r.endScope()
@@ -149,13 +401,76 @@ func (r *Resolver) VisitForClass(c *stmt.Class[any]) (any, error) {
 	return nil, nil
 }
 
+// checkTraitConflicts reports the methods defined by more than one of the traits used by the class, or by a trait
+// and the class itself. Only the traits declared in the program being resolved can be checked.
+func (r *Resolver) checkTraitConflicts(c *stmt.Class[any]) {
+	owners := map[string]string{}
+	for _, method := range c.Methods {
+		owners[method.Name.Lexeme] = fmt.Sprintf("class '%s'", c.Name.Lexeme)
+	}
+	for _, variable := range c.Traits {
+		trait := r.lookupTrait(variable.Name)
+		if trait == nil {
+			continue
+		}
+		for _, method := range trait.Methods {
+			if owner, defined := owners[method.Name.Lexeme]; defined {
+				r.reporter.AtToken(errors.CodeResolve, variable.Name, fmt.Sprintf("Trait '%s' redefines method '%s' of %s.", trait.Name.Lexeme, method.Name.Lexeme, owner))
+				continue
+			}
+			owners[method.Name.Lexeme] = fmt.Sprintf("trait '%s'", trait.Name.Lexeme)
+		}
+	}
+}
+
+// lookupTrait returns the trait declaration the name refers to, or nil if it doesn't refer to a trait declared in the
+// program being resolved
+func (r *Resolver) lookupTrait(name tokens.Token) *stmt.Trait[any] {
+	for i := r.scopes.Size() - 1; i >= 0; i-- {
+		if local, exists := r.scopes.Get(i)[name.Lexeme]; exists {
+			return local.trait
+		}
+	}
+	return r.traits[name.Lexeme]
+}
+
+func (r *Resolver) VisitForTrait(t *stmt.Trait[any]) (any, error) {
+	enclosingClassType := r.currentClassType
+	r.currentClassType = ClassTypeTrait
+	r.declare(t.Name)
+	r.define(t.Name)
+	if r.scopes.IsEmpty() {
+		r.traits[t.Name.Lexeme] = t
+	} else {
+		r.scopes.Peek()[t.Name.Lexeme].trait = t
+	}
+
+	r.beginScope()
+	r.defineSynthetic("this")
+	for _, method := range t.Methods {
+		if method.Name.Lexeme == "init" {
+			r.reporter.AtToken(errors.CodeResolve, method.Name, "Can't define an initializer in a trait.")
+		}
+		if err := r.resolveFunction(method, FunctionTypeMethod); err != nil {
+			return nil, err
+		}
+	}
+	r.endScope()
+	r.currentClassType = enclosingClassType
+	return nil, nil
+}
+
 func (r *Resolver) VisitForSuper(t *expr.Super[any]) (any, error) {
 	if r.currentClassType == ClassTypeNone {
-		errors.AtToken(t.Keyword, "Can't use 'super' outside of a class.")
+		r.reporter.AtToken(errors.CodeResolve, t.Keyword, "Can't use 'super' outside of a class.")
+		return nil, nil
+	}
+	if r.currentClassType == ClassTypeTrait {
+		r.reporter.AtToken(errors.CodeResolve, t.Keyword, "Can't use 'super' in a trait.")
 		return nil, nil
 	}
 	if r.currentClassType != ClassTypeSubclass {
-		errors.AtToken(t.Keyword, "Can't use 'super' in a class with no superclass.")
+		r.reporter.AtToken(errors.CodeResolve, t.Keyword, "Can't use 'super' in a class with no superclass.")
 		return nil, nil
 	}
 	r.resolveLocal(t, t.Keyword)
@@ -164,7 +479,7 @@ func (r *Resolver) VisitForSuper(t *expr.Super[any]) (any, error) {
 
 func (r *Resolver) VisitForThis(t *expr.This[any]) (any, error) {
 	if r.currentClassType == ClassTypeNone {
-		errors.AtToken(t.Keyword, "Can't use 'this' outside of a class.")
+		r.reporter.AtToken(errors.CodeResolve, t.Keyword, "Can't use 'this' outside of a class.")
 		return nil, nil
 	}
 	r.resolveLocal(t, t.Keyword)
@@ -173,16 +488,20 @@ func (r *Resolver) VisitForThis(t *expr.This[any]) (any, error) {
 
 func (r *Resolver) VisitForVariable(v *expr.Variable[any]) (any, error) {
 	if !r.scopes.IsEmpty() {
-		if inScope, exits := r.scopes.Peek()[v.Name.Lexeme]; exits && !inScope {
-			errors.AtToken(v.Name, "Can't read local variable in its own initializer.")
+		if local, exits := r.scopes.Peek()[v.Name.Lexeme]; exits && !local.defined {
+			r.reporter.AtToken(errors.CodeResolve, v.Name, "Can't read local variable in its own initializer.")
 			return nil, nil
 		}
 	}
-	r.resolveLocal(v, v.Name)
+	r.read(r.resolveLocal(v, v.Name))
 	return nil, nil
 }
 
 func (r *Resolver) ResolveStatements(statements []stmt.Stmt[any]) error {
+	defer r.reportWarnings()
+	if r.lint {
+		r.declareGlobals(statements)
+	}
 	for _, statement := range statements {
 		if err := r.ResolveStatement(statement); err != nil {
 			return err
@@ -205,6 +524,9 @@ func (r *Resolver) VisitForCall(c *expr.Call[any]) (any, error) {
 	if err := r.resolveExpr(c.Callee); err != nil {
 		return nil, err
 	}
+	if r.lint {
+		r.checkNativeCall(c)
+	}
 	for _, arg := range c.Arguments {
 		if err := r.resolveExpr(arg); err != nil {
 			return nil, err
@@ -225,6 +547,7 @@ func (r *Resolver) VisitForLogical(l *expr.Logical[any]) (any, error) {
 	if err := r.resolveExpr(l.Left); err != nil {
 		return nil, err
 	}
+	r.branches++
 	if err := r.resolveExpr(l.Right); err != nil {
 		return nil, err
 	}
@@ -235,10 +558,59 @@ func (r *Resolver) VisitForAssign(a *expr.Assign[any]) (any, error) {
 	if err := r.resolveExpr(a.Value); err != nil {
 		return nil, err
 	}
-	r.resolveLocal(a, a.Name)
+	r.write(r.resolveLocal(a, a.Name), a.Name)
+	return nil, nil
+}
+
+func (r *Resolver) VisitForCompoundAssign(a *expr.CompoundAssign[any]) (any, error) {
+	if err := r.resolveExpr(a.Value); err != nil {
+		return nil, err
+	}
+	local := r.resolveLocal(a, a.Name)
+	r.read(local)
+	r.write(local, a.Name)
 	return nil, nil
 }
 
+func (r *Resolver) VisitForCompoundSet(s *expr.CompoundSet[any]) (any, error) {
+	if err := r.resolveExpr(s.Object); err != nil {
+		return nil, err
+	}
+	return nil, r.resolveExpr(s.Value)
+}
+
+func (r *Resolver) VisitForCompoundIndexSet(s *expr.CompoundIndexSet[any]) (any, error) {
+	if err := r.resolveExpr(s.Object); err != nil {
+		return nil, err
+	}
+	if err := r.resolveExpr(s.Index); err != nil {
+		return nil, err
+	}
+	return nil, r.resolveExpr(s.Value)
+}
+
+func (r *Resolver) VisitForConditional(c *expr.Conditional[any]) (any, error) {
+	if err := r.resolveExpr(c.Condition); err != nil {
+		return nil, err
+	}
+	r.branches++
+	if err := r.resolveExpr(c.Then); err != nil {
+		return nil, err
+	}
+	r.branches++
+	return nil, r.resolveExpr(c.Else)
+}
+
+func (r *Resolver) VisitForOptionalGet(g *expr.OptionalGet[any]) (any, error) {
+	return nil, r.resolveExpr(g.Object)
+}
+
+func (r *Resolver) VisitForOptionalChain(c *expr.OptionalChain[any]) (any, error) {
+	// the chain stops at the first nil value
+	r.branches++
+	return nil, r.resolveExpr(c.Expression)
+}
+
 func (r *Resolver) VisitForUnary(unary *expr.Unary[any]) (any, error) {
 	return nil, r.resolveExpr(unary.Right)
 }
@@ -249,11 +621,14 @@ func (r *Resolver) ResolveStatement(statement stmt.Stmt[any]) error {
 }
 
 func (r *Resolver) beginScope() {
-	r.scopes.Push(map[string]bool{})
+	r.scopes.Push(scope{})
 }
 
 func (r *Resolver) endScope() {
-	_ = r.scopes.Pop()
+	s := r.scopes.Pop()
+	if r.lint {
+		r.checkUnused(s)
+	}
 }
 
 func (r *Resolver) declare(name tokens.Token) {
@@ -262,28 +637,39 @@ func (r *Resolver) declare(name tokens.Token) {
 	}
 	scope := r.scopes.Peek()
 	if _, exits := scope[name.Lexeme]; exits {
-		errors.AtToken(name, "Already a variable with this name in this scope.")
+		r.reporter.AtToken(errors.CodeResolve, name, "Already a variable with this name in this scope.")
+		return
 	}
-	scope[name.Lexeme] = false
+	if r.lint {
+		r.checkShadowing(name)
+	}
+	scope[name.Lexeme] = &variable{slot: len(scope), name: name, functionDepth: r.functionDepth, loopDepth: r.loopDepth}
 }
 
 func (r *Resolver) define(name tokens.Token) {
 	if r.scopes.IsEmpty() {
 		return
 	}
+	r.scopes.Peek()[name.Lexeme].defined = true
+}
+
+// defineSynthetic defines a variable that is not declared in the source, such as "this"
+func (r *Resolver) defineSynthetic(name string) {
 	scope := r.scopes.Peek()
-	scope[name.Lexeme] = true
+	scope[name] = &variable{defined: true, slot: len(scope), kind: variableSynthetic}
 }
 
-func (r *Resolver) resolveLocal(expression expr.Expr[any], name tokens.Token) {
+// resolveLocal resolves the variable referenced by the expression, it returns nil if it is not a local variable
+func (r *Resolver) resolveLocal(expression expr.Expr[any], name tokens.Token) *variable {
 	for i := r.scopes.Size() - 1; i >= 0; i-- {
 		scope := r.scopes.Get(i)
-		if _, containsKey := scope[name.Lexeme]; containsKey {
+		if local, containsKey := scope[name.Lexeme]; containsKey {
 			dept := r.scopes.Size() - 1 - i
-			r.Interpreter.Resolve(expression, dept)
-			return
+			r.locals.Resolve(expression, dept, local.slot)
+			return local
 		}
 	}
+	return nil
 }
 
 func (r *Resolver) resolveExpr(v expr.Expr[any]) error {
@@ -297,28 +683,51 @@ func (r *Resolver) resolveStmt(s stmt.Stmt[any]) error {
 }
 
 func (r *Resolver) resolveStmtList(l []stmt.Stmt[any]) error {
-	for _, s := range l {
+	unreachable := false
+	for n, s := range l {
 		if err := r.resolveStmt(s); err != nil {
 			return err
 		}
+		if r.lint && !unreachable && n < len(l)-1 {
+			unreachable = r.checkUnreachable(s, l[n+1])
+		}
 	}
 	return nil
 }
 
+// resolveBlock resolves the provided statements in a new scope
+func (r *Resolver) resolveBlock(statements []stmt.Stmt[any]) error {
+	r.beginScope()
+	if err := r.resolveStmtList(statements); err != nil {
+		return err
+	}
+	r.endScope()
+	return nil
+}
+
 func (r *Resolver) resolveFunction(f *stmt.Function[any], functionType FunctionType) error {
-	enclosingFunctionType := r.currentFunctionType
-	r.currentFunctionType = functionType
+	enclosingFunctionType, enclosingLoopDepth := r.currentFunctionType, r.loopDepth
+	r.currentFunctionType, r.loopDepth = functionType, 0
+	r.functionDepth++
 
 	r.beginScope()
-	for _, param := range f.Params {
+	for i, param := range f.Params {
+		// defaults are evaluated in the function scope, they can refer to the previous parameters
+		if f.Defaults[i] != nil {
+			if err := r.resolveExpr(f.Defaults[i]); err != nil {
+				return err
+			}
+		}
 		r.declare(param)
 		r.define(param)
+		r.scopes.Peek()[param.Lexeme].kind = variableParameter
 	}
 	if err := r.resolveStmtList(f.Body); err != nil {
 		return err
 	}
 	r.endScope()
-	r.currentFunctionType = enclosingFunctionType
+	r.functionDepth--
+	r.currentFunctionType, r.loopDepth = enclosingFunctionType, enclosingLoopDepth
 	return nil
 }
 
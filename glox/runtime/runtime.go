@@ -0,0 +1,246 @@
+// Package runtime provides an embeddable glox runtime. Each Runtime holds its own interpreter state and
+// diagnostics, so several scripts can run in the same process without interfering with each other.
+package runtime
+
+import (
+	"errors"
+	"fmt"
+	"glox/compiler"
+	gloxErrors "glox/errors"
+	"glox/expr"
+	"glox/interpreter"
+	"glox/parser"
+	"glox/resolver"
+	"glox/scanner"
+	"glox/stmt"
+	"glox/types"
+	"glox/vm"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Value is any value handled by glox programs
+type Value = any
+
+// Diagnostic is an error reported while running a program
+type Diagnostic = gloxErrors.Diagnostic
+
+// ErrCompile is returned when the source could not be scanned, parsed, resolved or type checked
+var ErrCompile = errors.New("compile error")
+
+// ErrUnsupportedByVM is returned when registering a host value the vm backend can't hold
+var ErrUnsupportedByVM = errors.New("not supported by the vm backend")
+
+// Backend identifies how programs are executed
+type Backend int
+
+const (
+	// BackendTree walks the syntax tree
+	BackendTree Backend = iota
+	// BackendVM compiles the program to bytecode and runs it in a virtual machine
+	BackendVM
+)
+
+type Runtime struct {
+	reporter    *gloxErrors.Reporter
+	backend     Backend
+	interpreter interpreter.Interpreter
+	vm          *vm.VM
+}
+
+// New returns a runtime writing the program output to stdout and the reported diagnostics to stderr.
+func New(stdout io.Writer, stderr io.Writer) *Runtime {
+	reporter := gloxErrors.NewReporter(stderr)
+	r := &Runtime{reporter: reporter, interpreter: interpreter.New(reporter, stdout), vm: vm.New(stdout)}
+	r.interpreter.SetModuleLoader(r)
+	return r
+}
+
+// SetBackend sets the backend used to run programs. Each backend keeps its own globals: natives are defined in
+// both, modules and values registered with Bind only in the tree backend.
+func (r *Runtime) SetBackend(backend Backend) {
+	r.backend = backend
+}
+
+// SetStdin sets the reader the input() function reads lines from, it defaults to os.Stdin
+func (r *Runtime) SetStdin(stdin io.Reader) {
+	r.interpreter.SetStdin(stdin)
+}
+
+// RegisterModule makes a native module available to the programs run by the tree backend. It returns
+// ErrUnsupportedByVM if the vm backend is selected.
+func (r *Runtime) RegisterModule(module *interpreter.NativeModule) error {
+	if r.backend == BackendVM {
+		return fmt.Errorf("can't register module '%s': %w", module.Name, ErrUnsupportedByVM)
+	}
+	r.interpreter.RegisterModule(module)
+	return nil
+}
+
+// DefineNative defines a global function implemented in Go, available to the programs run by both backends. The
+// vm backend only passes numbers, strings, booleans and nil to it.
+func (r *Runtime) DefineNative(name string, arity int, fn func(arguments []Value) (Value, error)) {
+	r.interpreter.DefineNative(name, arity, fn)
+	r.vm.DefineNative(name, arity, fn)
+}
+
+// DefineVariadicNative works as DefineNative, the function accepts any number of arguments from minArity on
+func (r *Runtime) DefineVariadicNative(name string, minArity int, fn func(arguments []Value) (Value, error)) {
+	r.interpreter.DefineVariadicNative(name, minArity, fn)
+	r.vm.DefineVariadicNative(name, minArity, fn)
+}
+
+// Bind defines a global variable holding the provided Go value, available to the programs run by the tree
+// backend. Go functions and structs are converted to glox functions and instances, see interpreter.Bind. It
+// returns ErrUnsupportedByVM if the vm backend is selected, use DefineNative to define functions for it.
+func (r *Runtime) Bind(name string, value any) error {
+	if r.backend == BackendVM {
+		return fmt.Errorf("can't bind '%s': %w", name, ErrUnsupportedByVM)
+	}
+	return r.interpreter.Bind(name, value)
+}
+
+// SetErrorFormat sets the format used to print diagnostics
+func (r *Runtime) SetErrorFormat(format gloxErrors.Format) {
+	r.reporter.SetFormat(format)
+}
+
+// Eval runs the provided source and returns the value of its last expression statement along with the
+// diagnostics reported. Global definitions are kept between calls.
+// The returned error is ErrCompile if the source could not be compiled, or the *errors.RuntimeError
+// that aborted its execution.
+func (r *Runtime) Eval(source string) (Value, []Diagnostic, error) {
+	return r.EvalSource("<script>", source)
+}
+
+// EvalSource works as Eval, name identifies the source in diagnostics (usually, the file it was read from).
+// Modules imported by the source are looked up relative to name.
+func (r *Runtime) EvalSource(name string, source string) (Value, []Diagnostic, error) {
+	r.reporter.Reset()
+	statements, err := r.parse(name, source, false)
+	if err != nil {
+		return nil, r.reporter.Diagnostics(), err
+	}
+
+	var value Value
+	if r.backend == BackendVM {
+		value, err = r.runVM(statements)
+	} else {
+		value, err = r.interpreter.Interpret(statements)
+	}
+	if err != nil {
+		return nil, r.reporter.Diagnostics(), err
+	}
+	return value, r.reporter.Diagnostics(), nil
+}
+
+// Lint checks the provided source without running it and returns the diagnostics reported: compile errors and
+// warnings about unused variables, shadowed variables, unreachable code, values never read and calls to natives with
+// the wrong number of arguments. Warnings can be disabled with `// glox:ignore <code>...` comments. The returned
+// error is ErrCompile if the source could not be compiled.
+func (r *Runtime) Lint(name string, source string) ([]Diagnostic, error) {
+	r.reporter.Reset()
+	_, err := r.parse(name, source, true)
+	return r.reporter.Diagnostics(), err
+}
+
+// LoadModule reads, parses and resolves the module stored in the provided path. It implements
+// interpreter.ModuleLoader.
+func (r *Runtime) LoadModule(path string) ([]stmt.Stmt[any], error) {
+	source, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	// diagnostics show paths relative to the working directory when possible
+	name := path
+	if wd, err := os.Getwd(); err == nil {
+		if relative, err := filepath.Rel(wd, path); err == nil && !strings.HasPrefix(relative, "..") {
+			name = relative
+		}
+	}
+	// diagnostics without a file refer to the importing source
+	defer r.reporter.SetFile(r.reporter.File())
+	statements, err := r.parse(name, string(source), false)
+	if err != nil {
+		return nil, errors.New("the module has errors")
+	}
+	return statements, nil
+}
+
+// parse scans, parses, resolves and type checks the provided source, it returns ErrCompile if any error is found. The resolver
+// also runs the linter checks when lint is set.
+func (r *Runtime) parse(name string, source string, lint bool) ([]stmt.Stmt[any], error) {
+	r.reporter.SetSource(name, source)
+	errorsFound := errorCount(r.reporter.Diagnostics())
+
+	scanner := scanner.NewScanner(source, r.reporter)
+	scanner.ScanTokens()
+	parser := parser.NewParser[any](scanner.Tokens(), r.reporter)
+	statements, _ := parser.Parse()
+	if errorCount(r.reporter.Diagnostics()) > errorsFound {
+		return nil, ErrCompile
+	}
+	var locals resolver.Locals = &r.interpreter
+	if r.backend == BackendVM || lint {
+		// the compiler resolves variables by itself, the resolver is only needed to report errors
+		locals = noLocals{}
+	}
+	resolver := resolver.NewResolver(locals, r.reporter)
+	if lint {
+		resolver.EnableLint(r.natives())
+	}
+	if err := resolver.ResolveStatements(statements); err != nil || errorCount(r.reporter.Diagnostics()) > errorsFound {
+		return nil, ErrCompile
+	}
+	types.NewChecker(r.reporter).Check(statements)
+	if errorCount(r.reporter.Diagnostics()) > errorsFound {
+		return nil, ErrCompile
+	}
+	return statements, nil
+}
+
+// natives returns the native functions the linter checks the calls to
+func (r *Runtime) natives() map[string]resolver.Callable {
+	natives := map[string]resolver.Callable{}
+	for name, native := range r.interpreter.Natives() {
+		natives[name] = native
+	}
+	return natives
+}
+
+func errorCount(diagnostics []Diagnostic) int {
+	count := 0
+	for _, d := range diagnostics {
+		if d.Severity == gloxErrors.SeverityError {
+			count++
+		}
+	}
+	return count
+}
+
+func (r *Runtime) runVM(statements []stmt.Stmt[any]) (Value, error) {
+	function, err := compiler.Compile(statements)
+	if err != nil {
+		var compileError *compiler.CompileError
+		if errors.As(err, &compileError) {
+			r.reporter.AtToken(gloxErrors.CodeCompile, compileError.Token, compileError.Message)
+		}
+		return nil, ErrCompile
+	}
+	value, err := r.vm.Interpret(function)
+	if err != nil {
+		var runtimeError *gloxErrors.RuntimeError
+		if errors.As(err, &runtimeError) {
+			r.reporter.ReportRuntimeError(runtimeError)
+		}
+		return nil, err
+	}
+	return value, nil
+}
+
+// noLocals discards the resolved variables
+type noLocals struct{}
+
+func (noLocals) Resolve(expr.Expr[any], int, int) {}
@@ -0,0 +1,28 @@
+package runtime
+
+import (
+	"io"
+	"testing"
+)
+
+const fibSource = `
+fun fib(n) {
+  if (n < 2) return n;
+  return fib(n - 1) + fib(n - 2);
+}
+fib(20);
+`
+
+func BenchmarkFib(b *testing.B) {
+	for backendName, backend := range backends {
+		b.Run(backendName, func(b *testing.B) {
+			r := New(io.Discard, io.Discard)
+			r.SetBackend(backend)
+			for n := 0; n < b.N; n++ {
+				if _, _, err := r.Eval(fibSource); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
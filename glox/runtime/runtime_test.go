@@ -0,0 +1,1528 @@
+package runtime
+
+import (
+	"bytes"
+	"fmt"
+	"glox/errors"
+	"glox/interpreter"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEval(t *testing.T) {
+	cases := []struct {
+		source   string
+		expected Value
+		output   string
+	}{
+		{
+			source:   "1 + 2;",
+			expected: 3.0,
+		},
+		{
+			source:   "var a = \"a\"; a + \"b\";",
+			expected: "ab",
+		},
+		{
+			source: "print 1; print \"two\";",
+			output: "1\ntwo\n",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.source, func(t *testing.T) {
+			t.Parallel()
+			var stdout bytes.Buffer
+			r := New(&stdout, io.Discard)
+			value, diagnostics, err := r.Eval(tc.source)
+			require.NoError(t, err)
+			require.Empty(t, diagnostics)
+			require.Equal(t, tc.expected, value)
+			require.Equal(t, tc.output, stdout.String())
+		})
+	}
+}
+
+func TestEvalErrors(t *testing.T) {
+	r := New(io.Discard, io.Discard)
+
+	_, diagnostics, err := r.Eval("print ;")
+	require.ErrorIs(t, err, ErrCompile)
+	require.Len(t, diagnostics, 1)
+	require.Equal(t, "[line 1] Error at ';': Expect expression.", diagnostics[0].String())
+
+	_, diagnostics, err = r.Eval("print 1 - \"a\";")
+	var runtimeError *errors.RuntimeError
+	require.ErrorAs(t, err, &runtimeError)
+	require.Len(t, diagnostics, 1)
+	require.True(t, diagnostics[0].IsRuntime())
+
+	// previous errors do not leak into later evaluations
+	value, diagnostics, err := r.Eval("2 * 2;")
+	require.NoError(t, err)
+	require.Empty(t, diagnostics)
+	require.Equal(t, 4.0, value)
+}
+
+func TestEvalKeepsGlobals(t *testing.T) {
+	r := New(io.Discard, io.Discard)
+	_, _, err := r.Eval("var a = 40;")
+	require.NoError(t, err)
+	value, _, err := r.Eval("a + 2;")
+	require.NoError(t, err)
+	require.Equal(t, 42.0, value)
+}
+
+func TestEvalRendersErrorsWithTheirSource(t *testing.T) {
+	var stderr strings.Builder
+	r := New(io.Discard, &stderr)
+	_, _, err := r.EvalSource("<stdin:1>", "fun f() { return 1 - nil; }\n")
+	require.NoError(t, err)
+	_, _, err = r.EvalSource("<stdin:2>", "f();\n")
+	require.Error(t, err)
+	require.Contains(t, stderr.String(), "--> <stdin:1>:1:20\n")
+	require.Contains(t, stderr.String(), "1 | fun f() { return 1 - nil; }\n")
+}
+
+func TestRuntimesAreIndependent(t *testing.T) {
+	failing := New(io.Discard, io.Discard)
+	ok := New(io.Discard, io.Discard)
+
+	_, _, err := failing.Eval("print ;")
+	require.ErrorIs(t, err, ErrCompile)
+	_, diagnostics, err := ok.Eval("var a = 1;")
+	require.NoError(t, err)
+	require.Empty(t, diagnostics)
+
+	_, _, err = ok.Eval("a;")
+	require.NoError(t, err)
+	_, _, err = failing.Eval("a;")
+	require.Error(t, err)
+}
+
+func TestEvalRuntimeErrorTrace(t *testing.T) {
+	source := `class Point {
+  inner() {
+    return 1 - "a";
+  }
+}
+fun outer(p) {
+  return p.inner();
+}
+outer(Point());
+`
+	for backendName, backend := range backends {
+		t.Run(backendName, func(t *testing.T) {
+			r := New(io.Discard, io.Discard)
+			r.SetBackend(backend)
+			_, diagnostics, err := r.Eval(source)
+			var runtimeError *errors.RuntimeError
+			require.ErrorAs(t, err, &runtimeError)
+			require.Equal(t, []errors.StackFrame{
+				{Function: "outer", Line: 9},
+				{Function: "inner", Class: "Point", Line: 7},
+			}, runtimeError.Trace())
+			require.Len(t, diagnostics, 1)
+			expected := "Operands must be numbers.\n[line 3]\n" +
+				"Traceback (most recent call last):\n" +
+				"  [line 9] in script\n" +
+				"  [line 7] in outer()\n" +
+				"  [line 3] in Point.inner()"
+			require.Equal(t, expected, diagnostics[0].String())
+		})
+	}
+}
+
+func TestStackOverflow(t *testing.T) {
+	for backendName, backend := range backends {
+		t.Run(backendName, func(t *testing.T) {
+			_, err := runScriptWith(t, backend, "fun f(n) { return f(n + 1); } f(0);")
+			var runtimeError *errors.RuntimeError
+			require.ErrorAs(t, err, &runtimeError)
+			require.Equal(t, "Stack overflow.", runtimeError.Error())
+		})
+	}
+}
+
+func TestStackOverflowTraceback(t *testing.T) {
+	for backendName, backend := range backends {
+		t.Run(backendName, func(t *testing.T) {
+			r := New(io.Discard, io.Discard)
+			r.SetBackend(backend)
+			_, diagnostics, err := r.Eval("fun f(n) {\n  return f(n + 1);\n}\nf(0);")
+			require.Error(t, err)
+			require.Len(t, diagnostics, 1)
+			traceback := diagnostics[0].String()
+			require.Contains(t, traceback, "  [line 4] in script\n"+
+				"  [line 2] in f()\n"+
+				"  [line 2] in f()\n"+
+				"  [line 2] in f()\n"+
+				"  [Previous line repeated ")
+			require.Less(t, strings.Count(traceback, "\n"), 10)
+		})
+	}
+}
+
+// runScript evaluates the provided source and returns its output
+func runScript(t *testing.T, source string) (string, error) {
+	t.Helper()
+	return runScriptWith(t, BackendTree, source)
+}
+
+// runScriptWith evaluates the provided source using the provided backend and returns its output
+func runScriptWith(t *testing.T, backend Backend, source string) (string, error) {
+	t.Helper()
+	var stdout bytes.Buffer
+	r := New(&stdout, io.Discard)
+	r.SetBackend(backend)
+	_, _, err := r.Eval(source)
+	return stdout.String(), err
+}
+
+var backends = map[string]Backend{"tree": BackendTree, "vm": BackendVM}
+
+func TestTryCatch(t *testing.T) {
+	cases := []struct {
+		name     string
+		source   string
+		expected string
+	}{
+		{
+			name:     "thrown value",
+			source:   `try { throw "boom"; print "unreachable"; } catch (e) { print "caught " + e; }`,
+			expected: "caught boom\n",
+		},
+		{
+			name:     "runtime error",
+			source:   "try {\n print 1 - \"a\";\n} catch (e) { print e.message; print e.line; }",
+			expected: "Operands must be numbers.\n2\n",
+		},
+		{
+			name: "error from nested calls",
+			source: `fun fail() { return nil.field; }
+fun call() { fail(); print "unreachable"; }
+try { call(); } catch (e) { print e; }`,
+			expected: "Only instances have properties.\n",
+		},
+		{
+			name:     "finally",
+			source:   `try { print "body"; } finally { print "finally"; }`,
+			expected: "body\nfinally\n",
+		},
+		{
+			name: "finally with return",
+			source: `fun f() { try { return "returned"; } finally { print "finally"; } }
+print f();`,
+			expected: "finally\nreturned\n",
+		},
+		{
+			name:     "rethrow from catch",
+			source:   `try { try { throw 1; } catch (e) { throw e + 1; } finally { print "inner"; } } catch (e) { print e; }`,
+			expected: "inner\n2\n",
+		},
+		{
+			name:     "catch scope",
+			source:   `var e = "global"; try { throw "local"; } catch (e) { print e; } print e;`,
+			expected: "local\nglobal\n",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		for backendName, backend := range backends {
+			backend := backend
+			t.Run(tc.name+"/"+backendName, func(t *testing.T) {
+				t.Parallel()
+				output, err := runScriptWith(t, backend, tc.source)
+				require.NoError(t, err)
+				require.Equal(t, tc.expected, output)
+			})
+		}
+	}
+}
+
+func TestUncaughtThrow(t *testing.T) {
+	for backendName, backend := range backends {
+		t.Run(backendName, func(t *testing.T) {
+			output, err := runScriptWith(t, backend, `try { throw "boom"; } finally { print "finally"; }`)
+			require.Equal(t, "finally\n", output)
+			var runtimeError *errors.RuntimeError
+			require.ErrorAs(t, err, &runtimeError)
+			require.Equal(t, "Uncaught exception: boom", runtimeError.Error())
+			value, thrown := runtimeError.Thrown()
+			require.True(t, thrown)
+			require.Equal(t, "boom", value)
+		})
+	}
+}
+
+func TestBackendsGiveSameOutput(t *testing.T) {
+	paths, err := filepath.Glob("../examples/*.glox")
+	require.NoError(t, err)
+	require.NotEmpty(t, paths)
+
+	for _, path := range paths {
+		path := path
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			t.Parallel()
+			source, err := os.ReadFile(path)
+			require.NoError(t, err)
+			// keep the benchmark fast enough for the tests, the elapsed time is not compared
+			script := strings.ReplaceAll(string(source), "fib(40)", "fib(15)")
+			script = strings.ReplaceAll(script, "print after - before;", "")
+
+			expected, err := runScriptWith(t, BackendTree, script)
+			require.NoError(t, err)
+			output, err := runScriptWith(t, BackendVM, script)
+			require.NoError(t, err)
+			require.Equal(t, expected, output)
+		})
+	}
+}
+
+func TestVMCompileError(t *testing.T) {
+	var source strings.Builder
+	source.WriteString("fun f() {\n")
+	for i := 0; i < 300; i++ {
+		fmt.Fprintf(&source, "  var v%d;\n", i)
+	}
+	source.WriteString("}\n")
+
+	r := New(io.Discard, io.Discard)
+	r.SetBackend(BackendVM)
+	_, diagnostics, err := r.Eval(source.String())
+	require.ErrorIs(t, err, ErrCompile)
+	require.Len(t, diagnostics, 1)
+	require.Equal(t, errors.CodeCompile, diagnostics[0].Code)
+}
+
+func TestLocalVariables(t *testing.T) {
+	cases := []struct {
+		name     string
+		source   string
+		expected string
+	}{
+		{
+			name:     "shadowing",
+			source:   `var a = "global"; { var a = "outer"; { var a = "inner"; print a; } print a; } print a;`,
+			expected: "inner\nouter\nglobal\n",
+		},
+		{
+			name:     "assignment to enclosing scopes",
+			source:   `{ var a = 1; var b = 2; { var c = 3; a = a + c; b = b + a; } print a; print b; }`,
+			expected: "4\n6\n",
+		},
+		{
+			name:     "closures",
+			source:   `fun counter() { var n = 0; fun inc() { n = n + 1; return n; } return inc; } var c = counter(); c(); print c();`,
+			expected: "2\n",
+		},
+		{
+			name: "local classes",
+			source: `{
+  var name = "local";
+  class A { init(x) { this.x = x; } kind() { return A; } }
+  class B < A { init() { super.init(name); } show() { print this.x; print super.kind(); } }
+  B().show();
+}`,
+			expected: "local\nA\n",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		for backendName, backend := range backends {
+			backend := backend
+			t.Run(tc.name+"/"+backendName, func(t *testing.T) {
+				t.Parallel()
+				output, err := runScriptWith(t, backend, tc.source)
+				require.NoError(t, err)
+				require.Equal(t, tc.expected, output)
+			})
+		}
+	}
+}
+
+func TestLists(t *testing.T) {
+	cases := []struct {
+		name     string
+		source   string
+		expected string
+	}{
+		{
+			name:     "literal",
+			source:   `print [1, "two", nil, [3]]; print [];`,
+			expected: "[1, \"two\", nil, [3]]\n[]\n",
+		},
+		{
+			name:     "index",
+			source:   `var a = [1, 2, 3]; a[0] = a[1] + a[-1]; print a;`,
+			expected: "[5, 2, 3]\n",
+		},
+		{
+			name:     "methods",
+			source:   `var a = [1]; a.push(2); a.insert(0, 0); print a.len(); print a.pop(); print a.remove(0); print a;`,
+			expected: "3\n2\n0\n[1]\n",
+		},
+		{
+			name:     "insert",
+			source:   `var a = [1, 2, 3]; a.insert(-1, 7); a.insert(3, 8); a.insert(5, 9); print a;`,
+			expected: "[1, 2, 7, 8, 3, 9]\n",
+		},
+		{
+			name:     "slices",
+			source:   `var a = [1, 2, 3, 4]; print a[1:3]; print a[:1]; print a[-2:]; print a[3:1]; print a[:10];`,
+			expected: "[2, 3]\n[1]\n[3, 4]\n[]\n[1, 2, 3, 4]\n",
+		},
+		{
+			name:     "reference semantics",
+			source:   `var a = []; var b = a; b.push(1); print a; print a == b; print a[:] == a;`,
+			expected: "[1]\ntrue\nfalse\n",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			output, err := runScript(t, tc.source)
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, output)
+		})
+	}
+}
+
+func TestListErrors(t *testing.T) {
+	cases := map[string]string{
+		"[1][1];":           "List index out of range.",
+		"[1][0.5];":         "List index must be an integer.",
+		"[].pop();":         "Can't pop from an empty list.",
+		"[].insert(1, 1);":  "List index out of range.",
+		"[].insert(-1, 1);": "List index out of range.",
+		"1[0];":             "Only lists and maps can be indexed.",
+		"nil[0:1];":         "Only lists can be sliced.",
+		"[].size;":          "Undefined property 'size'.",
+	}
+	for source, expected := range cases {
+		_, err := runScript(t, source)
+		var runtimeError *errors.RuntimeError
+		require.ErrorAs(t, err, &runtimeError, source)
+		require.Equal(t, expected, runtimeError.Error(), source)
+	}
+
+	_, err := runScriptWith(t, BackendVM, "[1];")
+	require.ErrorIs(t, err, ErrCompile)
+}
+
+func TestMaps(t *testing.T) {
+	cases := []struct {
+		name     string
+		source   string
+		expected string
+	}{
+		{
+			name:     "literal",
+			source:   `print {"a": 1, 2: "two", true: nil, nil: [],}; print {};`,
+			expected: "{\"a\": 1, 2: \"two\", true: nil, nil: []}\n{}\n",
+		},
+		{
+			name:     "index",
+			source:   `var m = {"a": 1}; m["b"] = m["a"] + 1; m["a"] = 0; print m;`,
+			expected: "{\"a\": 0, \"b\": 2}\n",
+		},
+		{
+			name:     "nil keys",
+			source:   `var m = {}; var unset; m[unset] = 1; m[nil] = 2; print m; print m.len();`,
+			expected: "{nil: 2}\n1\n",
+		},
+		{
+			name: "methods",
+			source: `var m = {"a": 1, "b": 2};
+print m.keys(); print m.values(); print m.has("a"); print m.delete("a"); print m.delete("a"); print m.has("a"); print m;`,
+			expected: "[\"a\", \"b\"]\n[1, 2]\ntrue\ntrue\nfalse\nfalse\n{\"b\": 2}\n",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			output, err := runScript(t, tc.source)
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, output)
+		})
+	}
+}
+
+func TestMapErrors(t *testing.T) {
+	cases := map[string]string{
+		`print {"a": 1}["b"];`:   `Undefined key "b".`,
+		`var m = {}; m[[]] = 1;`: "Map keys must be strings, numbers, booleans or nil.",
+		`print {{}: 1};`:         "Map keys must be strings, numbers, booleans or nil.",
+		`print {}.has(clock);`:   "Map keys must be strings, numbers, booleans or nil.",
+		`print {}.size;`:         "Undefined property 'size'.",
+	}
+	for source, expected := range cases {
+		_, err := runScript(t, source)
+		var runtimeError *errors.RuntimeError
+		require.ErrorAs(t, err, &runtimeError, source)
+		require.Equal(t, expected, runtimeError.Error(), source)
+	}
+}
+
+// writeFiles writes the provided files, by relative path, into a temporary directory and returns it
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	}
+	return dir
+}
+
+func TestImport(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"lib/counter.glox": `import "helper.glox" as h;
+print "loading";
+var start = 10;
+var _count = 0;
+fun next() { _count = _count + 1; return start + _count; }
+class Box { init(value) { this.value = value; } }
+fun helper() { return h.name; }`,
+		"lib/helper.glox": `var name = "helper";`,
+	})
+
+	var stdout bytes.Buffer
+	r := New(&stdout, io.Discard)
+	_, diagnostics, err := r.EvalSource(filepath.Join(dir, "main.glox"), "import \"lib/counter.glox\" as c;\n"+
+		"import \"lib/counter.glox\" as again;\nprint c;\nprint c.next();\nprint again.next();\n"+
+		"print c.Box(c.start).value;\nprint c.helper();")
+	require.NoError(t, err)
+	require.Empty(t, diagnostics)
+	// the module is executed once and its globals are shared by every import
+	require.Equal(t, "loading\n<module c>\n11\n12\n10\nhelper\n", stdout.String())
+}
+
+func TestImportErrors(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"a.glox":       `import "b.glox" as b;`,
+		"b.glox":       `import "a.glox" as a;`,
+		"invalid.glox": `var = 1;`,
+		"failing.glox": "fun fail() {\n  return 1 - nil;\n}",
+		"private.glox": `var _secret = 1;`,
+	})
+
+	cases := []struct {
+		name     string
+		source   string
+		expected string
+	}{
+		{
+			name:     "cycle",
+			source:   `import "a.glox" as a;`,
+			expected: "Import cycle: a.glox -> b.glox -> a.glox.",
+		},
+		{
+			name:     "missing module",
+			source:   `import "missing.glox" as m;`,
+			expected: "Could not import module 'missing.glox'",
+		},
+		{
+			name:     "invalid module",
+			source:   `import "invalid.glox" as m;`,
+			expected: "Could not import module 'invalid.glox': the module has errors.",
+		},
+		{
+			name:     "private name",
+			source:   `import "private.glox" as p; p._secret;`,
+			expected: "Module 'p' does not export '_secret'.",
+		},
+		{
+			name:     "native",
+			source:   `import "private.glox" as p; p.clock;`,
+			expected: "Module 'p' does not export 'clock'.",
+		},
+		{
+			name:     "host global",
+			source:   `import "private.glox" as p; p.math;`,
+			expected: "Module 'p' does not export 'math'.",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			r := New(io.Discard, io.Discard)
+			_, _, err := r.EvalSource(filepath.Join(dir, "main.glox"), tc.source)
+			var runtimeError *errors.RuntimeError
+			require.ErrorAs(t, err, &runtimeError)
+			require.Contains(t, runtimeError.Error(), tc.expected)
+		})
+	}
+
+	// errors in module functions refer to the module file
+	r := New(io.Discard, io.Discard)
+	_, diagnostics, err := r.EvalSource(filepath.Join(dir, "main.glox"), "import \"failing.glox\" as f;\nf.fail();")
+	require.Error(t, err)
+	require.Len(t, diagnostics, 1)
+	require.Equal(t, 2, diagnostics[0].Line)
+	require.True(t, strings.HasSuffix(diagnostics[0].File, "failing.glox"))
+}
+
+func TestStdlib(t *testing.T) {
+	cases := map[string]string{
+		`print string.len("héllo");`:                                                   "5",
+		`print string.substr("hello", 1, 3);`:                                          "el",
+		`print string.substr("hello", -3, 10);`:                                        "llo",
+		`print string.split("a,b,,c", ",");`:                                           `["a", "b", "", "c"]`,
+		`print string.upper("abc") + string.lower("DEF");`:                             "ABCdef",
+		`print string.indexOf("héllo", "l");`:                                          "2",
+		`print string.indexOf("hello", "x");`:                                          "-1",
+		`print string.replace("a-b-c", "-", "+");`:                                     "a+b+c",
+		`print math.sqrt(16);`:                                                         "4",
+		`print math.floor(2.7);`:                                                       "2",
+		`print math.pow(2, 10);`:                                                       "1024",
+		`print math.min(1, 2) + math.max(1, 2);`:                                       "3",
+		`math.seed(1); var a = math.random(); math.seed(1); print a == math.random();`: "true",
+		`print type(nil) + type(true) + type(1) + type("");`:                           "nilbooleannumberstring",
+		`class A {} print type(A) + type(A()) + type(clock) + type([]) + type(math);`:  "classinstancefunctionlistmodule",
+		`print str(1) + str([1, "a"]);`:                                                `1[1, "a"]`,
+		`print num(" 2.5 ") + num(1);`:                                                 "3.5",
+		`print math;`:                                                                  "<module math>",
+	}
+	for source, expected := range cases {
+		output, err := runScript(t, source)
+		require.NoError(t, err, source)
+		require.Equal(t, expected+"\n", output, source)
+	}
+}
+
+func TestStdlibErrors(t *testing.T) {
+	cases := map[string]string{
+		`string.len(1);`:        "Argument 1 of 'string.len' must be a string.",
+		`string.split("a", 1);`: "Argument 2 of 'string.split' must be a string.",
+		`math.sqrt("4");`:       "Argument 1 of 'math.sqrt' must be a number.",
+		`num("abc");`:           `Can't convert "abc" to a number.`,
+		`math.tau;`:             "Module 'math' does not export 'tau'.",
+		`string.len("a", "b");`: "Expected 1 arguments but got 2.",
+	}
+	for source, expected := range cases {
+		_, err := runScript(t, source)
+		var runtimeError *errors.RuntimeError
+		require.ErrorAs(t, err, &runtimeError, source)
+		require.Equal(t, expected, runtimeError.Error(), source)
+	}
+}
+
+func TestInput(t *testing.T) {
+	var stdout bytes.Buffer
+	r := New(&stdout, io.Discard)
+	r.SetStdin(strings.NewReader("first\nsecond"))
+	_, _, err := r.Eval(`print input(); print input(); print input();`)
+	require.NoError(t, err)
+	require.Equal(t, "first\nsecond\nnil\n", stdout.String())
+}
+
+func TestRegisterModule(t *testing.T) {
+	var stdout bytes.Buffer
+	r := New(&stdout, io.Discard)
+	greetings := interpreter.NewNativeModule("greetings")
+	greetings.Define("hello", 1, func(_ *interpreter.Interpreter, arguments []any) (any, error) {
+		return "hello " + arguments[0].(string), nil
+	})
+	require.NoError(t, r.RegisterModule(greetings))
+	_, _, err := r.Eval(`print greetings.hello("world");`)
+	require.NoError(t, err)
+	require.Equal(t, "hello world\n", stdout.String())
+
+	r.SetBackend(BackendVM)
+	require.ErrorIs(t, r.RegisterModule(greetings), ErrUnsupportedByVM)
+	require.ErrorIs(t, r.Bind("p", &point{}), ErrUnsupportedByVM)
+}
+
+func TestDefineNative(t *testing.T) {
+	for backendName, backend := range backends {
+		t.Run(backendName, func(t *testing.T) {
+			var stdout bytes.Buffer
+			r := New(&stdout, io.Discard)
+			r.SetBackend(backend)
+			r.DefineNative("double", 1, func(arguments []Value) (Value, error) {
+				n, isNumber := arguments[0].(float64)
+				if !isNumber {
+					return nil, fmt.Errorf("Expected a number.")
+				}
+				return n * 2, nil
+			})
+			r.DefineVariadicNative("count", 1, func(arguments []Value) (Value, error) {
+				return float64(len(arguments)), nil
+			})
+			_, _, err := r.Eval(`print double(21); print count(1, 2, 3);`)
+			require.NoError(t, err)
+			require.Equal(t, "42\n3\n", stdout.String())
+
+			errorCases := map[string]string{
+				`double("a");`: "Expected a number.",
+				`count();`:     "Expected at least 1 arguments but got 0.",
+			}
+			for source, expected := range errorCases {
+				_, _, err = r.Eval(source)
+				var runtimeError *errors.RuntimeError
+				require.ErrorAs(t, err, &runtimeError, source)
+				require.Equal(t, expected, runtimeError.Error(), source)
+			}
+		})
+	}
+}
+
+type point struct {
+	X, Y  float64
+	Label string
+}
+
+func (p *point) Move(dx, dy float64) {
+	p.X += dx
+	p.Y += dy
+}
+
+func (p point) Describe() string {
+	return fmt.Sprintf("%s(%v, %v)", p.Label, p.X, p.Y)
+}
+
+func TestBind(t *testing.T) {
+	p := &point{X: 1, Y: 2, Label: "p"}
+	var stdout bytes.Buffer
+	r := New(&stdout, io.Discard)
+	require.NoError(t, r.Bind("p", p))
+	require.NoError(t, r.Bind("join", strings.Join))
+	require.NoError(t, r.Bind("origin", func() point { return point{Label: "origin"} }))
+	require.NoError(t, r.Bind("norm", func(p point) float64 { return p.X*p.X + p.Y*p.Y }))
+	require.NoError(t, r.Bind("check", func(ok bool) error {
+		if !ok {
+			return fmt.Errorf("Check failed.")
+		}
+		return nil
+	}))
+	require.NoError(t, r.Bind("small", func(x int8) int8 { return x }))
+	require.NoError(t, r.Bind("unsigned", func(x uint) uint { return x }))
+
+	cases := map[string]string{
+		`print p.x + p.Y;`:             "3",
+		`print p.describe();`:          "p(1, 2)",
+		`print join(["a", "b"], "-");`: "a-b",
+		`print origin();`:              "point instance",
+		`print origin().label;`:        "origin",
+		`class V { init(x, y) { this.x = x; this.y = y; } } print norm(V(3, 4));`: "25",
+		`print norm(p);`:     "5",
+		`print check(true);`: "nil",
+		`print type(p);`:     "instance",
+		`print small(-128);`: "-128",
+		`print unsigned(7);`: "7",
+	}
+	for source, expected := range cases {
+		stdout.Reset()
+		_, _, err := r.Eval(source)
+		require.NoError(t, err, source)
+		require.Equal(t, expected+"\n", stdout.String(), source)
+	}
+
+	_, _, err := r.Eval(`p.move(1, 1); p.label = "q";`)
+	require.NoError(t, err)
+	require.Equal(t, point{X: 2, Y: 3, Label: "q"}, *p)
+
+	errorCases := map[string]string{
+		`check(false);`:   "Check failed.",
+		`join("a", "-");`: "Argument 1 of 'join' must be a list.",
+		`p.move("a", 1);`: "Argument 1 of 'move' must be a number.",
+		`p.label = 1;`:    "Property 'label' must be a string.",
+		`p.z;`:            "Undefined property 'z'.",
+		`norm(1);`:        "Argument 1 of 'norm' must be a runtime.point instance.",
+		`small(300);`:     "Argument 1 of 'small' must be an integer.",
+		`small(-129);`:    "Argument 1 of 'small' must be an integer.",
+		`unsigned(-1);`:   "Argument 1 of 'unsigned' must be an integer.",
+		`unsigned(1e20);`: "Argument 1 of 'unsigned' must be an integer.",
+	}
+	for source, expected := range errorCases {
+		_, _, err := r.Eval(source)
+		var runtimeError *errors.RuntimeError
+		require.ErrorAs(t, err, &runtimeError, source)
+		require.Equal(t, expected, runtimeError.Error(), source)
+	}
+
+	require.NoError(t, r.Bind("sprintf", fmt.Sprintf))
+	stdout.Reset()
+	_, _, err = r.Eval(`print sprintf("%s-%v", "a", 1);`)
+	require.NoError(t, err)
+	require.Equal(t, "a-1\n", stdout.String())
+
+	require.Error(t, r.Bind("pair", func() (int, int) { return 1, 2 }))
+}
+
+func TestParameters(t *testing.T) {
+	cases := map[string]string{
+		`fun f(a, b = 2) { print a + b; } f(1); f(1, 3);`:                  "3\n4",
+		`fun f(a, b = a * 2) { print b; } f(2);`:                           "4",
+		`var n = 0; fun f(a = n) { print a; } n = 1; f();`:                 "1",
+		`fun f(a, ...rest) { print rest; } f(1); f(1, 2, 3);`:              "[]\n[2, 3]",
+		`fun f(a = 1, ...rest) { print a; print rest.len(); } f();`:        "1\n0",
+		`fun f(x) { fun g(y = x) { return y; } return g; } print f(5)();`:  "5",
+		`class A { init(a, b = "b") { this.v = a + b; } } print A("a").v;`: "ab",
+		`print math.max(3, 1, 7, 2);`:                                      "7",
+	}
+	for source, expected := range cases {
+		output, err := runScript(t, source)
+		require.NoError(t, err, source)
+		require.Equal(t, expected+"\n", output, source)
+	}
+
+	errorCases := map[string]string{
+		`fun f(a, b = 2) {} f();`:        "Expected 1 to 2 arguments but got 0.",
+		`fun f(a, b = 2) {} f(1, 2, 3);`: "Expected 1 to 2 arguments but got 3.",
+		`fun f(a, ...rest) {} f();`:      "Expected at least 1 arguments but got 0.",
+		`math.min();`:                    "Expected at least 1 arguments but got 0.",
+		`fun f(a = 1 - "a") {} f();`:     "Operands must be numbers.",
+	}
+	for source, expected := range errorCases {
+		_, err := runScript(t, source)
+		var runtimeError *errors.RuntimeError
+		require.ErrorAs(t, err, &runtimeError, source)
+		require.Equal(t, expected, runtimeError.Error(), source)
+	}
+
+	_, err := runScriptWith(t, BackendVM, `fun f(a = 1) {}`)
+	require.ErrorIs(t, err, ErrCompile)
+}
+
+func TestLambdas(t *testing.T) {
+	cases := map[string]string{
+		`var add = fun (a, b) { return a + b; }; print add(1, 2);`:                                "3",
+		`fun apply(f, x) { return f(x); } print apply(x => x * 2, 21);`:                           "42",
+		`var add = (a, b) => a + b; print add(1, 2);`:                                             "3",
+		`var f = () => "constant"; print f();`:                                                    "constant",
+		`fun counter() { var n = 0; return () => n = n + 1; } var c = counter(); c(); print c();`: "2",
+		`fun (x) { print x; }("called");`:                                                         "called",
+		`print (x => x + 1)(1);`:                                                                  "2",
+		`print fun () {};`:                                                                        "<anonymous fn>",
+		`print (1 + 2) * 3;`:                                                                      "9",
+	}
+	for name, backend := range backends {
+		for source, expected := range cases {
+			output, err := runScriptWith(t, backend, source)
+			require.NoError(t, err, name+": "+source)
+			require.Equal(t, expected+"\n", output, name+": "+source)
+		}
+	}
+}
+
+func TestLambdaStackTrace(t *testing.T) {
+	for name, backend := range backends {
+		r := New(io.Discard, io.Discard)
+		r.SetBackend(backend)
+		_, _, err := r.Eval("var f = fun () {\n  return 1 - nil;\n};\nf();")
+		var runtimeError *errors.RuntimeError
+		require.ErrorAs(t, err, &runtimeError, name)
+		require.Equal(t, "<anonymous>()", runtimeError.Trace()[0].String(), name)
+	}
+}
+
+func TestBreakContinue(t *testing.T) {
+	cases := []struct {
+		name     string
+		source   string
+		expected string
+	}{
+		{
+			name:     "break",
+			source:   `var i = 0; while (true) { if (i == 3) break; print i; i = i + 1; }`,
+			expected: "0\n1\n2\n",
+		},
+		{
+			name:     "continue runs the increment",
+			source:   `for (var i = 0; i < 5; i = i + 1) { if (i == 1 or i == 3) continue; print i; }`,
+			expected: "0\n2\n4\n",
+		},
+		{
+			name:     "nested loops",
+			source:   `for (var i = 0; i < 2; i = i + 1) { for (var j = 0; j < 5; j = j + 1) { if (j == 2) break; print i + j; } }`,
+			expected: "0\n1\n1\n2\n",
+		},
+		{
+			name:     "locals in the loop body",
+			source:   `for (var i = 0; i < 3; i = i + 1) { var a = i; fun f() { return a; } if (i == 1) continue; print f(); } print "done";`,
+			expected: "0\n2\ndone\n",
+		},
+		{
+			name:     "finally runs",
+			source:   `while (true) { try { break; } finally { print "finally"; } } print "after";`,
+			expected: "finally\nafter\n",
+		},
+		{
+			name:     "loop in a function",
+			source:   `fun first(n) { var i = 0; while (true) { var x = i * 2; if (x >= n) break; i = i + 1; } return i; } print first(7);`,
+			expected: "4\n",
+		},
+	}
+	for name, backend := range backends {
+		for _, tc := range cases {
+			output, err := runScriptWith(t, backend, tc.source)
+			require.NoError(t, err, name+": "+tc.name)
+			require.Equal(t, tc.expected, output, name+": "+tc.name)
+		}
+	}
+}
+
+func TestBreakOutsideLoop(t *testing.T) {
+	cases := map[string]string{
+		`break;`:                              "Can't use 'break' outside of a loop.",
+		`if (true) continue;`:                 "Can't use 'continue' outside of a loop.",
+		`while (true) { fun f() { break; } }`: "Can't use 'break' outside of a loop.",
+	}
+	for source, expected := range cases {
+		r := New(io.Discard, io.Discard)
+		_, diagnostics, err := r.Eval(source)
+		require.ErrorIs(t, err, ErrCompile, source)
+		require.Equal(t, expected, diagnostics[0].Message, source)
+	}
+}
+
+func TestClassMembers(t *testing.T) {
+	cases := []struct {
+		name     string
+		source   string
+		expected string
+	}{
+		{
+			name:     "class method",
+			source:   `class Math { class square(n) { return n * n; } } print Math.square(3);`,
+			expected: "9\n",
+		},
+		{
+			name:     "class method using this",
+			source:   `class Point { init(x) { this.x = x; } class origin() { return this(0); } } print Point.origin().x;`,
+			expected: "0\n",
+		},
+		{
+			name:     "inherited class method and super",
+			source:   `class A { class name() { return "A"; } } class B < A { class name() { return "B" + super.name(); } } print B.name();`,
+			expected: "BA\n",
+		},
+		{
+			name:     "class fields",
+			source:   `class Counter { class next() { this.count = this.count + 1; return this.count; } } Counter.count = 0; Counter.next(); print Counter.next();`,
+			expected: "2\n",
+		},
+		{
+			name:     "getter",
+			source:   `class Circle { init(r) { this.r = r; } area { return 3 * this.r * this.r; } } print Circle(2).area;`,
+			expected: "12\n",
+		},
+		{
+			name:     "inherited getter and super",
+			source:   `class A { name { return "A"; } } class B < A { name { return "B" + super.name; } } print B().name;`,
+			expected: "BA\n",
+		},
+		{
+			name:     "setter",
+			source:   `class Temp { set celsius(c) { this.f = c * 9 / 5 + 32; } } var t = Temp(); t.celsius = 100; print t.f;`,
+			expected: "212\n",
+		},
+		{
+			name:     "getter and setter",
+			source:   `class P { name { return this._name; } set name(n) { this._name = "<" + n + ">"; } } var p = P(); print p.name = "x"; print p.name;`,
+			expected: "x\n<x>\n",
+		},
+		{
+			name:     "method named set",
+			source:   `class M { set(k) { return k; } } print M().set(1);`,
+			expected: "1\n",
+		},
+		{
+			name:     "setter writing the property field",
+			source:   `class P { set x(v) { this.x = v * 2; } } var p = P(); p.x = 2; print p.x; p.x = 3; print p.x;`,
+			expected: "4\n6\n",
+		},
+		{
+			name:     "getter and setter backed by the property field",
+			source:   `class P { x { return this.x + 1; } set x(v) { this.x = v; } } var p = P(); p.x = 1; print p.x; p.x += 1; print p.x;`,
+			expected: "2\n4\n",
+		},
+		{
+			name:     "method overriding a getter",
+			source:   `class A { x { return "getter"; } } class B < A { x() { return "method"; } } print B().x();`,
+			expected: "method\n",
+		},
+	}
+	for _, tc := range cases {
+		output, err := runScript(t, tc.source)
+		require.NoError(t, err, tc.name)
+		require.Equal(t, tc.expected, output, tc.name)
+	}
+
+	_, err := runScript(t, `class A { value { return nil.x; } } A().value;`)
+	var runtimeError *errors.RuntimeError
+	require.ErrorAs(t, err, &runtimeError)
+	require.Equal(t, "A.value()", runtimeError.Trace()[0].String())
+
+	_, err = runScript(t, `class A { x { return 1; } } var a = A(); a.x = 5;`)
+	require.ErrorAs(t, err, &runtimeError)
+	require.Equal(t, "Can't assign to property 'x', it has a getter and no setter.", runtimeError.Error())
+
+	_, err = runScriptWith(t, BackendVM, `class A { class f() {} }`)
+	require.ErrorIs(t, err, ErrCompile)
+}
+
+func TestTraits(t *testing.T) {
+	cases := []struct {
+		name     string
+		source   string
+		expected string
+	}{
+		{
+			name:     "trait methods",
+			source:   `trait Greets { greet() { return "hi " + this.name; } } class P with Greets { init(n) { this.name = n; } } print P("bob").greet();`,
+			expected: "hi bob\n",
+		},
+		{
+			name:     "several traits and a superclass",
+			source:   `trait A { a() { return "a"; } } trait B { b() { return "b"; } } class Base { base() { return "base"; } } class C < Base with A, B { all() { return this.a() + this.b() + super.base(); } } print C().all();`,
+			expected: "abbase\n",
+		},
+		{
+			name:     "inherited trait methods",
+			source:   `trait T { t() { return "t"; } } class A with T {} class B < A { t() { return "b" + super.t(); } } print B().t();`,
+			expected: "bt\n",
+		},
+		{
+			name:     "trait value",
+			source:   `trait T {} print T; print type(T);`,
+			expected: "T\ntrait\n",
+		},
+		{
+			name:     "trait shadowed in a block",
+			source:   `trait T { a() { return "a"; } } { trait T { b() {} } } class C with T { b() { return "b"; } } print C().a() + C().b();`,
+			expected: "ab\n",
+		},
+		{
+			name:     "with is only a keyword in class declarations",
+			source:   `var with = "w"; print with;`,
+			expected: "w\n",
+		},
+	}
+	for _, tc := range cases {
+		output, err := runScript(t, tc.source)
+		require.NoError(t, err, tc.name)
+		require.Equal(t, tc.expected, output, tc.name)
+	}
+
+	resolveErrors := map[string]string{
+		`trait A { m() {} } trait B { m() {} } class C with A, B {}`: "Trait 'B' redefines method 'm' of trait 'A'.",
+		`trait A { m() {} } class C with A { m() {} }`:               "Trait 'A' redefines method 'm' of class 'C'.",
+		`{ trait A { m() {} } class C with A { m() {} } }`:           "Trait 'A' redefines method 'm' of class 'C'.",
+		`trait A { m() { super.m(); } }`:                             "Can't use 'super' in a trait.",
+		`trait A { init() {} }`:                                      "Can't define an initializer in a trait.",
+	}
+	for source, expected := range resolveErrors {
+		r := New(io.Discard, io.Discard)
+		_, diagnostics, err := r.Eval(source)
+		require.ErrorIs(t, err, ErrCompile, source)
+		require.Equal(t, expected, diagnostics[0].Message, source)
+	}
+
+	runtimeErrors := map[string]string{
+		`class A {} class B with A {}`:                                          "'A' is not a trait.",
+		`trait A { m() {} } var T = A; trait B { m() {} } class C with T, B {}`: "Trait 'B' redefines method 'm' of trait 'A'.",
+	}
+	for source, expected := range runtimeErrors {
+		_, err := runScript(t, source)
+		var runtimeError *errors.RuntimeError
+		require.ErrorAs(t, err, &runtimeError, source)
+		require.Equal(t, expected, runtimeError.Error(), source)
+	}
+}
+
+func TestOperatorOverloading(t *testing.T) {
+	vector := `class Vec {
+  init(x, y) { this.x = x; this.y = y; }
+  __add__(other) { return Vec(this.x + other.x, this.y + other.y); }
+  __sub__(other) { return Vec(this.x - other.x, this.y - other.y); }
+  __mul__(k) { return Vec(this.x * k, this.y * k); }
+  __div__(k) { return Vec(this.x / k, this.y / k); }
+  __eq__(other) { return this.x == other.x and this.y == other.y; }
+  __lt__(other) { return this.x < other.x; }
+  __le__(other) { return this.x <= other.x; }
+  __gt__(other) { return this.x > other.x; }
+  __ge__(other) { return this.x >= other.x; }
+  show() { print this.x; print this.y; }
+}
+`
+	cases := map[string]string{
+		`(Vec(1, 2) + Vec(3, 4)).show();`:                       "4\n6",
+		`(Vec(1, 2) - Vec(3, 4)).show();`:                       "-2\n-2",
+		`(Vec(1, 2) * 3 / 2).show();`:                           "1.5\n3",
+		`print Vec(1, 2) == Vec(1, 2);`:                         "true",
+		`print Vec(1, 2) != Vec(1, 2);`:                         "false",
+		`print Vec(1, 2) != Vec(2, 2);`:                         "true",
+		`print Vec(1, 0) < Vec(2, 0);`:                          "true",
+		`print Vec(1, 0) >= Vec(2, 0);`:                         "false",
+		`class A {} var a = A(); print a == a; print a == A();`: "true\nfalse",
+	}
+	for name, backend := range backends {
+		for source, expected := range cases {
+			output, err := runScriptWith(t, backend, vector+source)
+			require.NoError(t, err, name+": "+source)
+			require.Equal(t, expected+"\n", output, name+": "+source)
+		}
+	}
+
+	_, err := runScript(t, `class A {} A() + 1;`)
+	var runtimeError *errors.RuntimeError
+	require.ErrorAs(t, err, &runtimeError)
+	require.Equal(t, "Operands must be two numbers or two strings.", runtimeError.Error())
+}
+
+func TestEquality(t *testing.T) {
+	cases := map[string]string{
+		`var a; print a == nil;`:       "true",
+		`print nil == nil;`:            "true",
+		`print nil != false;`:          "true",
+		`print 1 == 1 and "a" == "a";`: "true",
+		`print clock == clock;`:        "true",
+	}
+	for name, backend := range backends {
+		for source, expected := range cases {
+			output, err := runScriptWith(t, backend, source)
+			require.NoError(t, err, name+": "+source)
+			require.Equal(t, expected+"\n", output, name+": "+source)
+		}
+	}
+}
+
+func TestStringInterpolation(t *testing.T) {
+	cases := []struct {
+		name     string
+		source   string
+		expected string
+	}{
+		{
+			name:     "escapes",
+			source:   `print "a\tb\n\"c\" \u{e9} \${x}";`,
+			expected: "a\tb\n\"c\" é ${x}\n",
+		},
+		{
+			name:     "variables and expressions",
+			source:   `var name = "world"; print "Hello ${name}! ${1 + 2} ${nil} ${true}";`,
+			expected: "Hello world! 3 nil true\n",
+		},
+		{
+			name:     "nested strings",
+			source:   `var a = "x"; print "${"<${a}>"}${a}";`,
+			expected: "<x>x\n",
+		},
+		{
+			name:     "locals and closures",
+			source:   `fun greet(name) { fun inner() { return "Hi ${name}"; } return inner(); } print greet("Bob");`,
+			expected: "Hi Bob\n",
+		},
+	}
+	for name, backend := range backends {
+		for _, tc := range cases {
+			output, err := runScriptWith(t, backend, tc.source)
+			require.NoError(t, err, name+": "+tc.name)
+			require.Equal(t, tc.expected, output, name+": "+tc.name)
+		}
+	}
+
+	errorCases := map[string]string{
+		`print "${1 2}";`: "Expect '}' after interpolated expression.",
+		`print "${}";`:    "Expect expression.",
+		`print "\x";`:     `Invalid escape sequence '\x'.`,
+	}
+	for source, expected := range errorCases {
+		r := New(io.Discard, io.Discard)
+		_, diagnostics, err := r.Eval(source)
+		require.ErrorIs(t, err, ErrCompile, source)
+		require.Equal(t, expected, diagnostics[0].Message, source)
+	}
+}
+
+func TestNumbers(t *testing.T) {
+	cases := []struct {
+		source   string
+		expected string
+	}{
+		{source: `print 0xFF + 0b1010;`, expected: "265\n"},
+		{source: `print 1_000_000;`, expected: "1000000\n"},
+		{source: `print 1e9;`, expected: "1000000000\n"},
+		{source: `print 2.5e-3;`, expected: "0.0025\n"},
+		{source: `print 1e21;`, expected: "1e+21\n"},
+		{source: `print 1e-7;`, expected: "1e-07\n"},
+		{source: `print 7 % 3; print -7 % 3; print 7.5 % 2;`, expected: "1\n-1\n1.5\n"},
+		{source: `print 7 ~/ 2; print -7 ~/ 2;`, expected: "3\n-3\n"},
+		{source: `print 8 / 2 * 2; print 2 * 7 % 4;`, expected: "8\n2\n"},
+		{source: `class N { init(v) { this.v = v; } __mod__(o) { return this.v - o; } } print N(5) % 2;`, expected: "3\n"},
+	}
+	for name, backend := range backends {
+		for _, tc := range cases {
+			output, err := runScriptWith(t, backend, tc.source)
+			require.NoError(t, err, name+": "+tc.source)
+			require.Equal(t, tc.expected, output, name+": "+tc.source)
+		}
+	}
+
+	for name, backend := range backends {
+		for _, source := range []string{`print 1 % 0;`, `print 1 ~/ 0;`} {
+			_, err := runScriptWith(t, backend, source)
+			require.ErrorContains(t, err, "Cannot divide by zero.", name+": "+source)
+		}
+	}
+	// invalid literals are reported once, the parser doesn't complain about the missing operand
+	_, diagnostics, err := New(io.Discard, io.Discard).Eval(`print 1__0 + 0x;`)
+	require.ErrorIs(t, err, ErrCompile)
+	require.Len(t, diagnostics, 2)
+	require.Equal(t, "Digit separators must be between digits.", diagnostics[0].Message)
+	require.Equal(t, "Expect hexadecimal digits after '0x'.", diagnostics[1].Message)
+}
+
+func TestCompoundAssignment(t *testing.T) {
+	cases := []struct {
+		name     string
+		source   string
+		expected string
+	}{
+		{
+			name:     "variables",
+			source:   `var i = 1; i += 2; print i; i -= 1; print i; i *= 5; print i; i /= 2; print i; i %= 3; print i; var s = "a"; s += "b"; print s;`,
+			expected: "3\n2\n10\n5\n2\nab\n",
+		},
+		{
+			name:     "increments",
+			source:   `var i = 0; print i++; print i; print ++i; print i--; print --i;`,
+			expected: "0\n1\n2\n2\n0\n",
+		},
+		{
+			name:     "locals and upvalues",
+			source:   `fun counter() { var n = 0; fun next() { n += 1; return n; } next(); return next; } var c = counter(); c(); print c();`,
+			expected: "3\n",
+		},
+		{
+			name:     "for loop increment",
+			source:   `for (var i = 0; i < 3; i++) print i;`,
+			expected: "0\n1\n2\n",
+		},
+		{
+			name: "properties evaluate the object once",
+			source: `class P { init() { this.x = 1; } } var p = P(); var calls = 0; fun get() { calls++; return p; }
+				get().x += 10; print p.x; print get().x++; print p.x; print --get().x; print calls;`,
+			expected: "11\n11\n12\n11\n3\n",
+		},
+	}
+	for name, backend := range backends {
+		for _, tc := range cases {
+			output, err := runScriptWith(t, backend, tc.source)
+			require.NoError(t, err, name+": "+tc.name)
+			require.Equal(t, tc.expected, output, name+": "+tc.name)
+		}
+	}
+
+	// lists and maps are only supported by the tree backend
+	indexCases := map[string]string{
+		`var l = [1, 2]; l[0] += 10; l[-1] %= 2; print l;`:                                                                             "[11, 0]\n",
+		`var l = [1]; print l[0]++; print ++l[0]; print l;`:                                                                            "1\n3\n[3]\n",
+		`var counts = {"a": 0}; var keys = ["a", "a"]; for (var i = 0; i < keys.len(); i++) counts[keys[i]] += 1; print counts;`:       "{\"a\": 2}\n",
+		`var l = [[1]]; var calls = 0; fun get() { calls++; return l[0]; } var i = 0; get()[i++] *= 5; print l; print calls; print i;`: "[[5]]\n1\n1\n",
+	}
+	for source, expected := range indexCases {
+		output, err := runScript(t, source)
+		require.NoError(t, err, source)
+		require.Equal(t, expected, output, source)
+	}
+
+	errorCases := map[string]string{
+		`1 += 2;`:              "Invalid assignment target.",
+		`var a; (a)++;`:        "Invalid assignment target.",
+		`fun a() {} a() -= 1;`: "Invalid assignment target.",
+	}
+	for source, expected := range errorCases {
+		r := New(io.Discard, io.Discard)
+		_, diagnostics, err := r.Eval(source)
+		require.ErrorIs(t, err, ErrCompile, source)
+		require.Equal(t, expected, diagnostics[0].Message, source)
+	}
+}
+
+func TestConditionalExpressions(t *testing.T) {
+	cases := []struct {
+		name     string
+		source   string
+		expected string
+	}{
+		{
+			name:     "conditional",
+			source:   `print 1 < 2 ? "yes" : "no"; print false ? 1 : nil ? 2 : 3; var a = true ? "t" : "f"; print a;`,
+			expected: "yes\n3\nt\n",
+		},
+		{
+			name:     "only the taken branch is evaluated",
+			source:   `fun f(v) { print v; return v; } print true ? f(1) : f(2);`,
+			expected: "1\n1\n",
+		},
+		{
+			name:     "null coalescing",
+			source:   `var x; print x ?? "default"; print false ?? "default"; print nil ?? nil ?? 5;`,
+			expected: "default\nfalse\n5\n",
+		},
+		{
+			name: "optional chaining",
+			source: `class A { init() { this.n = 7; this.none = nil; } m() { return "m"; } } var a = A(); var x;
+				print a?.n; print a?.m(); print x?.n; print x?.m(); print x?.b.c.d(); print a.none?.n; print x?.n ?? -1;`,
+			expected: "7\nm\nnil\nnil\nnil\nnil\n-1\n",
+		},
+		{
+			name:     "optional chain is evaluated once",
+			source:   `var calls = 0; fun f() { calls = calls + 1; return nil; } print f()?.x.y; print calls;`,
+			expected: "nil\n1\n",
+		},
+	}
+	for name, backend := range backends {
+		for _, tc := range cases {
+			output, err := runScriptWith(t, backend, tc.source)
+			require.NoError(t, err, name+": "+tc.name)
+			require.Equal(t, tc.expected, output, name+": "+tc.name)
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	classes := `class Shape {} class Point < Shape { init(x, y) { this.x = x; this.y = y; } } class Circle < Shape {}
+		fun describe(v) {
+			match (v) {
+				case 1, 2 => print "small";
+				case "x", -1 => print "literal";
+				case nil => print "nothing";
+				case Point p if (p.x > 0) => print p.y;
+				case Point => print "point";
+				case Shape s => { var name = "shape"; print name; }
+				default => print "other";
+			}
+		}
+	`
+	cases := []struct {
+		name     string
+		source   string
+		expected string
+	}{
+		{name: "literals", source: `describe(2); describe("x"); describe(-1); describe(nil); describe(3);`, expected: "small\nliteral\nliteral\nnothing\nother\n"},
+		{name: "classes and guards", source: `describe(Point(1, 5)); describe(Point(-1, 0)); describe(Circle());`, expected: "5\npoint\nshape\n"},
+		{name: "no case matches", source: `match (1) { case 2 => print "two"; } print "done";`, expected: "done\n"},
+		{name: "loops", source: `for (var i = 0; i < 5; i++) { match (i) { case 1 => continue; case 3 => break; } print i; }`, expected: "0\n2\n"},
+	}
+	for _, tc := range cases {
+		output, err := runScript(t, classes+tc.source)
+		require.NoError(t, err, tc.name)
+		require.Equal(t, tc.expected, output, tc.name)
+	}
+
+	_, err := runScript(t, `var P = 1; match (1) { case P p => print 1; }`)
+	var runtimeError *errors.RuntimeError
+	require.ErrorAs(t, err, &runtimeError)
+	require.Equal(t, "'P' is not a class.", runtimeError.Error())
+}
+
+func TestMatchWarnings(t *testing.T) {
+	cases := map[string]string{
+		`match (1) { case 1, "a" => print 1; case "a" => print 2; }`:               `Duplicate case "a", a previous case matches it.`,
+		`match (true) { case true, false => print 1; case false => print 2; }`:     "Duplicate case false, a previous case matches it.",
+		`match (1) { default => print 1; case 2 => print 2; }`:                     "Unreachable 'case', the previous 'default' matches every value.",
+		`match (1) { case 1 => print 1; default => print 2; default => print 3; }`: "Unreachable 'default', the previous 'default' matches every value.",
+	}
+	for source, expected := range cases {
+		var stdout bytes.Buffer
+		r := New(&stdout, io.Discard)
+		_, diagnostics, err := r.Eval(source)
+		require.NoError(t, err, source)
+		require.Len(t, diagnostics, 1, source)
+		require.Equal(t, errors.SeverityWarning, diagnostics[0].Severity, source)
+		require.Equal(t, expected, diagnostics[0].Message, source)
+		require.Equal(t, "1\n", stdout.String(), source)
+	}
+
+	// guarded cases can't make others unreachable
+	_, diagnostics, err := New(io.Discard, io.Discard).Eval(`match (1) { case 1 if (false) => print 1; case 1 => print 2; }`)
+	require.NoError(t, err)
+	require.Empty(t, diagnostics)
+}
+
+func TestLint(t *testing.T) {
+	testCases := []struct {
+		name     string
+		source   string
+		expected []string
+	}{
+		{
+			name:     "unused local",
+			source:   "{ var a = 1; var b = 2; print b; }",
+			expected: []string{"W0003 Local variable 'a' is never used."},
+		},
+		{
+			name:     "unused parameter",
+			source:   "fun f(a, b) { return b; }",
+			expected: []string{"W0004 Parameter 'a' is never used."},
+		},
+		{
+			name:     "names starting with underscore",
+			source:   "fun f(_a) { var _b = 1; }",
+			expected: nil,
+		},
+		{
+			name:     "shadowed variable",
+			source:   "fun f(a) {\n  { var a = 1; print a; }\n  return a;\n}",
+			expected: []string{"W0005 'a' shadows the variable declared in line 1."},
+		},
+		{
+			name:     "code after return",
+			source:   "fun f() { return 1; print 2; }",
+			expected: []string{"W0006 Unreachable code after 'return'."},
+		},
+		{
+			name:     "code after break",
+			source:   "while (true) { break; print 1; continue; }",
+			expected: []string{"W0006 Unreachable code after 'break'."},
+		},
+		{
+			name:     "value never read",
+			source:   "{ var a = 1; print a; a = 2; }",
+			expected: []string{"W0007 Value assigned to 'a' is never read."},
+		},
+		{
+			name:     "value overwritten",
+			source:   "{ var z = 1; z = 2; z = 3; print z; }",
+			expected: []string{"W0007 Value assigned to 'z' is never read."},
+		},
+		{
+			name:     "values assigned in different branches",
+			source:   "{ var z = 1; print z; if (z > 0) z = 2; else z = 3; z = z > 2 ? (z = 4) : 5; print z; }",
+			expected: nil,
+		},
+		{
+			name:     "value read in a catch block",
+			source:   "fun f() {} { var z = 1; print z; try { z = 2; f(); z = 3; } catch (_e) { print z; } print z; }",
+			expected: nil,
+		},
+		{
+			name:     "value read in the next iteration",
+			source:   "{ for (var i = 0; i < 3; i++) { print i; } var a = 0; while (a < 3) { a = a + 1; } }",
+			expected: nil,
+		},
+		{
+			name:     "value read from a closure",
+			source:   "fun f() { var a = 1; fun g() { return a; } a = 2; return g; }",
+			expected: nil,
+		},
+		{
+			name:     "wrong native arity",
+			source:   "print str(1, 2); print math.sqrt(); print math.max();",
+			expected: []string{"W0008 'str' expects 1 argument but got 2.", "W0008 'math.sqrt' expects 1 argument but got 0.", "W0008 'math.max' expects at least 1 argument but got 0."},
+		},
+		{
+			name:     "natives redefined by the program",
+			source:   "fun str(a, b) { return a + b; } print str(1, 2); { var clock = str; print clock(1, 2); }",
+			expected: nil,
+		},
+		{
+			name:     "ignored warnings",
+			source:   "fun f(a) { // glox:ignore W0004\n  // glox:ignore W0003, W0008\n  var b = str();\n}",
+			expected: nil,
+		},
+		{
+			name:     "pragmas ignore the listed codes only",
+			source:   "fun f(a) { // glox:ignore W0003\n}",
+			expected: []string{"W0004 Parameter 'a' is never used."},
+		},
+		{
+			name:     "pragmas in strings",
+			source:   "{ var a = \"// glox:ignore W0003\"; }",
+			expected: []string{"W0003 Local variable 'a' is never used."},
+		},
+	}
+	for _, tc := range testCases {
+		var stdout bytes.Buffer
+		diagnostics, err := New(&stdout, io.Discard).Lint("script.glox", tc.source)
+		require.NoError(t, err, tc.name)
+		var reported []string
+		for _, d := range diagnostics {
+			require.Equal(t, errors.SeverityWarning, d.Severity, tc.name)
+			reported = append(reported, string(d.Code)+" "+d.Message)
+		}
+		require.Equal(t, tc.expected, reported, tc.name)
+		require.Empty(t, stdout.String(), "lint must not run the program")
+	}
+
+	// overwritten values are reported where they are assigned
+	diagnostics, err := New(io.Discard, io.Discard).Lint("script.glox", "{\n  var z = 1;\n  z = 2;\n  z = 3;\n  print z;\n}")
+	require.NoError(t, err)
+	require.Len(t, diagnostics, 1)
+	require.Equal(t, 3, diagnostics[0].Line)
+
+	// warnings are reported in source order, not in the order scopes are closed
+	diagnostics, err = New(io.Discard, io.Discard).Lint("script.glox", "{\n  var a = 1;\n  { var b = 2; }\n}")
+	require.NoError(t, err)
+	require.Len(t, diagnostics, 2)
+	require.Equal(t, []int{2, 3}, []int{diagnostics[0].Line, diagnostics[1].Line})
+
+	// unreachable code is reported at the first statement that is not executed
+	positions := map[string][2]int{
+		"fun f() {\n  return 1;\n  print 2;\n}":                  {3, 3},
+		"var a = 1;\nwhile (true) {\n  break;\n    ++a; a++;\n}": {4, 5},
+		"fun f() {\n  throw 1;\n  {\n    f();\n  }\n}":           {4, 5},
+	}
+	for source, position := range positions {
+		diagnostics, err := New(io.Discard, io.Discard).Lint("script.glox", source)
+		require.NoError(t, err, source)
+		require.Len(t, diagnostics, 1, source)
+		require.Equal(t, errors.CodeUnreachableCode, diagnostics[0].Code, source)
+		require.Equal(t, position, [2]int{diagnostics[0].Line, diagnostics[0].Column}, source)
+	}
+}
+
+func TestLintErrors(t *testing.T) {
+	diagnostics, err := New(io.Discard, io.Discard).Lint("script.glox", "{ var a = 1; } print 1 +;")
+	require.ErrorIs(t, err, ErrCompile)
+	require.Len(t, diagnostics, 1)
+	require.Equal(t, errors.CodeParse, diagnostics[0].Code)
+
+	// the warnings are only reported by the linter
+	_, diagnostics, err = New(io.Discard, io.Discard).Eval("{ var a = 1; }")
+	require.NoError(t, err)
+	require.Empty(t, diagnostics)
+}
+
+func TestTypeAnnotations(t *testing.T) {
+	source := `fun area(width: num, height: num): num { return width * height; }
+var label: str = "area";
+var total: num? = nil;
+total = area(2, 3);
+print label + ":";
+print total;`
+	for backendName, backend := range backends {
+		output, err := runScriptWith(t, backend, source)
+		require.NoError(t, err, backendName)
+		require.Equal(t, "area:\n6\n", output, backendName)
+	}
+
+	// type errors are reported before running the program
+	var stdout bytes.Buffer
+	r := New(&stdout, io.Discard)
+	_, diagnostics, err := r.Eval("class Point { x: num; init(x) { this.x = x; } }\nprint \"start\";\nvar p: Point = Point(\"1\");\np.x = \"1\";")
+	require.ErrorIs(t, err, ErrCompile)
+	require.Empty(t, stdout.String())
+	require.Len(t, diagnostics, 1)
+	require.Equal(t, errors.CodeType, diagnostics[0].Code)
+	require.Equal(t, "[line 4] Error at 'x': Can't assign str to field 'x' of type num.", diagnostics[0].String())
+}
@@ -5,50 +5,82 @@ import (
 	"glox/errors"
 	. "glox/tokens"
 	"strconv"
+	"strings"
 	"unicode"
 	"unicode/utf8"
 )
 
 var keywords = map[string]TokenType{
-	"and":    And,
-	"class":  Class,
-	"else":   Else,
-	"false":  False,
-	"for":    For,
-	"fun":    Fun,
-	"if":     If,
-	"nil":    Nil,
-	"or":     Or,
-	"print":  Print,
-	"return": Return,
-	"super":  Super,
-	"this":   This,
-	"true":   True,
-	"var":    Var,
-	"while":  While,
+	"and":      And,
+	"class":    Class,
+	"else":     Else,
+	"false":    False,
+	"for":      For,
+	"fun":      Fun,
+	"if":       If,
+	"nil":      Nil,
+	"or":       Or,
+	"print":    Print,
+	"return":   Return,
+	"super":    Super,
+	"this":     This,
+	"true":     True,
+	"var":      Var,
+	"while":    While,
+	"try":      Try,
+	"catch":    Catch,
+	"finally":  Finally,
+	"throw":    Throw,
+	"import":   Import,
+	"break":    Break,
+	"continue": Continue,
+	"trait":    Trait,
+	"match":    Match,
+	"case":     Case,
+	"default":  Default,
 }
 
 type Scanner struct {
-	tokens []Token
-	source string
+	tokens   []Token
+	source   string
+	reporter *errors.Reporter
+	// file is the name of the file being scanned, taken from the reporter
+	file string
 
 	start   int
 	current int
 	line    int
+
+	lineStart   int // offset of the first character of the current line
+	startColumn int // column of the token being scanned
+
+	// last column computed in the current line, so long lines are not counted again for each token
+	columnOffset int
+	lastColumn   int
+
+	// interpolations holds the count of open braces of each string interpolation being scanned, the string
+	// continues at the '}' closing the interpolation
+	interpolations []int
 }
 
-func NewScanner(source string) Scanner {
-	return Scanner{source: source, line: 1}
+func NewScanner(source string, reporter *errors.Reporter) Scanner {
+	return Scanner{source: source, reporter: reporter, file: reporter.File(), line: 1, lastColumn: 1}
 }
 
 func (s *Scanner) ScanTokens() {
 
 	for !s.isAtEnd() {
 		s.start = s.current
+		s.startColumn = s.column(s.start)
 		s.scanToken()
 	}
+	if len(s.interpolations) > 0 {
+		s.error("Unterminated string interpolation.")
+	}
 
-	s.tokens = append(s.tokens, NewToken(Eof, "", nil, s.line))
+	s.start = s.current
+	s.startColumn = s.column(s.start)
+	s.tokens = append(s.tokens, s.newToken(Eof, "", nil))
 }
 
 func (s *Scanner) Tokens() []Token {
@@ -63,21 +95,82 @@ func (s *Scanner) scanToken() {
 	case ')':
 		s.addNilToken(RightParen)
 	case '{':
+		if depth := len(s.interpolations); depth > 0 {
+			s.interpolations[depth-1]++
+		}
 		s.addNilToken(LeftBrace)
 	case '}':
-		s.addNilToken(RightBrace)
+		depth := len(s.interpolations)
+		switch {
+		case depth > 0 && s.interpolations[depth-1] == 0:
+			s.interpolations = s.interpolations[:depth-1]
+			s.handleString()
+		case depth > 0:
+			s.interpolations[depth-1]--
+			s.addNilToken(RightBrace)
+		default:
+			s.addNilToken(RightBrace)
+		}
+	case '[':
+		s.addNilToken(LeftBracket)
+	case ']':
+		s.addNilToken(RightBracket)
+	case ':':
+		s.addNilToken(Colon)
+	case '?':
+		if s.advanceIfMatches('?') {
+			s.addNilToken(QuestionQuestion)
+		} else if s.advanceIfMatches('.') {
+			s.addNilToken(QuestionDot)
+		} else {
+			s.addNilToken(Question)
+		}
 	case ',':
 		s.addNilToken(Comma)
 	case '.':
-		s.addNilToken(Dot)
+		if s.peek() == '.' && s.peekNext() == '.' {
+			s.advance()
+			s.advance()
+			s.addNilToken(Ellipsis)
+		} else {
+			s.addNilToken(Dot)
+		}
 	case ';':
 		s.addNilToken(Semicolon)
 	case '*':
-		s.addNilToken(Star)
+		if s.advanceIfMatches('=') {
+			s.addNilToken(StarEqual)
+		} else {
+			s.addNilToken(Star)
+		}
+	case '%':
+		if s.advanceIfMatches('=') {
+			s.addNilToken(PercentEqual)
+		} else {
+			s.addNilToken(Percent)
+		}
+	case '~':
+		if s.advanceIfMatches('/') {
+			s.addNilToken(TildeSlash)
+		} else {
+			s.error("Unexpected character.")
+		}
 	case '+':
-		s.addNilToken(Plus)
+		if s.advanceIfMatches('+') {
+			s.addNilToken(PlusPlus)
+		} else if s.advanceIfMatches('=') {
+			s.addNilToken(PlusEqual)
+		} else {
+			s.addNilToken(Plus)
+		}
 	case '-':
-		s.addNilToken(Minus)
+		if s.advanceIfMatches('-') {
+			s.addNilToken(MinusMinus)
+		} else if s.advanceIfMatches('=') {
+			s.addNilToken(MinusEqual)
+		} else {
+			s.addNilToken(Minus)
+		}
 
 	case '!':
 		if s.advanceIfMatches('=') {
@@ -88,6 +181,8 @@ func (s *Scanner) scanToken() {
 	case '=':
 		if s.advanceIfMatches('=') {
 			s.addNilToken(EqualEqual)
+		} else if s.advanceIfMatches('>') {
+			s.addNilToken(Arrow)
 		} else {
 			s.addNilToken(Equal)
 		}
@@ -108,22 +203,25 @@ func (s *Scanner) scanToken() {
 			for s.peek() != '\n' && !s.isAtEnd() {
 				s.advance()
 			}
+			s.reporter.Comment(s.line, s.source[s.start:s.current])
+		} else if s.advanceIfMatches('=') {
+			s.addNilToken(SlashEqual)
 		} else {
 			s.addNilToken(Slash)
 		}
 	case ' ', '\r', '\t': // Ignore whitespace
 	case '\n':
-		s.line++
+		s.newLine()
 	case '"':
 		s.handleString()
 
 	default:
-		if unicode.IsDigit(r) {
+		if isDigit(r) {
 			s.handleNumber()
 		} else if unicode.IsLetter(r) || r == '_' {
 			s.handleIdentifier()
 		} else {
-			errors.AtLine(s.line, "Unexpected character.")
+			s.error("Unexpected character.")
 		}
 	}
 
@@ -161,6 +259,11 @@ func (s *Scanner) currentRune() (rune, int) {
 	return utf8.DecodeRuneInString(s.source[s.current:])
 }
 
+func (s *Scanner) previousRune() rune {
+	r, _ := utf8.DecodeLastRuneInString(s.source[:s.current])
+	return r
+}
+
 func (s *Scanner) advance() rune {
 	currentRune, width := s.currentRune()
 	s.current += width
@@ -173,7 +276,43 @@ func (s *Scanner) addNilToken(tokenType TokenType) {
 
 func (s *Scanner) addToken(tokenType TokenType, literal any) {
 	text := s.source[s.start:s.current]
-	s.tokens = append(s.tokens, NewToken(tokenType, text, literal, s.line))
+	s.tokens = append(s.tokens, s.newToken(tokenType, text, literal))
+}
+
+func (s *Scanner) newToken(tokenType TokenType, lexeme string, literal any) Token {
+	token := NewToken(tokenType, lexeme, literal, s.line)
+	token.Column = s.startColumn
+	token.Offset = s.start
+	token.File = s.file
+	return token
+}
+
+// error reports an error for the token being scanned
+func (s *Scanner) error(message string) {
+	span := Span{Start: s.start, End: s.current}
+	s.reporter.AtSpan(errors.CodeScan, s.line, s.startColumn, span, message)
+}
+
+// errorAt reports an error from the provided offset to the current one, Eg: an invalid escape in a string
+func (s *Scanner) errorAt(start int, message string) {
+	span := Span{Start: start, End: s.current}
+	s.reporter.AtSpan(errors.CodeScan, s.line, s.column(start), span, message)
+}
+
+func (s *Scanner) newLine() {
+	s.line++
+	s.lineStart = s.current
+	s.columnOffset, s.lastColumn = s.current, 1
+}
+
+// column returns the 1-based column of the provided offset in the current line
+func (s *Scanner) column(offset int) int {
+	if offset < s.columnOffset {
+		s.columnOffset, s.lastColumn = s.lineStart, 1
+	}
+	s.lastColumn += utf8.RuneCountInString(s.source[s.columnOffset:offset])
+	s.columnOffset = offset
+	return s.lastColumn
 }
 
 func (s *Scanner) advanceIfMatches(expected rune) bool {
@@ -190,42 +329,171 @@ func (s *Scanner) advanceIfMatches(expected rune) bool {
 	return true
 }
 
+// handleString scans a string literal decoding its escape sequences. When it finds an embedded expression
+// (`${`) it emits an Interpolation token and lets the expression be scanned, the string is resumed by the
+// '}' closing it.
 func (s *Scanner) handleString() {
+	var value strings.Builder
 	for s.peek() != '"' && !s.isAtEnd() {
-		if s.peek() == '\n' {
-			s.line++
+		r := s.advance()
+		switch {
+		case r == '\\':
+			s.escape(&value)
+		case r == '$' && s.peek() == '{':
+			s.advance()
+			s.addToken(Interpolation, value.String())
+			s.interpolations = append(s.interpolations, 0)
+			return
+		default:
+			if r == '\n' {
+				s.newLine()
+			}
+			value.WriteRune(r)
 		}
-		s.advance()
 	}
 	if s.isAtEnd() {
-		errors.AtLine(s.line, "Unterminated string.")
+		s.error("Unterminated string.")
 		return
 	}
 
 	s.advance() // The closing "
 
-	value := s.source[s.start+1 : s.current-1]
-	s.addToken(String, value)
+	s.addToken(String, value.String())
 }
 
-func (s *Scanner) handleNumber() {
-	for unicode.IsDigit(s.peek()) {
+var escapes = map[rune]rune{'n': '\n', 't': '\t', 'r': '\r', '0': 0, '"': '"', '\\': '\\', '$': '$'}
+
+// escape writes the character of the escape sequence following a backslash
+func (s *Scanner) escape(value *strings.Builder) {
+	start := s.current - 1
+	if s.isAtEnd() {
+		return // Reported as unterminated string
+	}
+	r := s.advance()
+	if escaped, ok := escapes[r]; ok {
+		value.WriteRune(escaped)
+		return
+	}
+	if r == 'u' {
+		s.unicodeEscape(start, value)
+		return
+	}
+	s.errorAt(start, fmt.Sprintf("Invalid escape sequence '\\%c'.", r))
+	if r == '\n' {
+		s.newLine()
+	}
+}
+
+// unicodeEscape writes the code point of an escape sequence like `\u{1F600}`
+func (s *Scanner) unicodeEscape(start int, value *strings.Builder) {
+	if !s.advanceIfMatches('{') {
+		s.errorAt(start, "Expect '{' after '\\u'.")
+		return
+	}
+	digitsStart := s.current
+	for isHexDigit(s.peek()) {
 		s.advance()
 	}
+	digits := s.source[digitsStart:s.current]
+	if !s.advanceIfMatches('}') {
+		s.errorAt(start, "Expect '}' after unicode escape digits.")
+		return
+	}
+	codePoint, err := strconv.ParseUint(digits, 16, 32)
+	if err != nil || len(digits) > 6 || !utf8.ValidRune(rune(codePoint)) {
+		s.errorAt(start, fmt.Sprintf("Invalid unicode escape sequence '%s'.", s.source[start:s.current]))
+		return
+	}
+	value.WriteRune(rune(codePoint))
+}
+
+// handleNumber scans decimal numbers, with optional fraction and exponent, and hexadecimal or binary integers.
+// Digits can be separated by underscores, Eg: 1_000_000
+func (s *Scanner) handleNumber() {
+	if s.previousRune() == '0' {
+		switch s.peek() {
+		case 'x', 'X':
+			s.handleIntegerLiteral(16, "hexadecimal", isHexDigit)
+			return
+		case 'b', 'B':
+			s.handleIntegerLiteral(2, "binary", isBinaryDigit)
+			return
+		}
+	}
+	valid := s.digits(isDigit)
 	// check for fractional part
-	if s.peek() == '.' && unicode.IsDigit(s.peekNext()) {
-		s.advance()                     // the "."
-		for unicode.IsDigit(s.peek()) { // The digits after
-			s.advance()
+	if s.peek() == '.' && isDigit(s.peekNext()) {
+		s.advance() // the "."
+		valid = s.digits(isDigit) && valid
+	}
+	// check for exponent, the "e" is left for the next token if no digits follow
+	if s.peek() == 'e' || s.peek() == 'E' {
+		mantissaEnd := s.current
+		s.advance()
+		if !s.advanceIfMatches('+') {
+			s.advanceIfMatches('-')
 		}
+		if isDigit(s.peek()) {
+			valid = s.digits(isDigit) && valid
+		} else {
+			s.current = mantissaEnd
+		}
+	}
+	if !valid {
+		s.invalidNumber("Digit separators must be between digits.")
+		return
 	}
-	n, err := strconv.ParseFloat(s.source[s.start:s.current], 64)
+	n, err := strconv.ParseFloat(strings.ReplaceAll(s.source[s.start:s.current], "_", ""), 64)
 	if err != nil {
-		panic("unreachable, we have just checked it is number")
+		s.invalidNumber("Number literal is too large.")
+		return
 	}
 	s.addToken(Number, n)
 }
 
+// handleIntegerLiteral scans the digits of an integer in the provided base after its prefix, Eg: 0xFF
+func (s *Scanner) handleIntegerLiteral(base int, name string, isBaseDigit func(rune) bool) {
+	prefix := s.advance()
+	if !isBaseDigit(s.peek()) {
+		s.invalidNumber(fmt.Sprintf("Expect %s digits after '0%c'.", name, prefix))
+		return
+	}
+	valid := s.digits(isBaseDigit)
+	if isAlphaNumeric(s.peek()) {
+		s.advance()
+		s.invalidNumber(fmt.Sprintf("Invalid %s digit '%c'.", name, s.previousRune()))
+		return
+	}
+	if !valid {
+		s.invalidNumber("Digit separators must be between digits.")
+		return
+	}
+	n, err := strconv.ParseUint(strings.ReplaceAll(s.source[s.start+2:s.current], "_", ""), base, 64)
+	if err != nil {
+		s.invalidNumber("Number literal is too large.")
+		return
+	}
+	s.addToken(Number, float64(n))
+}
+
+// invalidNumber reports an error in the number being scanned. A number token is still added, so the parser doesn't
+// report the missing operand too.
+func (s *Scanner) invalidNumber(message string) {
+	s.error(message)
+	s.addToken(Number, 0.0)
+}
+
+// digits consumes a sequence of digits and underscores, it returns false if an underscore is not between digits
+func (s *Scanner) digits(isBaseDigit func(rune) bool) bool {
+	valid := true
+	for isBaseDigit(s.peek()) || s.peek() == '_' {
+		if s.advance() == '_' && !isBaseDigit(s.peek()) {
+			valid = false
+		}
+	}
+	return valid
+}
+
 func (s *Scanner) handleIdentifier() {
 	for isAlphaNumeric(s.peek()) {
 		s.advance()
@@ -246,6 +514,18 @@ func isAlpha(r rune) bool {
 	return unicode.IsLetter(r) || r == '_'
 }
 
+func isDigit(r rune) bool {
+	return r >= '0' && r <= '9'
+}
+
+func isBinaryDigit(r rune) bool {
+	return r == '0' || r == '1'
+}
+
+func isHexDigit(r rune) bool {
+	return strings.ContainsRune("0123456789abcdefABCDEF", r)
+}
+
 func isAlphaNumeric(r rune) bool {
 	return isAlpha(r) || unicode.IsDigit(r)
 }
@@ -1,7 +1,198 @@
 package scanner
 
-import "testing"
+import (
+	"glox/errors"
+	"glox/tokens"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
 
 func TestScanner(t *testing.T) {
+	source := "var a = 1;\n  print \"ñb\" + a;"
+	reporter := errors.NewReporter(io.Discard)
+	s := NewScanner(source, reporter)
+	s.ScanTokens()
+	require.False(t, reporter.ErrorFound())
+
+	type position struct {
+		tokenType           tokens.TokenType
+		line, column, start int
+	}
+	expected := []position{
+		{tokens.Var, 1, 1, 0},
+		{tokens.Identifier, 1, 5, 4},
+		{tokens.Equal, 1, 7, 6},
+		{tokens.Number, 1, 9, 8},
+		{tokens.Semicolon, 1, 10, 9},
+		{tokens.Print, 2, 3, 13},
+		{tokens.String, 2, 9, 19},
+		{tokens.Plus, 2, 14, 25},
+		{tokens.Identifier, 2, 16, 27},
+		{tokens.Semicolon, 2, 17, 28},
+		{tokens.Eof, 2, 18, 29},
+	}
+	result := []position{}
+	for _, token := range s.Tokens() {
+		result = append(result, position{token.TokenType, token.Line, token.Column, token.Offset})
+	}
+	require.Equal(t, expected, result)
+	require.Equal(t, "\"ñb\"", source[s.Tokens()[6].Span().Start:s.Tokens()[6].Span().End])
+}
+
+func TestScannerErrors(t *testing.T) {
+	reporter := errors.NewReporter(io.Discard)
+	s := NewScanner("var a;\n  @", reporter)
+	s.ScanTokens()
+	require.True(t, reporter.ErrorFound())
+	diagnostics := reporter.Diagnostics()
+	require.Len(t, diagnostics, 1)
+	require.Equal(t, errors.CodeScan, diagnostics[0].Code)
+	require.Equal(t, 2, diagnostics[0].Line)
+	require.Equal(t, 3, diagnostics[0].Column)
+	require.Equal(t, tokens.Span{Start: 9, End: 10}, diagnostics[0].Span)
+}
+
+func TestScannerTokenTypes(t *testing.T) {
+	cases := []struct {
+		source   string
+		expected []tokens.TokenType
+	}{
+		{
+			source:   "a[1:]",
+			expected: []tokens.TokenType{tokens.Identifier, tokens.LeftBracket, tokens.Number, tokens.Colon, tokens.RightBracket},
+		},
+		{
+			source:   "(...a.b..)",
+			expected: []tokens.TokenType{tokens.LeftParen, tokens.Ellipsis, tokens.Identifier, tokens.Dot, tokens.Identifier, tokens.Dot, tokens.Dot, tokens.RightParen},
+		},
+		{
+			source:   "1e 1.e5 7%2~/3",
+			expected: []tokens.TokenType{tokens.Number, tokens.Identifier, tokens.Number, tokens.Dot, tokens.Identifier, tokens.Number, tokens.Percent, tokens.Number, tokens.TildeSlash, tokens.Number},
+		},
+		{
+			source:   "a+=b-=c*=d/=e%=f++--//",
+			expected: []tokens.TokenType{tokens.Identifier, tokens.PlusEqual, tokens.Identifier, tokens.MinusEqual, tokens.Identifier, tokens.StarEqual, tokens.Identifier, tokens.SlashEqual, tokens.Identifier, tokens.PercentEqual, tokens.Identifier, tokens.PlusPlus, tokens.MinusMinus},
+		},
+		{
+			source:   "a?b:c??d?.e",
+			expected: []tokens.TokenType{tokens.Identifier, tokens.Question, tokens.Identifier, tokens.Colon, tokens.Identifier, tokens.QuestionQuestion, tokens.Identifier, tokens.QuestionDot, tokens.Identifier},
+		},
+		{
+			source:   `"${ {"k": 1}["k"] }"`,
+			expected: []tokens.TokenType{tokens.Interpolation, tokens.LeftBrace, tokens.String, tokens.Colon, tokens.Number, tokens.RightBrace, tokens.LeftBracket, tokens.String, tokens.RightBracket, tokens.String},
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.source, func(t *testing.T) {
+			reporter := errors.NewReporter(io.Discard)
+			s := NewScanner(tc.source, reporter)
+			s.ScanTokens()
+			require.False(t, reporter.ErrorFound())
+			result := []tokens.TokenType{}
+			for _, token := range s.Tokens() {
+				result = append(result, token.TokenType)
+			}
+			require.Equal(t, append(tc.expected, tokens.Eof), result)
+		})
+	}
+}
+
+func TestScannerStrings(t *testing.T) {
+	cases := []struct {
+		source   string
+		expected []any
+	}{
+		{source: `"a\tb\n\"c\" \\ \$"`, expected: []any{"a\tb\n\"c\" \\ $"}},
+		{source: `"\u{e9}\u{1F600}"`, expected: []any{"é😀"}},
+		{source: `"Hello ${name}!"`, expected: []any{"Hello ", tokens.NilLiteral, "!"}},
+		{source: `"${a} and ${"in ${b}"}"`, expected: []any{"", tokens.NilLiteral, " and ", "in ", tokens.NilLiteral, "", ""}},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.source, func(t *testing.T) {
+			reporter := errors.NewReporter(io.Discard)
+			s := NewScanner(tc.source, reporter)
+			s.ScanTokens()
+			require.False(t, reporter.ErrorFound())
+			result := []any{}
+			for _, token := range s.Tokens()[:len(s.Tokens())-1] {
+				result = append(result, token.Literal)
+			}
+			require.Equal(t, tc.expected, result)
+		})
+	}
+}
+
+func TestScannerStringErrors(t *testing.T) {
+	cases := []struct {
+		source   string
+		expected string
+		span     tokens.Span
+	}{
+		{source: `"a\qb"`, expected: `Invalid escape sequence '\q'.`, span: tokens.Span{Start: 2, End: 4}},
+		{source: `"\u{110000}"`, expected: `Invalid unicode escape sequence '\u{110000}'.`, span: tokens.Span{Start: 1, End: 11}},
+		{source: `"\u12"`, expected: `Expect '{' after '\u'.`, span: tokens.Span{Start: 1, End: 3}},
+		{source: `"${a`, expected: "Unterminated string interpolation.", span: tokens.Span{Start: 3, End: 4}},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.source, func(t *testing.T) {
+			reporter := errors.NewReporter(io.Discard)
+			s := NewScanner(tc.source, reporter)
+			s.ScanTokens()
+			diagnostics := reporter.Diagnostics()
+			require.NotEmpty(t, diagnostics)
+			require.Equal(t, tc.expected, diagnostics[0].Message)
+			require.Equal(t, tc.span, diagnostics[0].Span)
+		})
+	}
+}
+
+func TestScannerNumbers(t *testing.T) {
+	cases := map[string]float64{
+		"123":       123,
+		"1.5":       1.5,
+		"0xFF":      255,
+		"0Xff":      255,
+		"0b1010":    10,
+		"1_000_000": 1000000,
+		"0xFF_FF":   65535,
+		"1e9":       1e9,
+		"2.5E-3":    0.0025,
+		"1e+21":     1e21,
+	}
+	for source, expected := range cases {
+		reporter := errors.NewReporter(io.Discard)
+		s := NewScanner(source, reporter)
+		s.ScanTokens()
+		require.False(t, reporter.ErrorFound(), source)
+		require.Len(t, s.Tokens(), 2, source)
+		require.Equal(t, expected, s.Tokens()[0].Literal, source)
+		require.Equal(t, source, s.Tokens()[0].Lexeme, source)
+	}
 
+	errorCases := map[string]string{
+		"1__0":                "Digit separators must be between digits.",
+		"1_":                  "Digit separators must be between digits.",
+		"0x":                  "Expect hexadecimal digits after '0x'.",
+		"0b102":               "Invalid binary digit '2'.",
+		"0x1FFFFFFFFFFFFFFFF": "Number literal is too large.",
+		"1e999":               "Number literal is too large.",
+	}
+	for source, expected := range errorCases {
+		reporter := errors.NewReporter(io.Discard)
+		s := NewScanner(source, reporter)
+		s.ScanTokens()
+		diagnostics := reporter.Diagnostics()
+		require.Len(t, diagnostics, 1, source)
+		require.Equal(t, expected, diagnostics[0].Message, source)
+		require.Len(t, s.Tokens(), 2, source)
+		require.Equal(t, tokens.Number, s.Tokens()[0].TokenType, source)
+	}
 }
@@ -0,0 +1,12 @@
+package stmt
+
+import (
+	"glox/expr"
+	"glox/tokens"
+)
+
+// Field is the declaration of a typed field in a class body, Eg: `x: num;`
+type Field struct {
+	Name tokens.Token
+	Type *expr.TypeAnnotation
+}
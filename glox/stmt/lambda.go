@@ -0,0 +1,8 @@
+package stmt
+
+import "glox/expr"
+
+// LambdaFunction returns the declaration of an anonymous function
+func LambdaFunction[T any](l *expr.Lambda[T]) *Function[T] {
+	return l.Function.(*Function[T])
+}
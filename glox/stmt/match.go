@@ -0,0 +1,21 @@
+package stmt
+
+import (
+	"glox/expr"
+	"glox/tokens"
+)
+
+// MatchCase is a case of a match statement, Eg: `case Point p if (p.x > 0) => print p;`. It matches when any of
+// its patterns does and the guard, if any, is truthy. Default cases have no patterns.
+type MatchCase[T any] struct {
+	Keyword  tokens.Token
+	Patterns []expr.Expr[T]
+	// Binding is the variable holding the matched value in the guard and the body
+	Binding *tokens.Token
+	Guard   expr.Expr[T]
+	Body    Stmt[T]
+}
+
+func (c *MatchCase[T]) IsDefault() bool {
+	return c.Keyword.TokenType == tokens.Default
+}
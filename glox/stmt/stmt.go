@@ -18,16 +18,37 @@ func (e *Block[T]) Accept(v Visitor[T]) (T, error) {
 	return v.VisitForBlock(e)
 }
 
+type Break[T any] struct {
+	Keyword tokens.Token
+}
+
+func (e *Break[T]) Accept(v Visitor[T]) (T, error) {
+	return v.VisitForBreak(e)
+}
+
 type Class[T any] struct {
-	Name       tokens.Token
-	SuperClass *expr.Variable[T]
-	Methods    []*Function[T]
+	Name         tokens.Token
+	SuperClass   *expr.Variable[T]
+	Traits       []*expr.Variable[T]
+	Fields       []*Field
+	Methods      []*Function[T]
+	ClassMethods []*Function[T]
+	Getters      []*Function[T]
+	Setters      []*Function[T]
 }
 
 func (e *Class[T]) Accept(v Visitor[T]) (T, error) {
 	return v.VisitForClass(e)
 }
 
+type Continue[T any] struct {
+	Keyword tokens.Token
+}
+
+func (e *Continue[T]) Accept(v Visitor[T]) (T, error) {
+	return v.VisitForContinue(e)
+}
+
 type Expression[T any] struct {
 	Expression expr.Expr[T]
 }
@@ -37,9 +58,13 @@ func (e *Expression[T]) Accept(v Visitor[T]) (T, error) {
 }
 
 type Function[T any] struct {
-	Name   tokens.Token
-	Params []tokens.Token
-	Body   []Stmt[T]
+	Name       tokens.Token
+	Params     []tokens.Token
+	ParamTypes []*expr.TypeAnnotation
+	Defaults   []expr.Expr[T]
+	Variadic   bool
+	ReturnType *expr.TypeAnnotation
+	Body       []Stmt[T]
 }
 
 func (e *Function[T]) Accept(v Visitor[T]) (T, error) {
@@ -47,6 +72,7 @@ func (e *Function[T]) Accept(v Visitor[T]) (T, error) {
 }
 
 type If[T any] struct {
+	Keyword    tokens.Token
 	Condition  expr.Expr[T]
 	ThenBranch Stmt[T]
 	ElseBranch Stmt[T]
@@ -56,7 +82,28 @@ func (e *If[T]) Accept(v Visitor[T]) (T, error) {
 	return v.VisitForIf(e)
 }
 
+type Import[T any] struct {
+	Keyword tokens.Token
+	Path    tokens.Token
+	Name    tokens.Token
+}
+
+func (e *Import[T]) Accept(v Visitor[T]) (T, error) {
+	return v.VisitForImport(e)
+}
+
+type Match[T any] struct {
+	Keyword tokens.Token
+	Value   expr.Expr[T]
+	Cases   []*MatchCase[T]
+}
+
+func (e *Match[T]) Accept(v Visitor[T]) (T, error) {
+	return v.VisitForMatch(e)
+}
+
 type Print[T any] struct {
+	Keyword    tokens.Token
 	Expression expr.Expr[T]
 }
 
@@ -73,8 +120,39 @@ func (e *Return[T]) Accept(v Visitor[T]) (T, error) {
 	return v.VisitForReturn(e)
 }
 
+type Throw[T any] struct {
+	Keyword tokens.Token
+	Value   expr.Expr[T]
+}
+
+func (e *Throw[T]) Accept(v Visitor[T]) (T, error) {
+	return v.VisitForThrow(e)
+}
+
+type Trait[T any] struct {
+	Name    tokens.Token
+	Methods []*Function[T]
+}
+
+func (e *Trait[T]) Accept(v Visitor[T]) (T, error) {
+	return v.VisitForTrait(e)
+}
+
+type Try[T any] struct {
+	Keyword     tokens.Token
+	Body        []Stmt[T]
+	CatchName   *tokens.Token
+	CatchBody   []Stmt[T]
+	FinallyBody []Stmt[T]
+}
+
+func (e *Try[T]) Accept(v Visitor[T]) (T, error) {
+	return v.VisitForTry(e)
+}
+
 type Var[T any] struct {
 	Name        tokens.Token
+	Type        *expr.TypeAnnotation
 	Initializer expr.Expr[T]
 }
 
@@ -83,8 +161,10 @@ func (e *Var[T]) Accept(v Visitor[T]) (T, error) {
 }
 
 type While[T any] struct {
+	Keyword   tokens.Token
 	Condition expr.Expr[T]
 	Body      Stmt[T]
+	Increment expr.Expr[T]
 }
 
 func (e *While[T]) Accept(v Visitor[T]) (T, error) {
@@ -93,12 +173,19 @@ func (e *While[T]) Accept(v Visitor[T]) (T, error) {
 
 type Visitor[T any] interface {
 	VisitForBlock(*Block[T]) (T, error)
+	VisitForBreak(*Break[T]) (T, error)
 	VisitForClass(*Class[T]) (T, error)
+	VisitForContinue(*Continue[T]) (T, error)
 	VisitForExpression(*Expression[T]) (T, error)
 	VisitForFunction(*Function[T]) (T, error)
 	VisitForIf(*If[T]) (T, error)
+	VisitForImport(*Import[T]) (T, error)
+	VisitForMatch(*Match[T]) (T, error)
 	VisitForPrint(*Print[T]) (T, error)
 	VisitForReturn(*Return[T]) (T, error)
+	VisitForThrow(*Throw[T]) (T, error)
+	VisitForTrait(*Trait[T]) (T, error)
+	VisitForTry(*Try[T]) (T, error)
 	VisitForVar(*Var[T]) (T, error)
 	VisitForWhile(*While[T]) (T, error)
 }
@@ -2,6 +2,7 @@ package tokens
 
 import (
 	"fmt"
+	"math"
 	"strconv"
 	"strings"
 )
@@ -17,6 +18,18 @@ type Token struct {
 	Lexeme    string
 	Literal   any
 	Line      int
+	// Column is the 1-based position (in runes) of the token start within its line
+	Column int
+	// Offset is the byte offset of the token start within the source
+	Offset int
+	// File is the name of the source file the token belongs to
+	File string
+}
+
+// Span represents a range of the source, as byte offsets [Start, End)
+type Span struct {
+	Start int
+	End   int
 }
 
 func NewToken(tokenType TokenType, lexeme string, literal any, line int) Token {
@@ -28,6 +41,11 @@ func NewToken(tokenType TokenType, lexeme string, literal any, line int) Token {
 	}
 }
 
+// Span returns the range of the source covered by the token
+func (t Token) Span() Span {
+	return Span{Start: t.Offset, End: t.Offset + len(t.Lexeme)}
+}
+
 func (t Token) String() string {
 	if t.TokenType == Eof {
 		return "EOF  null"
@@ -38,10 +56,38 @@ func (t Token) String() string {
 	return fmt.Sprintf("%v %s %v", t.TokenType, t.Lexeme, t.Literal)
 }
 
+// compoundOperators maps the compound assignment and increment operators to the binary operator they apply
+var compoundOperators = map[TokenType]TokenType{
+	PlusEqual:    Plus,
+	MinusEqual:   Minus,
+	StarEqual:    Star,
+	SlashEqual:   Slash,
+	PercentEqual: Percent,
+	PlusPlus:     Plus,
+	MinusMinus:   Minus,
+}
+
+// BinaryOperator returns the binary operator applied by a compound assignment or increment operator, the token keeps
+// its lexeme and position so errors point to the original operator
+func BinaryOperator(t Token) Token {
+	t.TokenType = compoundOperators[t.TokenType]
+	return t
+}
+
+// FormatFloat formats numbers as the Java implementation used in book's tests, integers get a ".0" suffix
 func FormatFloat(v any) string {
-	s := strconv.FormatFloat(v.(float64), 'f', -1, 64)
-	if !strings.Contains(s, ".") {
+	s := FormatNumber(v.(float64))
+	if strings.Trim(s, "-0123456789") == "" {
 		s = s + ".0"
 	}
 	return s
 }
+
+// FormatNumber returns the shortest representation of the number that scans back to the same value, very large and
+// very small numbers use exponent notation, Eg: 1e+21
+func FormatNumber(f float64) string {
+	if abs := math.Abs(f); abs != 0 && (abs < 1e-6 || abs >= 1e21) {
+		return strconv.FormatFloat(f, 'g', -1, 64)
+	}
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
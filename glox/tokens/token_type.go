@@ -8,6 +8,10 @@ const (
 	RightParen
 	LeftBrace
 	RightBrace
+	LeftBracket
+	RightBracket
+	Colon
+	Question
 	Comma
 	Dot
 	Minus
@@ -15,6 +19,7 @@ const (
 	Semicolon
 	Slash
 	Star
+	Percent
 
 	// On or two character tokens
 	Bang
@@ -25,10 +30,24 @@ const (
 	GreaterEqual
 	Less
 	LessEqual
+	Ellipsis
+	Arrow
+	TildeSlash
+	PlusEqual
+	MinusEqual
+	StarEqual
+	SlashEqual
+	PercentEqual
+	PlusPlus
+	MinusMinus
+	QuestionQuestion
+	QuestionDot
 
 	// Literals
 	Identifier
 	String
+	// Interpolation is the part of a string before an embedded expression, Eg: "Hello ${
+	Interpolation
 	Number
 
 	// Keywords
@@ -48,55 +67,95 @@ const (
 	True
 	Var
 	While
+	Try
+	Catch
+	Finally
+	Throw
+	Import
+	Break
+	Continue
+	Trait
+	Match
+	Case
+	Default
 
 	Eof
 )
 
 var tokenTypeName = map[TokenType]string{
-	LeftParen:  "LEFT_PAREN",
-	RightParen: "RIGHT_PAREN",
-	LeftBrace:  "LEFT_BRACE",
-	RightBrace: "RIGHT_BRACE",
-	Comma:      "COMMA",
-	Dot:        "DOT",
-	Minus:      "MINUS",
-	Plus:       "PLUS",
-	Semicolon:  "SEMICOLON",
-	Slash:      "SLASH",
-	Star:       "STAR",
+	LeftParen:    "LEFT_PAREN",
+	RightParen:   "RIGHT_PAREN",
+	LeftBrace:    "LEFT_BRACE",
+	RightBrace:   "RIGHT_BRACE",
+	LeftBracket:  "LEFT_BRACKET",
+	RightBracket: "RIGHT_BRACKET",
+	Colon:        "COLON",
+	Question:     "QUESTION",
+	Comma:        "COMMA",
+	Dot:          "DOT",
+	Minus:        "MINUS",
+	Plus:         "PLUS",
+	Semicolon:    "SEMICOLON",
+	Slash:        "SLASH",
+	Star:         "STAR",
+	Percent:      "PERCENT",
 
 	// On or two character tokens
-	Bang:         "BANG",
-	BangEqual:    "BANG_EQUAL",
-	Equal:        "EQUAL",
-	EqualEqual:   "EQUAL_EQUAL",
-	Greater:      "GREATER",
-	GreaterEqual: "GREATER_EQUAL",
-	Less:         "LESS",
-	LessEqual:    "LESS_EQUAL",
+	Bang:             "BANG",
+	BangEqual:        "BANG_EQUAL",
+	Equal:            "EQUAL",
+	EqualEqual:       "EQUAL_EQUAL",
+	Greater:          "GREATER",
+	GreaterEqual:     "GREATER_EQUAL",
+	Less:             "LESS",
+	LessEqual:        "LESS_EQUAL",
+	Ellipsis:         "ELLIPSIS",
+	Arrow:            "ARROW",
+	TildeSlash:       "TILDE_SLASH",
+	PlusEqual:        "PLUS_EQUAL",
+	MinusEqual:       "MINUS_EQUAL",
+	StarEqual:        "STAR_EQUAL",
+	SlashEqual:       "SLASH_EQUAL",
+	PercentEqual:     "PERCENT_EQUAL",
+	PlusPlus:         "PLUS_PLUS",
+	MinusMinus:       "MINUS_MINUS",
+	QuestionQuestion: "QUESTION_QUESTION",
+	QuestionDot:      "QUESTION_DOT",
 
 	// Literals
-	Identifier: "IDENTIFIER",
-	String:     "STRING",
-	Number:     "NUMBER",
+	Identifier:    "IDENTIFIER",
+	String:        "STRING",
+	Interpolation: "INTERPOLATION",
+	Number:        "NUMBER",
 
 	// Keywords
-	And:    "AND",
-	Class:  "CLASS",
-	Else:   "ELSE",
-	False:  "FALSE",
-	Fun:    "FUN",
-	For:    "FOR",
-	If:     "IF",
-	Nil:    "NIL",
-	Or:     "OR",
-	Print:  "PRINT",
-	Return: "RETURN",
-	Super:  "SUPER",
-	This:   "THIS",
-	True:   "TRUE",
-	Var:    "VAR",
-	While:  "WHILE",
+	And:      "AND",
+	Class:    "CLASS",
+	Else:     "ELSE",
+	False:    "FALSE",
+	Fun:      "FUN",
+	For:      "FOR",
+	If:       "IF",
+	Nil:      "NIL",
+	Or:       "OR",
+	Print:    "PRINT",
+	Return:   "RETURN",
+	Super:    "SUPER",
+	This:     "THIS",
+	True:     "TRUE",
+	Var:      "VAR",
+	While:    "WHILE",
+	Try:      "TRY",
+	Catch:    "CATCH",
+	Finally:  "FINALLY",
+	Throw:    "THROW",
+	Import:   "IMPORT",
+	Break:    "BREAK",
+	Continue: "CONTINUE",
+	Trait:    "TRAIT",
+	Match:    "MATCH",
+	Case:     "CASE",
+	Default:  "DEFAULT",
 
 	Eof: "EOF",
 }
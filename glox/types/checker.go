@@ -0,0 +1,724 @@
+package types
+
+import (
+	"fmt"
+	"glox/errors"
+	"glox/expr"
+	"glox/stmt"
+	"glox/tokens"
+	"slices"
+)
+
+// typed is the result of checking an expression
+type typed struct {
+	t Type
+	// annotated tells if the type comes from a type annotation. Operations are only checked when they involve
+	// annotated values, so code without annotations keeps its dynamic behavior.
+	annotated bool
+}
+
+var untyped = typed{t: Any}
+
+type scope map[string]typed
+
+// Checker infers the types of the expressions of a program and reports the values that don't match the types
+// declared by annotations. It runs after the resolver, so the program is known to be well-formed.
+type Checker struct {
+	reporter *errors.Reporter
+	scopes   []scope
+	// function is the signature of the function being checked, nil at the top level
+	function *Function
+	// class is the class whose methods are being checked, nil outside classes and in traits
+	class *Class
+	// classes and signatures hold the types of the declarations already found, top-level declarations are
+	// collected before checking the program so they can be used before being declared
+	classes    map[*stmt.Class[any]]*Class
+	signatures map[*stmt.Function[any]]*Function
+}
+
+func NewChecker(reporter *errors.Reporter) *Checker {
+	return &Checker{reporter: reporter, scopes: []scope{{}}, classes: map[*stmt.Class[any]]*Class{}, signatures: map[*stmt.Function[any]]*Function{}}
+}
+
+// Check reports the type errors of the provided statements
+func (c *Checker) Check(statements []stmt.Stmt[any]) {
+	c.declareTopLevel(statements)
+	c.checkStatements(statements)
+}
+
+// declareTopLevel defines the classes and functions declared at the top level. Classes are defined first, so the
+// annotations of the members and parameters can refer to any of them.
+func (c *Checker) declareTopLevel(statements []stmt.Stmt[any]) {
+	for _, statement := range statements {
+		if s, isClass := statement.(*stmt.Class[any]); isClass {
+			c.classes[s] = newClass(s.Name.Lexeme)
+			c.define(s.Name, typed{t: c.classes[s]})
+		}
+	}
+	for _, statement := range statements {
+		switch s := statement.(type) {
+		case *stmt.Class[any]:
+			c.declareMembers(c.classes[s], s)
+		case *stmt.Function[any]:
+			c.define(s.Name, typed{t: c.signature(s)})
+		}
+	}
+}
+
+// declareMembers sets the superclass of the class and the types of its members
+func (c *Checker) declareMembers(class *Class, s *stmt.Class[any]) {
+	if s.SuperClass != nil {
+		if superclass, isClass := c.lookup(s.SuperClass.Name.Lexeme).t.(*Class); isClass {
+			class.Superclass = superclass
+		}
+	}
+	for _, field := range s.Fields {
+		class.Fields[field.Name.Lexeme] = c.annotationType(field.Type)
+	}
+	for _, getter := range s.Getters {
+		class.Fields[getter.Name.Lexeme] = c.annotationType(getter.ReturnType)
+	}
+	for _, method := range s.Methods {
+		class.Methods[method.Name.Lexeme] = c.signature(method)
+	}
+	for _, method := range s.ClassMethods {
+		class.ClassMethods[method.Name.Lexeme] = c.signature(method)
+	}
+}
+
+// signature returns the type of the function declaration
+func (c *Checker) signature(f *stmt.Function[any]) *Function {
+	if signature, found := c.signatures[f]; found {
+		return signature
+	}
+	signature := &Function{Variadic: f.Variadic, Return: c.annotationType(f.ReturnType)}
+	for i, param := range f.Params {
+		paramType := c.annotationType(f.ParamTypes[i])
+		if f.Variadic && i == len(f.Params)-1 {
+			if paramType != Any && paramType != List {
+				c.reporter.AtToken(errors.CodeType, param, fmt.Sprintf("Rest parameter '%s' must be of type list.", param.Lexeme))
+			}
+			paramType = List
+		}
+		signature.Params = append(signature.Params, paramType)
+		signature.ParamNames = append(signature.ParamNames, param.Lexeme)
+	}
+	c.signatures[f] = signature
+	return signature
+}
+
+// annotationType returns the type declared by the annotation, Any if there is no annotation
+func (c *Checker) annotationType(annotation *expr.TypeAnnotation) Type {
+	if annotation == nil {
+		return Any
+	}
+	t, isBuiltin := builtins[annotation.Name.Lexeme]
+	if !isBuiltin {
+		class, isClass := c.lookup(annotation.Name.Lexeme).t.(*Class)
+		if !isClass {
+			c.reporter.AtToken(errors.CodeType, annotation.Name, fmt.Sprintf("Unknown type '%s'.", annotation.Name.Lexeme))
+			return Any
+		}
+		t = Instance{Class: class}
+	}
+	if annotation.Optional {
+		return optional(t)
+	}
+	return t
+}
+
+func (c *Checker) VisitForBlock(s *stmt.Block[any]) (any, error) {
+	c.checkBlock(s.Statements)
+	return nil, nil
+}
+
+func (c *Checker) VisitForBreak(s *stmt.Break[any]) (any, error) {
+	return nil, nil
+}
+
+func (c *Checker) VisitForContinue(s *stmt.Continue[any]) (any, error) {
+	return nil, nil
+}
+
+func (c *Checker) VisitForClass(s *stmt.Class[any]) (any, error) {
+	class, declared := c.classes[s]
+	if !declared {
+		class = newClass(s.Name.Lexeme)
+		c.classes[s] = class
+		c.define(s.Name, typed{t: class})
+		c.declareMembers(class, s)
+	}
+	if s.SuperClass != nil {
+		c.check(s.SuperClass)
+	}
+	for _, trait := range s.Traits {
+		c.check(trait)
+	}
+
+	enclosingClass := c.class
+	c.class = class
+	for _, method := range s.Methods {
+		c.checkFunction(method, c.signature(method))
+	}
+	for _, method := range s.Getters {
+		c.checkFunction(method, &Function{Return: class.Fields[method.Name.Lexeme]})
+	}
+	for _, method := range s.Setters {
+		c.checkFunction(method, c.signature(method))
+	}
+	// in class methods "this" refers to the class
+	c.class = nil
+	for _, method := range s.ClassMethods {
+		c.checkFunction(method, c.signature(method))
+	}
+	c.class = enclosingClass
+	return nil, nil
+}
+
+func (c *Checker) VisitForTrait(s *stmt.Trait[any]) (any, error) {
+	c.define(s.Name, untyped)
+	enclosingClass := c.class
+	c.class = nil
+	for _, method := range s.Methods {
+		c.checkFunction(method, c.signature(method))
+	}
+	c.class = enclosingClass
+	return nil, nil
+}
+
+func (c *Checker) VisitForExpression(s *stmt.Expression[any]) (any, error) {
+	c.check(s.Expression)
+	return nil, nil
+}
+
+func (c *Checker) VisitForFunction(s *stmt.Function[any]) (any, error) {
+	signature := c.signature(s)
+	c.define(s.Name, typed{t: signature})
+	c.checkFunction(s, signature)
+	return nil, nil
+}
+
+// checkFunction checks the body of the function, the parameters are defined with the types of the signature
+func (c *Checker) checkFunction(f *stmt.Function[any], signature *Function) {
+	enclosingFunction := c.function
+	c.function = signature
+	c.beginScope()
+	for i, param := range f.Params {
+		paramType := signature.Params[i]
+		if f.Defaults[i] != nil {
+			value := c.check(f.Defaults[i])
+			if !assignable(value.t, paramType) {
+				c.reporter.AtToken(errors.CodeType, param, fmt.Sprintf("Can't assign %s to parameter '%s' of type %s.", value.t, param.Lexeme, paramType))
+			}
+		}
+		c.define(param, typed{t: paramType, annotated: f.ParamTypes[i] != nil})
+	}
+	c.checkStatements(f.Body)
+	c.endScope()
+	c.function = enclosingFunction
+
+	if !assignable(Nil, signature.Return) && !returns(f.Body) {
+		message := fmt.Sprintf("Missing return in a function returning %s.", signature.Return)
+		if f.Name.Lexeme != "" {
+			message = fmt.Sprintf("Missing return in function '%s' returning %s.", f.Name.Lexeme, signature.Return)
+		}
+		c.reporter.AtToken(errors.CodeType, f.Name, message)
+	}
+}
+
+// returns tells if the statements always return or throw an error, loops without condition nor break never finish
+func returns(statements []stmt.Stmt[any]) bool {
+	for _, statement := range statements {
+		switch s := statement.(type) {
+		case *stmt.Return[any], *stmt.Throw[any]:
+			return true
+		case *stmt.Block[any]:
+			if returns(s.Statements) {
+				return true
+			}
+		case *stmt.While[any]:
+			if condition, isLiteral := s.Condition.(*expr.Literal[any]); isLiteral && condition.Value == true && !breaks(s.Body) {
+				return true
+			}
+		case *stmt.Match[any]:
+			exhaustive := false
+			for _, matchCase := range s.Cases {
+				if !returns([]stmt.Stmt[any]{matchCase.Body}) {
+					exhaustive = false
+					break
+				}
+				exhaustive = exhaustive || matchCase.IsDefault()
+			}
+			if exhaustive {
+				return true
+			}
+		case *stmt.If[any]:
+			if s.ElseBranch != nil && returns([]stmt.Stmt[any]{s.ThenBranch}) && returns([]stmt.Stmt[any]{s.ElseBranch}) {
+				return true
+			}
+		case *stmt.Try[any]:
+			if returns(s.FinallyBody) || (returns(s.Body) && (s.CatchName == nil || returns(s.CatchBody))) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// breaks tells if the statement contains a break leaving the enclosing loop
+func breaks(statement stmt.Stmt[any]) bool {
+	switch s := statement.(type) {
+	case *stmt.Break[any]:
+		return true
+	case *stmt.Block[any]:
+		return slices.ContainsFunc(s.Statements, breaks)
+	case *stmt.If[any]:
+		return breaks(s.ThenBranch) || (s.ElseBranch != nil && breaks(s.ElseBranch))
+	case *stmt.Match[any]:
+		return slices.ContainsFunc(s.Cases, func(matchCase *stmt.MatchCase[any]) bool { return breaks(matchCase.Body) })
+	case *stmt.Try[any]:
+		return slices.ContainsFunc(s.Body, breaks) || slices.ContainsFunc(s.CatchBody, breaks) || slices.ContainsFunc(s.FinallyBody, breaks)
+	}
+	return false
+}
+
+func (c *Checker) VisitForIf(s *stmt.If[any]) (any, error) {
+	c.check(s.Condition)
+	c.checkStatement(s.ThenBranch)
+	if s.ElseBranch != nil {
+		c.checkStatement(s.ElseBranch)
+	}
+	return nil, nil
+}
+
+func (c *Checker) VisitForImport(s *stmt.Import[any]) (any, error) {
+	c.define(s.Name, untyped)
+	return nil, nil
+}
+
+func (c *Checker) VisitForMatch(s *stmt.Match[any]) (any, error) {
+	c.check(s.Value)
+	for _, matchCase := range s.Cases {
+		for _, pattern := range matchCase.Patterns {
+			c.check(pattern)
+		}
+		c.beginScope()
+		if matchCase.Binding != nil {
+			c.define(*matchCase.Binding, untyped)
+		}
+		if matchCase.Guard != nil {
+			c.check(matchCase.Guard)
+		}
+		c.checkStatement(matchCase.Body)
+		c.endScope()
+	}
+	return nil, nil
+}
+
+func (c *Checker) VisitForPrint(s *stmt.Print[any]) (any, error) {
+	c.check(s.Expression)
+	return nil, nil
+}
+
+func (c *Checker) VisitForReturn(s *stmt.Return[any]) (any, error) {
+	value := typed{t: Nil}
+	if s.Value != nil {
+		value = c.check(s.Value)
+	}
+	if c.function != nil && !assignable(value.t, c.function.Return) {
+		c.reporter.AtToken(errors.CodeType, s.Keyword, fmt.Sprintf("Can't return %s from a function returning %s.", value.t, c.function.Return))
+	}
+	return nil, nil
+}
+
+func (c *Checker) VisitForThrow(s *stmt.Throw[any]) (any, error) {
+	c.check(s.Value)
+	return nil, nil
+}
+
+func (c *Checker) VisitForTry(s *stmt.Try[any]) (any, error) {
+	c.checkBlock(s.Body)
+	if s.CatchName != nil {
+		c.beginScope()
+		c.define(*s.CatchName, untyped)
+		c.checkStatements(s.CatchBody)
+		c.endScope()
+	}
+	if s.FinallyBody != nil {
+		c.checkBlock(s.FinallyBody)
+	}
+	return nil, nil
+}
+
+func (c *Checker) VisitForVar(s *stmt.Var[any]) (any, error) {
+	variable := typed{t: c.annotationType(s.Type), annotated: s.Type != nil}
+	if s.Initializer != nil {
+		value := c.check(s.Initializer)
+		if !assignable(value.t, variable.t) {
+			c.reporter.AtToken(errors.CodeType, s.Name, fmt.Sprintf("Can't assign %s to variable '%s' of type %s.", value.t, s.Name.Lexeme, variable.t))
+		}
+	}
+	c.define(s.Name, variable)
+	return nil, nil
+}
+
+func (c *Checker) VisitForWhile(s *stmt.While[any]) (any, error) {
+	c.check(s.Condition)
+	c.checkStatement(s.Body)
+	if s.Increment != nil {
+		c.check(s.Increment)
+	}
+	return nil, nil
+}
+
+func (c *Checker) VisitForAssign(e *expr.Assign[any]) (any, error) {
+	value := c.check(e.Value)
+	c.checkAssignment(e.Name, value)
+	return value, nil
+}
+
+// checkAssignment reports an error if the value does not match the type of the variable
+func (c *Checker) checkAssignment(name tokens.Token, value typed) {
+	variable := c.lookup(name.Lexeme)
+	if variable.annotated && !assignable(value.t, variable.t) {
+		c.reporter.AtToken(errors.CodeType, name, fmt.Sprintf("Can't assign %s to variable '%s' of type %s.", value.t, name.Lexeme, variable.t))
+	}
+}
+
+func (c *Checker) VisitForBinary(e *expr.Binary[any]) (any, error) {
+	return c.binary(e.Operator, c.check(e.Left), c.check(e.Right)), nil
+}
+
+// binary returns the type of the result of the operation, reporting an error if the operand types are known to be
+// invalid. Only the left operand can overload the operator, so its type determines the result.
+func (c *Checker) binary(operator tokens.Token, left typed, right typed) typed {
+	annotated := left.annotated || right.annotated
+	result := func(t Type) typed {
+		return typed{t: t, annotated: annotated}
+	}
+	switch operator.TokenType {
+	case tokens.EqualEqual, tokens.BangEqual:
+		return result(Bool)
+	case tokens.Plus:
+		if !isBasic(left.t) {
+			return untyped
+		}
+		if (left.t != Num && left.t != Str) || (isBasic(right.t) && right.t != left.t) {
+			c.operandsError(annotated, operator, fmt.Sprintf("Operands of '%s' must be two numbers or two strings but got %s and %s.", operator.Lexeme, left.t, right.t))
+			return untyped
+		}
+		return result(left.t)
+	}
+	if !isBasic(left.t) {
+		return untyped
+	}
+	if left.t != Num || (isBasic(right.t) && right.t != Num) {
+		c.operandsError(annotated, operator, fmt.Sprintf("Operands of '%s' must be numbers but got %s and %s.", operator.Lexeme, left.t, right.t))
+		return untyped
+	}
+	switch operator.TokenType {
+	case tokens.Less, tokens.LessEqual, tokens.Greater, tokens.GreaterEqual:
+		return result(Bool)
+	}
+	return result(Num)
+}
+
+// isBasic tells if the type is a built-in type other than Any, whose values can't overload operators
+func isBasic(t Type) bool {
+	_, isBasic := t.(basic)
+	return isBasic && t != Any
+}
+
+func (c *Checker) operandsError(annotated bool, operator tokens.Token, message string) {
+	if annotated {
+		c.reporter.AtToken(errors.CodeType, operator, message)
+	}
+}
+
+func (c *Checker) VisitForUnary(e *expr.Unary[any]) (any, error) {
+	right := c.check(e.Right)
+	if e.Operator.TokenType == tokens.Bang {
+		return typed{t: Bool}, nil
+	}
+	if !isBasic(right.t) {
+		return untyped, nil
+	}
+	if right.t != Num {
+		c.operandsError(right.annotated, e.Operator, fmt.Sprintf("Operand of '-' must be a number but got %s.", right.t))
+		return untyped, nil
+	}
+	return typed{t: Num, annotated: right.annotated}, nil
+}
+
+// compound returns the type of the result of the compound assignment, reporting an error if the operand types are
+// known to be invalid
+func (c *Checker) compound(operator tokens.Token, target typed, value typed) typed {
+	if operator.TokenType != tokens.PlusPlus && operator.TokenType != tokens.MinusMinus {
+		return c.binary(tokens.BinaryOperator(operator), target, value)
+	}
+	if !isBasic(target.t) {
+		return untyped
+	}
+	if target.t != Num {
+		c.operandsError(target.annotated, operator, fmt.Sprintf("Operand of '%s' must be a number but got %s.", operator.Lexeme, target.t))
+		return untyped
+	}
+	return typed{t: Num, annotated: target.annotated}
+}
+
+func (c *Checker) VisitForCompoundAssign(e *expr.CompoundAssign[any]) (any, error) {
+	value := c.compound(e.Operator, c.lookup(e.Name.Lexeme), c.check(e.Value))
+	c.checkAssignment(e.Name, value)
+	return value, nil
+}
+
+func (c *Checker) VisitForCompoundSet(e *expr.CompoundSet[any]) (any, error) {
+	object := c.check(e.Object)
+	value := c.compound(e.Operator, c.property(object.t, e.Name), c.check(e.Value))
+	c.checkField(object.t, e.Name, value)
+	return value, nil
+}
+
+func (c *Checker) VisitForCall(e *expr.Call[any]) (any, error) {
+	callee := c.check(e.Callee)
+	arguments := make([]typed, len(e.Arguments))
+	for i, argument := range e.Arguments {
+		arguments[i] = c.check(argument)
+	}
+	switch callee := callee.t.(type) {
+	case *Function:
+		c.checkArguments(callee, e.Paren, arguments)
+		return typed{t: callee.Return, annotated: callee.Return != Any}, nil
+	case *Class:
+		if initializer := callee.method("init"); initializer != nil {
+			c.checkArguments(initializer, e.Paren, arguments)
+		}
+		return typed{t: Instance{Class: callee}}, nil
+	}
+	if callee.annotated && isBasic(callee.t) && callee.t != Fun {
+		c.reporter.AtToken(errors.CodeType, e.Paren, fmt.Sprintf("Can't call a value of type %s.", callee.t))
+	}
+	return untyped, nil
+}
+
+// checkArguments reports the arguments that don't match the type of their parameter
+func (c *Checker) checkArguments(function *Function, paren tokens.Token, arguments []typed) {
+	params := len(function.Params)
+	if function.Variadic {
+		params--
+	}
+	for i, argument := range arguments[:min(params, len(arguments))] {
+		if !assignable(argument.t, function.Params[i]) {
+			c.reporter.AtToken(errors.CodeType, paren, fmt.Sprintf("Can't pass %s as parameter '%s' of type %s.", argument.t, function.ParamNames[i], function.Params[i]))
+		}
+	}
+}
+
+func (c *Checker) VisitForConditional(e *expr.Conditional[any]) (any, error) {
+	c.check(e.Condition)
+	then, otherwise := c.check(e.Then), c.check(e.Else)
+	return typed{t: join(then.t, otherwise.t), annotated: then.annotated && otherwise.annotated}, nil
+}
+
+func (c *Checker) VisitForLogical(e *expr.Logical[any]) (any, error) {
+	left, right := c.check(e.Left), c.check(e.Right)
+	if e.Operator.TokenType == tokens.QuestionQuestion {
+		left.t = nonNil(left.t)
+	}
+	return typed{t: join(left.t, right.t), annotated: left.annotated && right.annotated}, nil
+}
+
+func (c *Checker) VisitForGet(e *expr.Get[any]) (any, error) {
+	return c.property(c.check(e.Object).t, e.Name), nil
+}
+
+func (c *Checker) VisitForOptionalGet(e *expr.OptionalGet[any]) (any, error) {
+	property := c.property(nonNil(c.check(e.Object).t), e.Name)
+	property.t = optional(property.t)
+	return property, nil
+}
+
+func (c *Checker) VisitForOptionalChain(e *expr.OptionalChain[any]) (any, error) {
+	value := c.check(e.Expression)
+	value.t = optional(value.t)
+	return value, nil
+}
+
+// property returns the type of the property of a value of type object, Any if it is unknown
+func (c *Checker) property(object Type, name tokens.Token) typed {
+	switch object := object.(type) {
+	case Instance:
+		if t, found := object.Class.field(name.Lexeme); found {
+			return typed{t: t, annotated: t != Any}
+		}
+		if method := object.Class.method(name.Lexeme); method != nil {
+			return typed{t: method}
+		}
+	case *Class:
+		if method, found := object.ClassMethods[name.Lexeme]; found {
+			return typed{t: method}
+		}
+	}
+	return untyped
+}
+
+func (c *Checker) VisitForSet(e *expr.Set[any]) (any, error) {
+	value := c.check(e.Value)
+	object := c.check(e.Object)
+	c.checkField(object.t, e.Name, value)
+	return value, nil
+}
+
+// checkField reports an error if the value does not match the declared type of the field
+func (c *Checker) checkField(object Type, name tokens.Token, value typed) {
+	instance, isInstance := object.(Instance)
+	if !isInstance {
+		return
+	}
+	if t, found := instance.Class.field(name.Lexeme); found && !assignable(value.t, t) {
+		c.reporter.AtToken(errors.CodeType, name, fmt.Sprintf("Can't assign %s to field '%s' of type %s.", value.t, name.Lexeme, t))
+	}
+}
+
+func (c *Checker) VisitForGrouping(e *expr.Grouping[any]) (any, error) {
+	return c.check(e.Expression), nil
+}
+
+func (c *Checker) VisitForIndex(e *expr.Index[any]) (any, error) {
+	c.check(e.Object)
+	c.check(e.Index)
+	return untyped, nil
+}
+
+func (c *Checker) VisitForIndexSet(e *expr.IndexSet[any]) (any, error) {
+	c.check(e.Object)
+	c.check(e.Index)
+	return c.check(e.Value), nil
+}
+
+func (c *Checker) VisitForCompoundIndexSet(e *expr.CompoundIndexSet[any]) (any, error) {
+	c.check(e.Object)
+	c.check(e.Index)
+	return c.compound(e.Operator, untyped, c.check(e.Value)), nil
+}
+
+func (c *Checker) VisitForSlice(e *expr.Slice[any]) (any, error) {
+	object := c.check(e.Object)
+	if e.Start != nil {
+		c.check(e.Start)
+	}
+	if e.End != nil {
+		c.check(e.End)
+	}
+	if object.t == Str || object.t == List {
+		return object, nil
+	}
+	return untyped, nil
+}
+
+func (c *Checker) VisitForInterpolation(e *expr.Interpolation[any]) (any, error) {
+	for _, part := range e.Parts {
+		c.check(part)
+	}
+	return typed{t: Str}, nil
+}
+
+func (c *Checker) VisitForLambda(e *expr.Lambda[any]) (any, error) {
+	function := stmt.LambdaFunction(e)
+	signature := c.signature(function)
+	c.checkFunction(function, signature)
+	return typed{t: signature}, nil
+}
+
+func (c *Checker) VisitForList(e *expr.List[any]) (any, error) {
+	for _, element := range e.Elements {
+		c.check(element)
+	}
+	return typed{t: List}, nil
+}
+
+func (c *Checker) VisitForMap(e *expr.Map[any]) (any, error) {
+	for i := range e.Keys {
+		c.check(e.Keys[i])
+		c.check(e.Values[i])
+	}
+	return typed{t: Map}, nil
+}
+
+func (c *Checker) VisitForLiteral(e *expr.Literal[any]) (any, error) {
+	switch e.Value.(type) {
+	case float64:
+		return typed{t: Num}, nil
+	case string:
+		return typed{t: Str}, nil
+	case bool:
+		return typed{t: Bool}, nil
+	case tokens.NilLiteralType:
+		return typed{t: Nil}, nil
+	}
+	return untyped, nil
+}
+
+func (c *Checker) VisitForSuper(e *expr.Super[any]) (any, error) {
+	if c.class != nil && c.class.Superclass != nil {
+		if method := c.class.Superclass.method(e.Method.Lexeme); method != nil {
+			return typed{t: method}, nil
+		}
+	}
+	return untyped, nil
+}
+
+func (c *Checker) VisitForThis(e *expr.This[any]) (any, error) {
+	if c.class != nil {
+		return typed{t: Instance{Class: c.class}}, nil
+	}
+	return untyped, nil
+}
+
+func (c *Checker) VisitForVariable(e *expr.Variable[any]) (any, error) {
+	return c.lookup(e.Name.Lexeme), nil
+}
+
+func (c *Checker) check(e expr.Expr[any]) typed {
+	result, _ := e.Accept(c)
+	return result.(typed)
+}
+
+func (c *Checker) checkStatement(s stmt.Stmt[any]) {
+	_, _ = s.Accept(c)
+}
+
+func (c *Checker) checkStatements(statements []stmt.Stmt[any]) {
+	for _, statement := range statements {
+		c.checkStatement(statement)
+	}
+}
+
+// checkBlock checks the provided statements in a new scope
+func (c *Checker) checkBlock(statements []stmt.Stmt[any]) {
+	c.beginScope()
+	c.checkStatements(statements)
+	c.endScope()
+}
+
+func (c *Checker) beginScope() {
+	c.scopes = append(c.scopes, scope{})
+}
+
+func (c *Checker) endScope() {
+	c.scopes = c.scopes[:len(c.scopes)-1]
+}
+
+func (c *Checker) define(name tokens.Token, t typed) {
+	c.scopes[len(c.scopes)-1][name.Lexeme] = t
+}
+
+// lookup returns the type of the variable, variables not declared in the program are untyped
+func (c *Checker) lookup(name string) typed {
+	for i := len(c.scopes) - 1; i >= 0; i-- {
+		if t, found := c.scopes[i][name]; found {
+			return t
+		}
+	}
+	return untyped
+}
@@ -0,0 +1,109 @@
+package types
+
+import (
+	"glox/errors"
+	"glox/parser"
+	"glox/scanner"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// check type checks the provided source and returns the messages of the errors found
+func check(t *testing.T, source string) []string {
+	t.Helper()
+	reporter := errors.NewReporter(io.Discard)
+	s := scanner.NewScanner(source, reporter)
+	s.ScanTokens()
+	p := parser.NewParser[any](s.Tokens(), reporter)
+	statements, parseErrors := p.Parse()
+	require.Empty(t, parseErrors, source)
+
+	NewChecker(reporter).Check(statements)
+	var messages []string
+	for _, d := range reporter.Diagnostics() {
+		require.Equal(t, errors.CodeType, d.Code, source)
+		messages = append(messages, d.Message)
+	}
+	return messages
+}
+
+const point = `class Point {
+  x: num;
+  y: num;
+  init(x: num, y: num) { this.x = x; this.y = y; }
+  norm: num { return this.x * this.x + this.y * this.y; }
+  add(other: Point): Point { return Point(this.x + other.x, this.y + other.y); }
+}
+class Point3 < Point { z: num?; }
+`
+
+func TestCheck(t *testing.T) {
+	sources := []string{
+		`var a: num = 1; var b: str = "a" + "b"; var c: bool = a > 2; var d: any = nil; var e: num? = nil; e = 2;`,
+		`var l: list = [1, 2]; var m: map = {"a": 1}; var f: fun = clock; var s: str = "${l}";`,
+		`fun f(a: str, b = 1): bool { return a == "x"; } var r: bool = f("x", "y");`,
+		`fun f(a: num?): num { return a ?? 0; } f(nil); f(1);`,
+		`fun f(...rest: list) {} f(1, "a");`,
+		`var f = (a: num) => a * 2; var x: num = f(1);`,
+		`fun f(a: num): num { if (a > 0) { return a; } else { throw "negative"; } }`,
+		`fun f(): num { while (true) { return 1; } } fun g(): num { for (;;) {} }`,
+		`fun f(a: num): num { match (a) { case 1 => return 1; default => throw "a"; } }`,
+		point + `var p: Point = Point(1, 2); var n: num = p.norm + p.add(p).x;`,
+		point + `var p: Point = Point3(1, 2); var z: num? = Point3(1, 2).z;`,
+		point + `fun f(p: Point?) { return p?.x; } var x: num? = f(nil);`,
+		`var a: num = 1; var b: num = a > 0 ? a : 2; a += 1; a++;`,
+		// unannotated code is dynamically typed
+		`var a = 1; a = "a"; print 1 - "a"; fun f(x) { return x; } var s: str = f(1);`,
+		// operators can be overloaded by instances
+		point + `fun f(p: Point) { return p + 1; }`,
+		// types can be used before being declared
+		`fun f(): Node { return Node(); } class Node { next: Node?; }`,
+	}
+	for _, source := range sources {
+		require.Empty(t, check(t, source), source)
+	}
+
+	errorCases := map[string]string{
+		`var a: num = "a";`:                              "Can't assign str to variable 'a' of type num.",
+		`var a: num = 1; a = nil;`:                       "Can't assign nil to variable 'a' of type num.",
+		`var a: num? = "a";`:                             "Can't assign str to variable 'a' of type num?.",
+		`var a: Unknown;`:                                "Unknown type 'Unknown'.",
+		`var a: fun = 1;`:                                "Can't assign num to variable 'a' of type fun.",
+		`fun f(a: str) {} f(1);`:                         "Can't pass num as parameter 'a' of type str.",
+		`fun f(a: str = 1) {}`:                           "Can't assign num to parameter 'a' of type str.",
+		`fun f(...rest: num) {}`:                         "Rest parameter 'rest' must be of type list.",
+		`fun f(): str { return 1; }`:                     "Can't return num from a function returning str.",
+		`fun f(): str { print 1; }`:                      "Missing return in function 'f' returning str.",
+		`var f = fun (): num { if (true) return 1; };`:   "Missing return in a function returning num.",
+		`fun f(a: str) { return a - 1; }`:                "Operands of '-' must be numbers but got str and num.",
+		`fun f(a: num) { return a + "b"; }`:              "Operands of '+' must be two numbers or two strings but got num and str.",
+		`fun f(a: bool) { return -a; }`:                  "Operand of '-' must be a number but got bool.",
+		`var a: str = "a"; a += 1;`:                      "Operands of '+=' must be two numbers or two strings but got str and num.",
+		`var s: str = "a"; s++;`:                         "Operand of '++' must be a number but got str.",
+		`var b: bool = true; b--;`:                       "Operand of '--' must be a number but got bool.",
+		`var a: num = 1; a();`:                           "Can't call a value of type num.",
+		`var a: num = 1 > 2 ? 1 : nil;`:                  "Can't assign num? to variable 'a' of type num.",
+		point + `Point(1, "2");`:                         "Can't pass str as parameter 'y' of type num.",
+		point + `var p: Point = Point(1, 2); p.x = "a";`: "Can't assign str to field 'x' of type num.",
+		point + `var p: Point3 = Point(1, 2);`:           "Can't assign Point to variable 'p' of type Point3.",
+		point + `var n: str = Point(1, 2).norm;`:         "Can't assign num to variable 'n' of type str.",
+		point + `var p: Point = nil;`:                    "Can't assign nil to variable 'p' of type Point.",
+		point + `var x: num = Point(1, 2)?.x;`:           "Can't assign num? to variable 'x' of type num.",
+		`class A { f: num { return "a"; } }`:             "Can't return str from a function returning num.",
+	}
+	for source, expected := range errorCases {
+		require.Equal(t, []string{expected}, check(t, source), source)
+	}
+
+	// loops left with break and matches that are not exhaustive can finish without returning
+	missingReturns := []string{
+		`fun f(): num { while (true) { if (clock() > 1) break; } }`,
+		`fun f(): num { match (1) { case 1 => return 1; } }`,
+		`fun f(): num { match (1) { case 1 => print 1; default => return 1; } }`,
+	}
+	for _, source := range missingReturns {
+		require.Equal(t, []string{"Missing return in function 'f' returning num."}, check(t, source), source)
+	}
+}
@@ -0,0 +1,168 @@
+// Package types implements the static type checker of glox. Types are optional: only the values declared with a
+// type annotation are checked, the rest of the program remains dynamically typed.
+package types
+
+// Type is the static type of a glox value
+type Type interface {
+	String() string
+}
+
+// basic is a built-in type, identified by the name used in annotations
+type basic string
+
+func (b basic) String() string {
+	return string(b)
+}
+
+var (
+	// Any is the type of the values whose type is unknown, it is compatible with every other type
+	Any  Type = basic("any")
+	Num  Type = basic("num")
+	Str  Type = basic("str")
+	Bool Type = basic("bool")
+	Nil  Type = basic("nil")
+	List Type = basic("list")
+	Map  Type = basic("map")
+	// Fun is the type of any callable value: functions and classes
+	Fun Type = basic("fun")
+)
+
+// builtins holds the built-in types by name
+var builtins = map[string]Type{"any": Any, "num": Num, "str": Str, "bool": Bool, "nil": Nil, "list": List, "map": Map, "fun": Fun}
+
+// Optional is a type that also accepts nil, Eg: `num?`
+type Optional struct {
+	Type Type
+}
+
+func (o Optional) String() string {
+	return o.Type.String() + "?"
+}
+
+// optional returns the type accepting nil besides the values of t
+func optional(t Type) Type {
+	if t == Any || t == Nil {
+		return t
+	}
+	if _, isOptional := t.(Optional); isOptional {
+		return t
+	}
+	return Optional{Type: t}
+}
+
+// nonNil returns the type of the values of t other than nil
+func nonNil(t Type) Type {
+	if o, isOptional := t.(Optional); isOptional {
+		return o.Type
+	}
+	return t
+}
+
+// Function is the signature of a function, the types of the parameters and the result are Any unless annotated
+type Function struct {
+	Params     []Type
+	ParamNames []string
+	// Variadic is set when the last parameter collects the remaining arguments
+	Variadic bool
+	Return   Type
+}
+
+func (f *Function) String() string {
+	return "fun"
+}
+
+// Class is the type of a class declared in the program, calling it creates an Instance
+type Class struct {
+	Name       string
+	Superclass *Class
+	// Fields holds the types of the fields declared in the class body and of its getters
+	Fields       map[string]Type
+	Methods      map[string]*Function
+	ClassMethods map[string]*Function
+}
+
+func newClass(name string) *Class {
+	return &Class{Name: name, Fields: map[string]Type{}, Methods: map[string]*Function{}, ClassMethods: map[string]*Function{}}
+}
+
+func (c *Class) String() string {
+	return "class " + c.Name
+}
+
+// field returns the type of the field, looking it up in the superclasses too
+func (c *Class) field(name string) (Type, bool) {
+	for class := c; class != nil; class = class.Superclass {
+		if t, found := class.Fields[name]; found {
+			return t, true
+		}
+	}
+	return nil, false
+}
+
+// method returns the signature of the method, looking it up in the superclasses too
+func (c *Class) method(name string) *Function {
+	for class := c; class != nil; class = class.Superclass {
+		if method, found := class.Methods[name]; found {
+			return method
+		}
+	}
+	return nil
+}
+
+// inherits tells if the class is the provided one or one of its subclasses
+func (c *Class) inherits(other *Class) bool {
+	for class := c; class != nil; class = class.Superclass {
+		if class == other {
+			return true
+		}
+	}
+	return false
+}
+
+// Instance is the type of the instances of a class
+type Instance struct {
+	Class *Class
+}
+
+func (i Instance) String() string {
+	return i.Class.Name
+}
+
+// assignable tells if a value of type value can be used where a value of type target is expected
+func assignable(value Type, target Type) bool {
+	if value == Any || target == Any || value == target {
+		return true
+	}
+	switch target := target.(type) {
+	case Optional:
+		if value == Nil {
+			return true
+		}
+		return assignable(nonNil(value), target.Type)
+	case Instance:
+		instance, isInstance := value.(Instance)
+		return isInstance && instance.Class.inherits(target.Class)
+	}
+	if target == Fun {
+		switch value.(type) {
+		case *Function, *Class:
+			return true
+		}
+	}
+	return false
+}
+
+// join returns the type of a value that can be either a or b
+func join(a Type, b Type) Type {
+	switch {
+	case assignable(a, b) && a != Any && a != Nil:
+		return b
+	case assignable(b, a) && b != Any && b != Nil:
+		return a
+	case a == Nil:
+		return optional(b)
+	case b == Nil:
+		return optional(a)
+	}
+	return Any
+}
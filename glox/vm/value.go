@@ -0,0 +1,99 @@
+package vm
+
+import (
+	"fmt"
+	"glox/compiler"
+	"glox/tokens"
+)
+
+// Closure is a compiled function along with the variables it captures
+type Closure struct {
+	Function *compiler.Function
+	Upvalues []*Upvalue
+}
+
+func (c *Closure) String() string {
+	return c.Function.String()
+}
+
+// Upvalue references a variable captured by a closure. While the variable is alive in the stack the upvalue
+// points to its slot, once it goes out of scope the value is moved into the upvalue.
+type Upvalue struct {
+	slot   int
+	closed any
+	open   bool
+	next   *Upvalue // next open upvalue, sorted by slot
+}
+
+type Class struct {
+	Name    string
+	Methods map[string]*Closure
+}
+
+func (c *Class) String() string {
+	return c.Name
+}
+
+type Instance struct {
+	Class  *Class
+	Fields map[string]any
+}
+
+func (i *Instance) String() string {
+	return i.Class.Name + " instance"
+}
+
+// BoundMethod is a method along with the instance it was accessed from
+type BoundMethod struct {
+	Receiver any
+	Method   *Closure
+}
+
+func (b *BoundMethod) String() string {
+	return b.Method.String()
+}
+
+// Native is a function implemented in Go
+type Native struct {
+	Name  string
+	Arity int
+	// Variadic is set when the function accepts any number of arguments from Arity on
+	Variadic bool
+	Fn       func(args []any) (any, error)
+}
+
+func (n *Native) String() string {
+	return "<native fn>"
+}
+
+// ErrorValue is the value a catch clause gets when a runtime error happens
+type ErrorValue struct {
+	Message string
+	Line    int
+}
+
+func (e *ErrorValue) String() string {
+	return e.Message
+}
+
+// isFalsey considers nil and false as false, anything else is true
+func isFalsey(v any) bool {
+	if v == nil {
+		return true
+	}
+	if b, isBool := v.(bool); isBool {
+		return !b
+	}
+	return false
+}
+
+// stringify returns the string representation of the provided value, it matches the tree-walking interpreter
+func stringify(v any) string {
+	if v == nil {
+		return "nil"
+	}
+	if f, ok := v.(float64); ok {
+		return tokens.FormatNumber(f)
+	}
+	return fmt.Sprintf("%v", v)
+}
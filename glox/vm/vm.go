@@ -0,0 +1,579 @@
+// Package vm implements a stack based virtual machine executing the bytecode produced by the compiler package.
+package vm
+
+import (
+	"fmt"
+	"glox/compiler"
+	"glox/errors"
+	"glox/tokens"
+	"io"
+	"math"
+	"strings"
+	"time"
+)
+
+const maxFrames = 10000
+
+type callFrame struct {
+	closure *Closure
+	ip      int
+	// base is the stack index of the frame's first slot
+	base int
+	// name and className describe the call in stack traces
+	name      string
+	className string
+}
+
+// handler holds where the execution continues when an error happens inside a try statement
+type handler struct {
+	frame    int
+	stackTop int
+	ip       int
+	// finally handlers get the error itself instead of its value, so it can be thrown again
+	finally bool
+}
+
+type VM struct {
+	stack        []any
+	frames       []callFrame
+	handlers     []handler
+	globals      map[string]any
+	openUpvalues *Upvalue
+	stdout       io.Writer
+	// result holds the value of the last top-level expression statement
+	result any
+}
+
+func New(stdout io.Writer) *VM {
+	vm := &VM{globals: map[string]any{}, stdout: stdout}
+	vm.DefineNative("clock", 0, func(args []any) (any, error) {
+		return float64(time.Now().UnixMilli()), nil
+	})
+	return vm
+}
+
+// DefineNative defines a global function implemented in Go
+func (vm *VM) DefineNative(name string, arity int, fn func(args []any) (any, error)) {
+	vm.globals[name] = &Native{Name: name, Arity: arity, Fn: fn}
+}
+
+// DefineVariadicNative works as DefineNative, the function accepts any number of arguments from minArity on
+func (vm *VM) DefineVariadicNative(name string, minArity int, fn func(args []any) (any, error)) {
+	vm.globals[name] = &Native{Name: name, Arity: minArity, Variadic: true, Fn: fn}
+}
+
+// Interpret executes the provided script and returns the value of its last top-level expression statement.
+// Globals are kept between calls.
+func (vm *VM) Interpret(script *compiler.Function) (any, error) {
+	vm.result = nil
+	closure := &Closure{Function: script}
+	vm.push(closure)
+	if err := vm.call(closure, 0, "", ""); err != nil {
+		return nil, err
+	}
+	if err := vm.run(); err != nil {
+		return nil, err
+	}
+	return vm.result, nil
+}
+
+func (vm *VM) run() *errors.RuntimeError {
+	frame := &vm.frames[len(vm.frames)-1]
+	chunk := &frame.closure.Function.Chunk
+
+	readByte := func() byte {
+		b := chunk.Code[frame.ip]
+		frame.ip++
+		return b
+	}
+	readShort := func() int {
+		frame.ip += 2
+		return int(chunk.Code[frame.ip-2])<<8 | int(chunk.Code[frame.ip-1])
+	}
+	readString := func() string {
+		return chunk.Constants[readShort()].(string)
+	}
+	// restore refreshes the current frame after calls, returns and errors
+	restore := func() {
+		frame = &vm.frames[len(vm.frames)-1]
+		chunk = &frame.closure.Function.Chunk
+	}
+
+	for {
+		var err *errors.RuntimeError
+
+		switch op := compiler.OpCode(readByte()); op {
+		case compiler.OpConstant:
+			vm.push(chunk.Constants[readShort()])
+		case compiler.OpNil:
+			vm.push(nil)
+		case compiler.OpTrue:
+			vm.push(true)
+		case compiler.OpFalse:
+			vm.push(false)
+		case compiler.OpPop:
+			vm.pop()
+		case compiler.OpCopy:
+			vm.push(vm.peek(int(readByte())))
+		case compiler.OpSwap:
+			top := len(vm.stack) - 1
+			vm.stack[top], vm.stack[top-1] = vm.stack[top-1], vm.stack[top]
+		case compiler.OpResult:
+			vm.result = vm.pop()
+
+		case compiler.OpGetLocal:
+			vm.push(vm.stack[frame.base+int(readByte())])
+		case compiler.OpSetLocal:
+			vm.stack[frame.base+int(readByte())] = vm.peek(0)
+		case compiler.OpGetGlobal:
+			name := readString()
+			value, defined := vm.globals[name]
+			if !defined {
+				err = vm.error(fmt.Sprintf("Undefined variable '%s'.", name))
+				break
+			}
+			vm.push(value)
+		case compiler.OpDefineGlobal:
+			vm.globals[readString()] = vm.pop()
+		case compiler.OpSetGlobal:
+			name := readString()
+			if _, defined := vm.globals[name]; !defined {
+				err = vm.error(fmt.Sprintf("Undefined variable '%s'.", name))
+				break
+			}
+			vm.globals[name] = vm.peek(0)
+		case compiler.OpGetUpvalue:
+			upvalue := frame.closure.Upvalues[readByte()]
+			if upvalue.open {
+				vm.push(vm.stack[upvalue.slot])
+			} else {
+				vm.push(upvalue.closed)
+			}
+		case compiler.OpSetUpvalue:
+			upvalue := frame.closure.Upvalues[readByte()]
+			if upvalue.open {
+				vm.stack[upvalue.slot] = vm.peek(0)
+			} else {
+				upvalue.closed = vm.peek(0)
+			}
+
+		case compiler.OpGetProperty:
+			name := readString()
+			var value any
+			if value, err = vm.getProperty(vm.peek(0), name); err == nil {
+				vm.stack[len(vm.stack)-1] = value
+			}
+		case compiler.OpSetProperty:
+			name := readString()
+			instance, isInstance := vm.peek(1).(*Instance)
+			if !isInstance {
+				err = vm.error("Only instances have fields.")
+				break
+			}
+			value := vm.pop()
+			instance.Fields[name] = value
+			vm.stack[len(vm.stack)-1] = value
+		case compiler.OpGetSuper:
+			name := readString()
+			superclass := vm.pop().(*Class)
+			method, exists := superclass.Methods[name]
+			if !exists {
+				err = vm.error(fmt.Sprintf("Undefined property '%s'.", name))
+				break
+			}
+			vm.stack[len(vm.stack)-1] = &BoundMethod{Receiver: vm.peek(0), Method: method}
+
+		case compiler.OpEqual, compiler.OpGreater, compiler.OpGreaterEqual, compiler.OpLess, compiler.OpLessEqual,
+			compiler.OpAdd, compiler.OpSubtract, compiler.OpMultiply, compiler.OpDivide, compiler.OpModulo, compiler.OpIntDivide:
+			// the operands on the stack become the receiver and the argument of the method overloading the operator
+			if instance, isInstance := vm.peek(1).(*Instance); isInstance {
+				if method, exists := instance.Class.Methods[operatorMethods[op]]; exists {
+					if err = vm.call(method, 1, method.Function.Name, method.Function.ClassName); err == nil {
+						restore()
+					}
+					break
+				}
+			}
+			err = vm.binaryOperation(op)
+		case compiler.OpNot:
+			vm.push(isFalsey(vm.pop()))
+		case compiler.OpNegate:
+			n, isNumber := vm.peek(0).(float64)
+			if !isNumber {
+				err = vm.error("Operand must be a number.")
+				break
+			}
+			vm.stack[len(vm.stack)-1] = -n
+
+		case compiler.OpInterpolate:
+			count := int(readByte())
+			var result strings.Builder
+			for _, part := range vm.stack[len(vm.stack)-count:] {
+				result.WriteString(stringify(part))
+			}
+			vm.stack = vm.stack[:len(vm.stack)-count]
+			vm.push(result.String())
+
+		case compiler.OpPrint:
+			fmt.Fprintln(vm.stdout, stringify(vm.pop()))
+
+		case compiler.OpJump:
+			offset := readShort()
+			frame.ip += offset
+		case compiler.OpJumpIfFalse:
+			offset := readShort()
+			if isFalsey(vm.peek(0)) {
+				frame.ip += offset
+			}
+		case compiler.OpJumpIfNil:
+			offset := readShort()
+			if vm.peek(0) == nil {
+				frame.ip += offset
+			}
+		case compiler.OpLoop:
+			offset := readShort()
+			frame.ip -= offset
+
+		case compiler.OpCall:
+			argCount := int(readByte())
+			if err = vm.callValue(vm.peek(argCount), argCount); err == nil {
+				restore()
+			}
+		case compiler.OpClosure:
+			function := chunk.Constants[readShort()].(*compiler.Function)
+			closure := &Closure{Function: function, Upvalues: make([]*Upvalue, function.UpvalueCount)}
+			for i := range closure.Upvalues {
+				isLocal, index := readByte(), int(readByte())
+				if isLocal == 1 {
+					closure.Upvalues[i] = vm.captureUpvalue(frame.base + index)
+				} else {
+					closure.Upvalues[i] = frame.closure.Upvalues[index]
+				}
+			}
+			vm.push(closure)
+		case compiler.OpCloseUpvalue:
+			vm.closeUpvalues(len(vm.stack) - 1)
+			vm.pop()
+		case compiler.OpReturn:
+			result := vm.pop()
+			vm.closeUpvalues(frame.base)
+			// handlers registered by the returning frame are no longer valid
+			for len(vm.handlers) > 0 && vm.handlers[len(vm.handlers)-1].frame >= len(vm.frames)-1 {
+				vm.handlers = vm.handlers[:len(vm.handlers)-1]
+			}
+			vm.frames = vm.frames[:len(vm.frames)-1]
+			if len(vm.frames) == 0 {
+				vm.stack = vm.stack[:0]
+				return nil
+			}
+			vm.stack = vm.stack[:frame.base]
+			vm.push(result)
+			restore()
+
+		case compiler.OpClass:
+			vm.push(&Class{Name: readString(), Methods: map[string]*Closure{}})
+		case compiler.OpInherit:
+			superclass, isClass := vm.peek(1).(*Class)
+			if !isClass {
+				err = vm.error("Superclass must be a class.")
+				break
+			}
+			subclass := vm.peek(0).(*Class)
+			for name, method := range superclass.Methods {
+				subclass.Methods[name] = method
+			}
+			vm.pop()
+		case compiler.OpMethod:
+			name := readString()
+			vm.peek(1).(*Class).Methods[name] = vm.peek(0).(*Closure)
+			vm.pop()
+
+		case compiler.OpThrow:
+			value := vm.pop()
+			err = errors.NewThrowError(vm.token(), "Uncaught exception: "+stringify(value), value)
+		case compiler.OpPushHandler, compiler.OpPushFinally:
+			offset := readShort()
+			vm.handlers = append(vm.handlers, handler{
+				frame:    len(vm.frames) - 1,
+				stackTop: len(vm.stack),
+				ip:       frame.ip + offset,
+				finally:  op == compiler.OpPushFinally,
+			})
+		case compiler.OpPopHandler:
+			vm.handlers = vm.handlers[:len(vm.handlers)-1]
+		case compiler.OpRethrow:
+			err = vm.pop().(*errors.RuntimeError)
+
+		default:
+			panic(fmt.Sprintf("unknown opcode %d", op))
+		}
+
+		if err != nil {
+			if !vm.catch(err) {
+				return err
+			}
+			restore()
+		}
+	}
+}
+
+// catch moves the execution to the innermost error handler, it returns false if there is none
+func (vm *VM) catch(err *errors.RuntimeError) bool {
+	if len(vm.handlers) == 0 {
+		if err.Trace() == nil {
+			err.SetTrace(vm.trace())
+		}
+		vm.reset()
+		return false
+	}
+	h := vm.handlers[len(vm.handlers)-1]
+	vm.handlers = vm.handlers[:len(vm.handlers)-1]
+
+	var value any = err
+	if h.finally {
+		// the trace is taken before unwinding the calls, as it is in case the error is not caught
+		if err.Trace() == nil {
+			err.SetTrace(vm.trace())
+		}
+	} else if thrown, isThrown := err.Thrown(); isThrown {
+		value = thrown
+	} else {
+		value = &ErrorValue{Message: err.Error(), Line: err.Token().Line}
+	}
+	vm.closeUpvalues(h.stackTop)
+	vm.frames = vm.frames[:h.frame+1]
+	vm.stack = vm.stack[:h.stackTop]
+	vm.push(value)
+	vm.frames[h.frame].ip = h.ip
+	return true
+}
+
+func (vm *VM) reset() {
+	vm.stack = vm.stack[:0]
+	vm.frames = vm.frames[:0]
+	vm.handlers = vm.handlers[:0]
+	vm.openUpvalues = nil
+}
+
+func (vm *VM) callValue(callee any, argCount int) *errors.RuntimeError {
+	switch c := callee.(type) {
+	case *Closure:
+		return vm.call(c, argCount, c.Function.Name, c.Function.ClassName)
+	case *BoundMethod:
+		vm.stack[len(vm.stack)-argCount-1] = c.Receiver
+		return vm.call(c.Method, argCount, c.Method.Function.Name, c.Method.Function.ClassName)
+	case *Class:
+		vm.stack[len(vm.stack)-argCount-1] = &Instance{Class: c, Fields: map[string]any{}}
+		if initializer, exists := c.Methods["init"]; exists {
+			return vm.call(initializer, argCount, c.Name, "")
+		}
+		if argCount != 0 {
+			return vm.error(fmt.Sprintf("Expected 0 arguments but got %d.", argCount))
+		}
+		return nil
+	case *Native:
+		if c.Variadic && argCount < c.Arity {
+			return vm.error(fmt.Sprintf("Expected at least %d arguments but got %d.", c.Arity, argCount))
+		}
+		if !c.Variadic && argCount != c.Arity {
+			return vm.error(fmt.Sprintf("Expected %d arguments but got %d.", c.Arity, argCount))
+		}
+		result, err := c.Fn(vm.stack[len(vm.stack)-argCount:])
+		if err != nil {
+			return vm.error(err.Error())
+		}
+		vm.stack = vm.stack[:len(vm.stack)-argCount-1]
+		vm.push(result)
+		return nil
+	}
+	return vm.error("Can only call functions and classes.")
+}
+
+func (vm *VM) call(closure *Closure, argCount int, name string, className string) *errors.RuntimeError {
+	if argCount != closure.Function.Arity {
+		return vm.error(fmt.Sprintf("Expected %d arguments but got %d.", closure.Function.Arity, argCount))
+	}
+	if len(vm.frames) == maxFrames {
+		return vm.error("Stack overflow.")
+	}
+	vm.frames = append(vm.frames, callFrame{
+		closure:   closure,
+		base:      len(vm.stack) - argCount - 1,
+		name:      name,
+		className: className,
+	})
+	return nil
+}
+
+func (vm *VM) getProperty(object any, name string) (any, *errors.RuntimeError) {
+	switch o := object.(type) {
+	case *Instance:
+		if value, exists := o.Fields[name]; exists {
+			return value, nil
+		}
+		if method, exists := o.Class.Methods[name]; exists {
+			return &BoundMethod{Receiver: o, Method: method}, nil
+		}
+	case *ErrorValue:
+		switch name {
+		case "message":
+			return o.Message, nil
+		case "line":
+			return float64(o.Line), nil
+		}
+	default:
+		return nil, vm.error("Only instances have properties.")
+	}
+	return nil, vm.error(fmt.Sprintf("Undefined property '%s'.", name))
+}
+
+func (vm *VM) captureUpvalue(slot int) *Upvalue {
+	var previous *Upvalue
+	upvalue := vm.openUpvalues
+	for upvalue != nil && upvalue.slot > slot {
+		previous = upvalue
+		upvalue = upvalue.next
+	}
+	if upvalue != nil && upvalue.slot == slot {
+		return upvalue
+	}
+	created := &Upvalue{slot: slot, open: true, next: upvalue}
+	if previous == nil {
+		vm.openUpvalues = created
+	} else {
+		previous.next = created
+	}
+	return created
+}
+
+// closeUpvalues moves the values of the stack slots from last onwards into the upvalues referencing them
+func (vm *VM) closeUpvalues(last int) {
+	for vm.openUpvalues != nil && vm.openUpvalues.slot >= last {
+		upvalue := vm.openUpvalues
+		upvalue.closed = vm.stack[upvalue.slot]
+		upvalue.open = false
+		vm.openUpvalues = upvalue.next
+	}
+}
+
+// operatorMethods holds the names of the methods overloading the binary operators
+var operatorMethods = map[compiler.OpCode]string{
+	compiler.OpEqual:        "__eq__",
+	compiler.OpGreater:      "__gt__",
+	compiler.OpGreaterEqual: "__ge__",
+	compiler.OpLess:         "__lt__",
+	compiler.OpLessEqual:    "__le__",
+	compiler.OpAdd:          "__add__",
+	compiler.OpSubtract:     "__sub__",
+	compiler.OpMultiply:     "__mul__",
+	compiler.OpDivide:       "__div__",
+	compiler.OpModulo:       "__mod__",
+	compiler.OpIntDivide:    "__idiv__",
+}
+
+func (vm *VM) binaryOperation(op compiler.OpCode) *errors.RuntimeError {
+	switch op {
+	case compiler.OpEqual:
+		b, a := vm.pop(), vm.pop()
+		vm.push(a == b)
+	case compiler.OpGreater:
+		return vm.numberOperation(func(a, b float64) any { return a > b })
+	case compiler.OpGreaterEqual:
+		return vm.numberOperation(func(a, b float64) any { return a >= b })
+	case compiler.OpLess:
+		return vm.numberOperation(func(a, b float64) any { return a < b })
+	case compiler.OpLessEqual:
+		return vm.numberOperation(func(a, b float64) any { return a <= b })
+	case compiler.OpAdd:
+		return vm.add()
+	case compiler.OpSubtract:
+		return vm.numberOperation(func(a, b float64) any { return a - b })
+	case compiler.OpMultiply:
+		return vm.numberOperation(func(a, b float64) any { return a * b })
+	case compiler.OpDivide:
+		return vm.divide(func(a, b float64) float64 { return a / b })
+	case compiler.OpModulo:
+		return vm.divide(math.Mod)
+	case compiler.OpIntDivide:
+		return vm.divide(func(a, b float64) float64 { return math.Trunc(a / b) })
+	}
+	return nil
+}
+
+// divide performs the division operations, which fail if both operands are numbers and the divisor is zero
+func (vm *VM) divide(f func(a, b float64) float64) *errors.RuntimeError {
+	if b, isNumber := vm.peek(0).(float64); isNumber && b == 0 {
+		if _, isNumber := vm.peek(1).(float64); isNumber {
+			return vm.error("Cannot divide by zero.")
+		}
+	}
+	return vm.numberOperation(func(a, b float64) any { return f(a, b) })
+}
+
+func (vm *VM) add() *errors.RuntimeError {
+	switch b := vm.peek(0).(type) {
+	case float64:
+		if a, isNumber := vm.peek(1).(float64); isNumber {
+			vm.pop()
+			vm.stack[len(vm.stack)-1] = a + b
+			return nil
+		}
+	case string:
+		if a, isString := vm.peek(1).(string); isString {
+			vm.pop()
+			vm.stack[len(vm.stack)-1] = a + b
+			return nil
+		}
+	}
+	return vm.error("Operands must be two numbers or two strings.")
+}
+
+func (vm *VM) numberOperation(f func(a, b float64) any) *errors.RuntimeError {
+	b, bIsNumber := vm.peek(0).(float64)
+	a, aIsNumber := vm.peek(1).(float64)
+	if !aIsNumber || !bIsNumber {
+		return vm.error("Operands must be numbers.")
+	}
+	vm.pop()
+	vm.stack[len(vm.stack)-1] = f(a, b)
+	return nil
+}
+
+// error returns a runtime error happening at the current instruction
+func (vm *VM) error(message string) *errors.RuntimeError {
+	return errors.NewRuntimeError(vm.token(), message)
+}
+
+// token returns the token the current instruction comes from
+func (vm *VM) token() tokens.Token {
+	frame := vm.frames[len(vm.frames)-1]
+	return frame.closure.Function.Chunk.Token(frame.ip - 1)
+}
+
+// trace returns the active calls, the outermost first
+func (vm *VM) trace() []errors.StackFrame {
+	trace := []errors.StackFrame{}
+	for i := 1; i < len(vm.frames); i++ {
+		caller := vm.frames[i-1]
+		trace = append(trace, errors.StackFrame{
+			Function: vm.frames[i].name,
+			Class:    vm.frames[i].className,
+			Line:     caller.closure.Function.Chunk.Line(caller.ip - 1),
+		})
+	}
+	return trace
+}
+
+func (vm *VM) push(v any) {
+	vm.stack = append(vm.stack, v)
+}
+
+func (vm *VM) pop() any {
+	v := vm.stack[len(vm.stack)-1]
+	vm.stack = vm.stack[:len(vm.stack)-1]
+	return v
+}
+
+func (vm *VM) peek(distance int) any {
+	return vm.stack[len(vm.stack)-1-distance]
+}
@@ -0,0 +1,88 @@
+package vm
+
+import (
+	"bytes"
+	"glox/compiler"
+	"glox/errors"
+	"glox/parser"
+	"glox/scanner"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func interpret(t *testing.T, vm *VM, source string) (any, error) {
+	t.Helper()
+	reporter := errors.NewReporter(io.Discard)
+	s := scanner.NewScanner(source, reporter)
+	s.ScanTokens()
+	p := parser.NewParser[any](s.Tokens(), reporter)
+	statements, parseErrors := p.Parse()
+	require.Empty(t, parseErrors)
+	function, err := compiler.Compile(statements)
+	require.NoError(t, err)
+	return vm.Interpret(function)
+}
+
+func TestInterpret(t *testing.T) {
+	cases := []struct {
+		source   string
+		expected any
+		output   string
+	}{
+		{source: "1 + 2 * 3;", expected: 7.0},
+		{source: "!nil == true;", expected: true},
+		{source: "var a = \"a\"; { var b = \"b\"; a = a + b; } a;", expected: "ab"},
+		{
+			source: "fun counter() { var i = 0; fun next() { i = i + 1; return i; } return next; }" +
+				"var c = counter(); c(); print c();",
+			expected: 1.0,
+			output:   "2\n",
+		},
+		{
+			source: "var fs = nil; for (var i = 0; i < 2; i = i + 1) { fun f() { return i; } if (fs == nil) fs = f; }" +
+				"print fs();",
+			output: "2\n",
+		},
+		{
+			source: "class A { init(n) { this.n = n; } get() { return this.n; } }" +
+				"class B < A { get() { return super.get() + 1; } } print B(1).get();",
+			output: "2\n",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.source, func(t *testing.T) {
+			t.Parallel()
+			var stdout bytes.Buffer
+			value, err := interpret(t, New(&stdout), tc.source)
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, value)
+			require.Equal(t, tc.output, stdout.String())
+		})
+	}
+}
+
+func TestInterpretAfterError(t *testing.T) {
+	vm := New(io.Discard)
+	_, err := interpret(t, vm, "var a = 1; fun f() { try { return -\"a\"; } finally { a = 2; } } f();")
+	var runtimeError *errors.RuntimeError
+	require.ErrorAs(t, err, &runtimeError)
+	require.Equal(t, "Operand must be a number.", runtimeError.Error())
+	require.Empty(t, vm.stack)
+	require.Empty(t, vm.handlers)
+
+	value, err := interpret(t, vm, "a;")
+	require.NoError(t, err)
+	require.Equal(t, 2.0, value)
+}
+
+func TestStackOverflow(t *testing.T) {
+	_, err := interpret(t, New(io.Discard), "fun f() { f(); } f();")
+	var runtimeError *errors.RuntimeError
+	require.ErrorAs(t, err, &runtimeError)
+	require.Equal(t, "Stack overflow.", runtimeError.Error())
+	require.Len(t, runtimeError.Trace(), maxFrames-1)
+}
@@ -13,11 +13,30 @@ func main() {
 		"Assign	  : Name tokens.Token, Value Expr[T]",
 		"Binary   : Left Expr[T], Operator tokens.Token, Right Expr[T]",
 		"Call     : Callee Expr[T], Paren tokens.Token, Arguments []Expr[T]",
+		// CompoundAssign, CompoundSet and CompoundIndexSet apply the operator to the target and the value, Eg: `a += 1`,
+		// `a.b++` or `a[i] *= 2`. Postfix increments evaluate to the previous value
+		"CompoundAssign : Name tokens.Token, Operator tokens.Token, Value Expr[T], Postfix bool",
+		"CompoundSet : Object Expr[T], Name tokens.Token, Operator tokens.Token, Value Expr[T], Postfix bool",
+		"CompoundIndexSet : Object Expr[T], Bracket tokens.Token, Index Expr[T], Operator tokens.Token, Value Expr[T], Postfix bool",
+		"Conditional : Condition Expr[T], Question tokens.Token, Then Expr[T], Else Expr[T]",
 		"Get	  : Object Expr[T], Name tokens.Token",
 		"Grouping : Expression Expr[T]",
+		"Index    : Object Expr[T], Bracket tokens.Token, Index Expr[T]",
+		"IndexSet : Object Expr[T], Bracket tokens.Token, Index Expr[T], Value Expr[T]",
+		// Interpolation concatenates the string value of its parts, Eg: "Hello ${name}!"
+		"Interpolation : Start tokens.Token, Parts []Expr[T]",
+		// Function is a *stmt.Function[T], the expr package can't refer to stmt types
+		"Lambda   : Keyword tokens.Token, Function any",
+		"List     : Bracket tokens.Token, Elements []Expr[T]",
+		"Map      : Brace tokens.Token, Keys []Expr[T], Values []Expr[T]",
 		"Literal  : Value any",
+		// OptionalGet is `object?.name`, when the object is nil the enclosing OptionalChain evaluates to nil
+		"OptionalGet : Object Expr[T], Name tokens.Token",
+		// OptionalChain is a chain of calls and property accesses containing an OptionalGet, Eg: `a?.b.c()`
+		"OptionalChain : Expression Expr[T]",
 		"Unary    : Operator tokens.Token, Right Expr[T]",
 		"Set	  : Object Expr[T], Name tokens.Token, Value Expr[T]",
+		"Slice    : Object Expr[T], Bracket tokens.Token, Start Expr[T], End Expr[T]",
 		"Super    : Keyword tokens.Token, Method tokens.Token",
 		"This	  : Keyword tokens.Token",
 		"Logical  : Left Expr[T], Operator tokens.Token, Right Expr[T]",
@@ -27,14 +46,22 @@ func main() {
 
 	types_stmt := []string{
 		"Block		: Statements []Stmt[T]",
-		"Class		: Name tokens.Token, SuperClass *expr.Variable[T], Methods []*Function[T]",
+		"Break		: Keyword tokens.Token",
+		"Class		: Name tokens.Token, SuperClass *expr.Variable[T], Traits []*expr.Variable[T], Fields []*Field, Methods []*Function[T], ClassMethods []*Function[T], Getters []*Function[T], Setters []*Function[T]",
+		"Continue	: Keyword tokens.Token",
 		"Expression	: Expression expr.Expr[T]",
-		"Function   : Name tokens.Token, Params []tokens.Token, Body []Stmt[T]",
-		"If			: Condition expr.Expr[T], ThenBranch Stmt[T], ElseBranch Stmt[T]",
-		"Print		: Expression expr.Expr[T]",
+		// ParamTypes and ReturnType hold the optional type annotations, Eg: `fun f(a: num): str`
+		"Function   : Name tokens.Token, Params []tokens.Token, ParamTypes []*expr.TypeAnnotation, Defaults []expr.Expr[T], Variadic bool, ReturnType *expr.TypeAnnotation, Body []Stmt[T]",
+		"If			: Keyword tokens.Token, Condition expr.Expr[T], ThenBranch Stmt[T], ElseBranch Stmt[T]",
+		"Import		: Keyword tokens.Token, Path tokens.Token, Name tokens.Token",
+		"Match		: Keyword tokens.Token, Value expr.Expr[T], Cases []*MatchCase[T]",
+		"Print		: Keyword tokens.Token, Expression expr.Expr[T]",
 		"Return 	: Keyword tokens.Token, Value expr.Expr[T]",
-		"Var		: Name tokens.Token, Initializer expr.Expr[T]",
-		"While		: Condition expr.Expr[T], Body Stmt[T]",
+		"Throw		: Keyword tokens.Token, Value expr.Expr[T]",
+		"Trait		: Name tokens.Token, Methods []*Function[T]",
+		"Try		: Keyword tokens.Token, Body []Stmt[T], CatchName *tokens.Token, CatchBody []Stmt[T], FinallyBody []Stmt[T]",
+		"Var		: Name tokens.Token, Type *expr.TypeAnnotation, Initializer expr.Expr[T]",
+		"While		: Keyword tokens.Token, Condition expr.Expr[T], Body Stmt[T], Increment expr.Expr[T]",
 	}
 	defineAst("../../glox/stmt", "Stmt", types_stmt)
 